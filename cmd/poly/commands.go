@@ -9,11 +9,14 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/Open-Science-Global/poly"
 	"github.com/Open-Science-Global/poly/io/genbank"
 	"github.com/Open-Science-Global/poly/io/gff"
+	"github.com/Open-Science-Global/poly/io/gtf"
 	"github.com/Open-Science-Global/poly/io/polyjson"
 	"github.com/Open-Science-Global/poly/seqhash"
 	"github.com/urfave/cli/v2"
@@ -68,9 +71,15 @@ parse them, and then spit out a similiarly named file with the .json extension.
 func convertCommand(c *cli.Context) error {
 	if isPipe(c) {
 
-		sequence := parseStdin(c)
+		sequence, err := parseStdin(c)
+		if err != nil {
+			return err
+		}
 
-		output := buildStdOut(c, sequence)
+		output, err := buildStdOut(c, sequence)
+		if err != nil {
+			return err
+		}
 
 		// logic for chosing output format, then builds string to be output.
 
@@ -89,6 +98,7 @@ func convertCommand(c *cli.Context) error {
 
 		// declaring wait group outside loop
 		var wg sync.WaitGroup
+		var fileErrors fileErrors
 
 		// concurrently iterate through each pattern match, read the file, output to new format.
 		for _, match := range matches {
@@ -98,10 +108,16 @@ func convertCommand(c *cli.Context) error {
 
 			// executing Go routine.
 			go func(match string) {
-				sequence := parseExt(match)
-				writeFile(c, sequence, match)
 				// decrementing wait group.
-				wg.Done()
+				defer wg.Done()
+
+				// files that fail to parse are skipped instead of being written out empty.
+				sequence, err := parseExt(match)
+				if err != nil {
+					fileErrors.add(match, err)
+					return
+				}
+				writeFile(c, sequence, match)
 
 			}(match) // passing match to Go routine anonymous function.
 
@@ -111,6 +127,7 @@ func convertCommand(c *cli.Context) error {
 
 		// waiting outside for loop for Go routines so they can run concurrently.
 		wg.Wait()
+		return fileErrors.err()
 	}
 
 	return nil
@@ -146,7 +163,10 @@ parse them, and then spit out a similiarly named file with the .json extension a
 func hashCommand(c *cli.Context) error {
 
 	if isPipe(c) {
-		sequence := parseStdin(c) // get sequence from stdin
+		sequence, err := parseStdin(c) // get sequence from stdin
+		if err != nil {
+			return err
+		}
 		hash, _ := seqhash.Hash(sequence.Sequence, sequence.Meta.Locus.MoleculeType, sequence.Meta.Locus.Circular, true)
 		printHash(c, hash, "-")
 
@@ -160,6 +180,7 @@ func hashCommand(c *cli.Context) error {
 
 		// declaring wait group outside loop
 		var wg sync.WaitGroup
+		var fileErrors fileErrors
 
 		// concurrently iterate through each pattern match, read the file, output to new format.
 		for _, match := range matches {
@@ -169,24 +190,50 @@ func hashCommand(c *cli.Context) error {
 
 			// executing Go routine.
 			go func(match string) {
-				sequence := parseExt(match)
+				// decrementing wait group.
+				defer wg.Done()
+
+				sequence, err := parseExt(match)
+				if err != nil {
+					fileErrors.add(match, err)
+					return
+				}
 				hash, _ := seqhash.Hash(sequence.Sequence, sequence.Meta.Locus.MoleculeType, sequence.Meta.Locus.Circular, true)
 				printHash(c, hash, match)
 
-				// decrementing wait group.
-				wg.Done()
-
 			}(match) // passing match to Go routine anonymous function.
 
 		}
 
 		// waiting outside for loop for Go routines so they can run concurrently.
 		wg.Wait()
+		return fileErrors.err()
 
 	}
 	return nil
 }
 
+// fileErrors collects the errors of files being processed concurrently so they can all be reported at once.
+type fileErrors struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (fileErrors *fileErrors) add(path string, err error) {
+	fileErrors.mutex.Lock()
+	defer fileErrors.mutex.Unlock()
+	fileErrors.messages = append(fileErrors.messages, path+": "+err.Error())
+}
+
+// err returns a single error listing every file that failed in path order or nil if none did.
+func (fileErrors *fileErrors) err() error {
+	if len(fileErrors.messages) == 0 {
+		return nil
+	}
+	sort.Strings(fileErrors.messages)
+	return errors.New(strings.Join(fileErrors.messages, "\n"))
+}
+
 // a simple helper function to convert an *os.File type into a string.
 func stdinToBytes(file io.Reader) []byte {
 	var stringBuffer bytes.Buffer
@@ -216,31 +263,27 @@ func isPipe(c *cli.Context) bool {
 }
 
 // a simple helper function to take stdin from a pipe and parse it into an Sequence
-func parseStdin(c *cli.Context) poly.Sequence {
-	var sequence poly.Sequence
-	// logic for determining input format, then parses accordingly.
-	if c.String("i") == "json" {
-		_ = json.Unmarshal([]byte(stdinToBytes(c.App.Reader)), &sequence)
-	} else if c.String("i") == "gbk" || c.String("i") == "gb" {
-		sequence = genbank.Parse(stdinToBytes(c.App.Reader))
-	} else if c.String("i") == "gff" {
-		sequence = gff.Parse(stdinToBytes(c.App.Reader))
-	}
-	return sequence
+func parseStdin(c *cli.Context) (poly.Sequence, error) {
+	return parseFlag(stdinToBytes(c.App.Reader), c.String("i"))
 }
 
-// a simple helper function to take stdin from a pipe and parse it into an Sequence
-func parseFlag(file []byte, flag string) poly.Sequence {
+// a simple helper function to parse a file's contents into an Sequence based on a format flag.
+func parseFlag(file []byte, flag string) (poly.Sequence, error) {
 	var sequence poly.Sequence
+	var err error
 	// logic for determining input format, then parses accordingly.
 	if flag == "json" {
-		_ = json.Unmarshal(file, &sequence)
+		sequence, err = polyjson.ParseStrict(file)
 	} else if flag == "gbk" || flag == "gb" {
-		sequence = genbank.Parse(file)
+		sequence, err = genbank.Parse(file)
 	} else if flag == "gff" {
-		sequence = gff.Parse(file)
+		sequence, err = gff.Parse(file)
+	} else if flag == "gtf" {
+		sequence, err = gtf.Parse(file)
+	} else {
+		err = fmt.Errorf("unknown input format %q", flag)
 	}
-	return sequence
+	return sequence, err
 }
 
 // helper function to get unique glob patterns from cli.context
@@ -279,16 +322,21 @@ func uniqueNonEmptyElementsOf(s []string) []string {
 
 }
 
-func buildStdOut(c *cli.Context, sequence poly.Sequence) []byte {
+func buildStdOut(c *cli.Context, sequence poly.Sequence) ([]byte, error) {
 	var output []byte
+	var err error
 	if c.String("o") == "json" {
-		output, _ = json.MarshalIndent(sequence, "", " ")
+		output, err = json.MarshalIndent(sequence, "", " ")
 	} else if c.String("o") == "gff" {
 		output = gff.Build(sequence)
+	} else if c.String("o") == "gtf" {
+		output = gtf.Build(sequence)
 	} else if c.String("o") == "gbk" || c.String("o") == "gb" {
 		output = genbank.Build(sequence)
+	} else {
+		err = fmt.Errorf("unknown output format %q", c.String("o"))
 	}
-	return output
+	return output, err
 }
 
 func writeFile(c *cli.Context, sequence poly.Sequence, match string) {
@@ -311,6 +359,8 @@ func writeFile(c *cli.Context, sequence poly.Sequence, match string) {
 		polyjson.Write(sequence, outputPath)
 	} else if outputExtension == "gff" {
 		gff.Write(sequence, outputPath)
+	} else if outputExtension == "gtf" {
+		gtf.Write(sequence, outputPath)
 	} else if outputExtension == "gbk" || c.String("o") == "gb" {
 		genbank.Write(sequence, outputPath)
 	}
@@ -325,17 +375,22 @@ func formatHashOutput(hash string, path string) string {
 	return hash + "  " + path + "\n"
 }
 
-func parseExt(match string) poly.Sequence {
+func parseExt(match string) (poly.Sequence, error) {
 	extension := filepath.Ext(match)
 	var sequence poly.Sequence
+	var err error
 
 	// determining which reader to use and parse into Sequence struct.
 	if extension == ".gff" {
-		sequence = gff.Read(match)
+		sequence, err = gff.Read(match)
+	} else if extension == ".gtf" {
+		sequence, err = gtf.Read(match)
 	} else if extension == ".gbk" || extension == ".gb" {
-		sequence = genbank.Read(match)
+		sequence, err = genbank.Read(match)
 	} else if extension == ".json" {
-		sequence = polyjson.Read(match)
+		sequence, err = polyjson.ReadStrict(match)
+	} else {
+		err = fmt.Errorf("unknown file extension %q", extension)
 	}
-	return sequence
+	return sequence, err
 }
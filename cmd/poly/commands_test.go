@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Open-Science-Global/poly"
@@ -24,7 +25,7 @@ via cli.app.reader and cli.app.writer. This is the only way to get true stack
 traceable coverage.
 ******************************************************************************/
 
-var testFilePaths = []string{"../../data/puc19.gbk", "../../data/ecoli-mg1655-short.gff", "../../data/sample.json"}
+var testFilePaths = []string{"../../data/puc19.gbk", "../../data/ecoli-mg1655-short.gff", "../../data/sample.gtf", "../../data/puc19static.json"}
 
 func TestMain(t *testing.T) {
 	rescueStdout := os.Stdout
@@ -59,7 +60,10 @@ func TestConvertPipe(t *testing.T) {
 		}
 
 		// getting test sequence from non-pipe io to compare against io to stdout
-		baseTestSequence := parseExt(match)
+		baseTestSequence, err := parseExt(match)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
 		pipeOutputTestSequence := polyjson.Parse(writeBuffer.Bytes())
 
 		if diff := cmp.Diff(baseTestSequence, pipeOutputTestSequence, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
@@ -88,9 +92,15 @@ func TestConvertIO(t *testing.T) {
 		}
 
 		// getting test sequence from non-pipe io to compare against io to stdout
-		baseTestSequence := parseExt(match)
+		baseTestSequence, err := parseExt(match)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
 
-		pipeOutputTestSequence := parseFlag(writeBuffer.Bytes(), extension)
+		pipeOutputTestSequence, err := parseFlag(writeBuffer.Bytes(), extension)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
 
 		if diff := cmp.Diff(baseTestSequence, pipeOutputTestSequence, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
 			t.Errorf(" mismatch reading and writing %q (-want +got):\n%s", extension, diff)
@@ -98,6 +108,58 @@ func TestConvertIO(t *testing.T) {
 	}
 }
 
+func TestConvertPipeError(t *testing.T) {
+	malformed := map[string]string{
+		"gbk":  "this is not a genbank file\n",
+		"json": `{"sequence": "ACGT", "features": [`,
+	}
+	for format, file := range malformed {
+		var writeBuffer bytes.Buffer
+		app := application()
+		app.Writer = &writeBuffer
+
+		args := os.Args[0:1] // Name of the program.
+		args = append(args, "c", "-i", format, "-o", "json")
+		app.Reader = bytes.NewReader([]byte(file))
+
+		err := app.Run(args)
+		if err == nil {
+			t.Fatalf("expected an error converting a malformed %s file", format)
+		}
+		if writeBuffer.Len() != 0 {
+			t.Errorf("expected no output for a malformed %s file, got %q", format, writeBuffer.String())
+		}
+	}
+
+	if _, err := parseExt("../../data/missing.json"); err == nil {
+		t.Errorf("expected an error reading a json file that doesn't exist")
+	}
+}
+
+func TestConvertGtfThroughGff(t *testing.T) {
+	file, _ := ioutil.ReadFile("../../data/sample.gtf")
+	input := file
+
+	for _, formats := range [][2]string{{"gtf", "gff"}, {"gff", "gtf"}} {
+		var writeBuffer bytes.Buffer
+		app := application()
+		app.Writer = &writeBuffer
+		app.Reader = bytes.NewReader(input)
+
+		args := append(os.Args[0:1], "c", "-i", formats[0], "-o", formats[1])
+		if err := app.Run(args); err != nil {
+			t.Fatalf("Run error converting %s to %s: %s", formats[0], formats[1], err)
+		}
+		input = writeBuffer.Bytes()
+	}
+
+	// only the first seqname is converted from a pipe and the header comment isn't kept.
+	expected := strings.Split(string(file), "\nchr2")[0][len("#!genome-build poly-test\n"):] + "\n"
+	if diff := cmp.Diff(expected, string(input)); diff != "" {
+		t.Errorf("gtf does not survive conversion to gff and back (-want +got):\n%s", diff)
+	}
+}
+
 func TestConvertWriteFile(t *testing.T) {
 
 	for _, match := range testFilePaths {
@@ -116,10 +178,16 @@ func TestConvertWriteFile(t *testing.T) {
 		}
 
 		// getting test sequence from non-pipe io to compare against io to stdout
-		baseTestSequence := parseExt(match)
+		baseTestSequence, err := parseExt(match)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
 
-		outputSequence := parseExt(testOutputPath)
+		outputSequence, err := parseExt(testOutputPath)
 		os.Remove(testOutputPath)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
 
 		if diff := cmp.Diff(baseTestSequence, outputSequence, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
 			t.Errorf(" mismatch reading and writing %q (-want +got):\n%s", extension, diff)
@@ -171,7 +239,7 @@ func TestConvertFile(t *testing.T) {
 		t.Fatalf("Run error: %s", err)
 	}
 
-	puc19InputTestSequence := genbank.Read("../../data/puc19.gbk")
+	puc19InputTestSequence, _ := genbank.Read("../../data/puc19.gbk")
 	puc19OutputTestSequence := polyjson.Read("../../data/puc19.json")
 
 	//clearing test data.
@@ -182,7 +250,7 @@ func TestConvertFile(t *testing.T) {
 		t.Errorf(" mismatch from concurrent gbk input test (-want +got):\n%s", diff)
 	}
 
-	t4InputTestSequence := genbank.Read("../../data/t4_intron.gb")
+	t4InputTestSequence, _ := genbank.Read("../../data/t4_intron.gb")
 	t4OutputTestSequence := polyjson.Read("../../data/t4_intron.json")
 
 	// clearing test data.
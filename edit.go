@@ -0,0 +1,375 @@
+package poly
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/Open-Science-Global/poly/transform"
+)
+
+/******************************************************************************
+
+Sequence editing begins here.
+
+Editing a sequence by hand means fixing every feature location by hand too.
+The functions here splice bases in and out of a Sequence and keep every
+Feature's SequenceLocation (and its SubLocations) pointing at the same bases
+it did before the edit.
+
+All positions are 0-based and ranges are half-open, just like Location.
+Features that span the origin of a circular sequence are split at the origin
+for the edit and merged back afterwards. Meta.Gaps are moved the same way.
+
+******************************************************************************/
+
+// EditPolicy tells Delete and Replace what to do with features that lose some of their bases.
+type EditPolicy int
+
+const (
+	// MarkPartial keeps broken features, trims them down to what is left and flags the cut ends as partial.
+	MarkPartial EditPolicy = iota
+	// DropBroken removes every feature that loses some of its bases.
+	DropBroken
+)
+
+// Insert inserts bases before a 0-based position. Features downstream of the insertion are shifted
+// and features spanning the insertion are split around it into a join.
+func (sequence *Sequence) Insert(position int, bases string) error {
+	if position < 0 || position > len(sequence.Sequence) {
+		return fmt.Errorf("insert position %d is outside of sequence of length %d", position, len(sequence.Sequence))
+	}
+	if len(bases) == 0 {
+		return nil
+	}
+
+	oldLength := len(sequence.Sequence)
+	sequence.Sequence = sequence.Sequence[:position] + bases + sequence.Sequence[position:]
+
+	for featureIndex := range sequence.Features {
+		feature := &sequence.Features[featureIndex]
+		location := feature.SequenceLocation.SplitAtOrigin(oldLength)
+		location = insertLocation(location, position, len(bases))
+		if feature.SequenceLocation.SpansOrigin() {
+			location = location.MergeAtOrigin(len(sequence.Sequence))
+		}
+		setFeatureLocation(feature, location)
+	}
+	sequence.editGaps(func(location Location) (Location, bool) {
+		return insertLocation(location, position, len(bases)), true
+	})
+
+	sequence.syncAfterEdit()
+	return nil
+}
+
+// Delete removes the bases in the 0-based half-open range [start, end). Features downstream of the
+// deletion are shifted and features that lose bases are handled according to policy. Features that
+// lose all of their bases are always removed.
+func (sequence *Sequence) Delete(start, end int, policy EditPolicy) error {
+	if start < 0 || end > len(sequence.Sequence) || start > end {
+		return fmt.Errorf("delete range %d..%d is outside of sequence of length %d", start, end, len(sequence.Sequence))
+	}
+	if start == end {
+		return nil
+	}
+
+	oldLength := len(sequence.Sequence)
+	sequence.Sequence = sequence.Sequence[:start] + sequence.Sequence[end:]
+
+	features := []Feature{}
+	for _, feature := range sequence.Features {
+		location := feature.SequenceLocation.SplitAtOrigin(oldLength)
+		location, kept, broken := deleteLocation(location, start, end)
+		if !kept || (broken && policy == DropBroken) {
+			continue
+		}
+		if feature.SequenceLocation.SpansOrigin() {
+			location = location.MergeAtOrigin(len(sequence.Sequence))
+		}
+		setFeatureLocation(&feature, location)
+		features = append(features, feature)
+	}
+	sequence.Features = features
+	sequence.editGaps(func(location Location) (Location, bool) {
+		location, kept, _ := deleteLocation(location, start, end)
+		return location, kept
+	})
+
+	sequence.syncAfterEdit()
+	return nil
+}
+
+// Replace swaps the bases in the 0-based half-open range [start, end) for new bases. It is a Delete
+// followed by an Insert at start, so features spanning the replaced range are split around the new bases.
+func (sequence *Sequence) Replace(start, end int, bases string, policy EditPolicy) error {
+	if start < 0 || end > len(sequence.Sequence) || start > end {
+		return fmt.Errorf("replace range %d..%d is outside of sequence of length %d", start, end, len(sequence.Sequence))
+	}
+	if err := sequence.Delete(start, end, policy); err != nil {
+		return err
+	}
+	return sequence.Insert(start, bases)
+}
+
+// Rotate sets the origin of a circular sequence to a 0-based position so that the base at that position
+// becomes the first base of the sequence. Every feature location is remapped to the new origin.
+func (sequence *Sequence) Rotate(origin int) error {
+	if !sequence.Meta.Locus.Circular {
+		return errors.New("only circular sequences can be rotated")
+	}
+	length := len(sequence.Sequence)
+	if origin < 0 || origin >= length {
+		return fmt.Errorf("new origin %d is outside of sequence of length %d", origin, length)
+	}
+	if origin == 0 {
+		return nil
+	}
+
+	sequence.Sequence = sequence.Sequence[origin:] + sequence.Sequence[:origin]
+
+	for featureIndex := range sequence.Features {
+		feature := &sequence.Features[featureIndex]
+		location := rotateLocation(feature.SequenceLocation, origin, length)
+		setFeatureLocation(feature, location)
+	}
+	sequence.editGaps(func(location Location) (Location, bool) {
+		return rotateLocation(location, origin, length), true
+	})
+
+	sequence.syncAfterEdit()
+	return nil
+}
+
+// ReverseComplement reverse complements a sequence and mirrors every feature onto the new strand, so
+// each feature still reads the same bases. Complement and GFF strands are toggled and joins are reversed.
+func (sequence *Sequence) ReverseComplement() {
+	length := len(sequence.Sequence)
+	sequence.Sequence = transform.ReverseComplement(sequence.Sequence)
+
+	for featureIndex := range sequence.Features {
+		feature := &sequence.Features[featureIndex]
+		location := mirrorLocation(feature.SequenceLocation, length)
+		location.Complement = !location.Complement
+		setFeatureLocation(feature, location)
+
+		switch feature.Strand {
+		case "+":
+			feature.Strand = "-"
+		case "-":
+			feature.Strand = "+"
+		}
+	}
+	sequence.editGaps(func(location Location) (Location, bool) {
+		return mirrorLocation(location, length), true
+	})
+
+	sequence.syncAfterEdit()
+}
+
+// setFeatureLocation updates a feature's location. GbkLocationString is cleared when the location
+// changes since genbank.Build would otherwise write out the stale string.
+func setFeatureLocation(feature *Feature, location Location) {
+	if !reflect.DeepEqual(feature.SequenceLocation, location) {
+		feature.SequenceLocation = location
+		feature.GbkLocationString = ""
+	}
+}
+
+// editGaps moves every gap in Meta.Gaps the way edit moves a location. Gaps the edit removes are dropped and gaps it
+// splits, like an insertion into a gap or a new origin inside one, become a gap per piece.
+func (sequence *Sequence) editGaps(edit func(location Location) (Location, bool)) {
+	var gaps []Gap
+	for _, gap := range sequence.Meta.Gaps {
+		location, kept := edit(Location{Start: gap.Start, End: gap.End})
+		if !kept {
+			continue
+		}
+		for _, part := range location.SplitAtOrigin(len(sequence.Sequence)).Ranges() {
+			gap.Start, gap.End = part.Start, part.End
+			gaps = append(gaps, gap)
+		}
+	}
+	// a new origin can move gaps from the end to the start.
+	sort.SliceStable(gaps, func(i, j int) bool { return gaps[i].Start < gaps[j].Start })
+	sequence.Meta.Gaps = gaps
+}
+
+// syncAfterEdit repoints every feature at its parent and updates recorded lengths after an edit.
+func (sequence *Sequence) syncAfterEdit() {
+	for featureIndex := range sequence.Features {
+		sequence.Features[featureIndex].ParentSequence = sequence
+	}
+
+	length := len(sequence.Sequence)
+	if sequence.Meta.Locus.SequenceLength != "" {
+		sequence.Meta.Locus.SequenceLength = strconv.Itoa(length)
+	}
+	if sequence.Meta.RegionEnd != 0 {
+		regionStart := sequence.Meta.RegionStart
+		if regionStart == 0 {
+			regionStart = 1
+		}
+		sequence.Meta.RegionEnd = regionStart + length - 1
+		sequence.Meta.Size = sequence.Meta.RegionEnd - sequence.Meta.RegionStart
+	}
+}
+
+// insertLocation shifts or splits a location around an insertion of length bases at position.
+func insertLocation(location Location, position int, length int) Location {
+	if len(location.SubLocations) == 0 {
+		switch {
+		case position <= location.Start:
+			location.Start += length
+			location.End += length
+		case position >= location.End:
+			// insertion is downstream so nothing to do.
+		default:
+			head := Location{Start: location.Start, End: position, FivePrimePartial: location.FivePrimePartial}
+			tail := Location{Start: position + length, End: location.End + length, ThreePrimePartial: location.ThreePrimePartial}
+			location = Location{Join: true, Complement: location.Complement, SubLocations: []Location{head, tail}}
+		}
+		return location
+	}
+
+	subLocations := []Location{}
+	for _, subLocation := range location.SubLocations {
+		newSubLocation := insertLocation(subLocation, position, length)
+		splitLeaf := len(subLocation.SubLocations) == 0 && len(newSubLocation.SubLocations) > 0
+		if location.Join && splitLeaf {
+			// flatten the split leaf into the parent join instead of nesting joins.
+			subLocations = append(subLocations, flattenJoin(newSubLocation)...)
+		} else {
+			subLocations = append(subLocations, newSubLocation)
+		}
+	}
+	location.SubLocations = subLocations
+	return location
+}
+
+// rotateLocation moves every range of a location to a new origin on a circular sequence of length bases.
+func rotateLocation(location Location, origin int, length int) Location {
+	if len(location.SubLocations) == 0 {
+		rangeLength := location.End - location.Start
+		if location.SpansOrigin() {
+			rangeLength += length
+		}
+
+		// features covering the whole sequence, like source features, stay that way.
+		if rangeLength == length {
+			location.Start = 0
+			location.End = length
+			return location
+		}
+
+		location.Start = (location.Start - origin + length) % length
+		location.End = location.Start + rangeLength
+		if location.End > length {
+			location.End -= length
+		}
+		return location
+	}
+
+	subLocations := make([]Location, len(location.SubLocations))
+	for subLocationIndex, subLocation := range location.SubLocations {
+		subLocations[subLocationIndex] = rotateLocation(subLocation, origin, length)
+	}
+	location.SubLocations = subLocations
+	return location
+}
+
+// mirrorLocation maps a location onto the reverse complement of a sequence of length bases. The mirrored
+// location reads the reverse complement of what the original location read.
+func mirrorLocation(location Location, length int) Location {
+	// partial flags are tied to the lower and upper coordinates, which swap when mirrored.
+	location.FivePrimePartial, location.ThreePrimePartial = location.ThreePrimePartial, location.FivePrimePartial
+
+	if len(location.SubLocations) == 0 {
+		location.Start, location.End = length-location.End, length-location.Start
+		return location
+	}
+
+	subLocations := make([]Location, len(location.SubLocations))
+	for subLocationIndex, subLocation := range location.SubLocations {
+		subLocations[len(subLocations)-1-subLocationIndex] = mirrorLocation(subLocation, length)
+	}
+	location.SubLocations = subLocations
+	return location
+}
+
+// deleteLocation shifts or trims a location around a deletion of [start, end). kept is false when
+// nothing of the location is left and broken is true when the location lost any of its bases.
+func deleteLocation(location Location, start, end int) (Location, bool, bool) {
+	length := end - start
+
+	if len(location.SubLocations) == 0 {
+		switch {
+		case end <= location.Start:
+			location.Start -= length
+			location.End -= length
+			return location, true, false
+		case start >= location.End:
+			return location, true, false
+		case start <= location.Start && end >= location.End:
+			return location, false, true
+		case start <= location.Start:
+			location.Start = start
+			location.End -= length
+			location.FivePrimePartial = true
+		case end >= location.End:
+			location.End = start
+			location.ThreePrimePartial = true
+		default:
+			// the deletion is internal so the location just contracts.
+			location.End -= length
+		}
+		return location, true, true
+	}
+
+	subLocations := []Location{}
+	broken := false
+	lostFirst := false
+	lostLast := false
+	for subLocationIndex, subLocation := range location.SubLocations {
+		newSubLocation, subKept, subBroken := deleteLocation(subLocation, start, end)
+		broken = broken || subBroken
+		if !subKept {
+			if subLocationIndex == 0 {
+				lostFirst = true
+			}
+			if subLocationIndex == len(location.SubLocations)-1 {
+				lostLast = true
+			}
+			continue
+		}
+		subLocations = append(subLocations, newSubLocation)
+	}
+
+	if len(subLocations) == 0 {
+		return location, false, true
+	}
+	if lostFirst {
+		subLocations[0].FivePrimePartial = true
+	}
+	if lostLast {
+		subLocations[len(subLocations)-1].ThreePrimePartial = true
+	}
+
+	// a join of one is just that one location.
+	if location.Join && len(subLocations) == 1 {
+		onlyLocation := subLocations[0]
+		onlyLocation.Complement = onlyLocation.Complement != location.Complement
+		return onlyLocation, true, broken
+	}
+
+	location.SubLocations = subLocations
+	return location, true, broken
+}
+
+/******************************************************************************
+
+Sequence editing ends here.
+
+******************************************************************************/
@@ -0,0 +1,283 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Open-Science-Global/poly/seqhash"
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleSequence_Insert() {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGG"
+
+	// annotate the T's.
+	var feature Feature
+	feature.Name = "T's"
+	feature.SequenceLocation.Start = 5
+	feature.SequenceLocation.End = 10
+	sequence.AddFeature(&feature)
+
+	// insert some C's upstream of the T's.
+	_ = sequence.Insert(2, "CCC")
+
+	fmt.Println(sequence.Features[0].GetSequence())
+	// Output: TTTTT
+}
+
+func ExampleSequence_Delete() {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGG"
+
+	var feature Feature
+	feature.Name = "T's"
+	feature.SequenceLocation.Start = 5
+	feature.SequenceLocation.End = 10
+	sequence.AddFeature(&feature)
+
+	// delete the first two T's. The feature is kept and marked partial.
+	_ = sequence.Delete(3, 7, MarkPartial)
+
+	location := sequence.Features[0].SequenceLocation
+	fmt.Println(sequence.Features[0].GetSequence(), location.FivePrimePartial)
+	// Output: TTT true
+}
+
+func TestSequence_InsertSplitsFeature(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGG"
+	sequence.Meta.Locus.SequenceLength = "15"
+
+	forward := Feature{Name: "forward", SequenceLocation: Location{Start: 5, End: 10}}
+	reverse := Feature{Name: "reverse", SequenceLocation: Location{Start: 5, End: 10, Complement: true}, GbkLocationString: "complement(6..10)"}
+	sequence.AddFeature(&forward)
+	sequence.AddFeature(&reverse)
+
+	if err := sequence.Insert(7, "CCC"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sequence.Sequence != "AAAAATTCCCTTTGGGGG" {
+		t.Errorf("Insert produced the wrong sequence. Got: %s", sequence.Sequence)
+	}
+	if sequence.Meta.Locus.SequenceLength != "18" {
+		t.Errorf("Insert did not update locus length. Got: %s", sequence.Meta.Locus.SequenceLength)
+	}
+	if got := sequence.Features[0].GetSequence(); got != "TTTTT" {
+		t.Errorf("Insert did not split forward feature around insertion. Got: %s", got)
+	}
+	if got := sequence.Features[1].GetSequence(); got != "AAAAA" {
+		t.Errorf("Insert did not split complement feature around insertion. Got: %s", got)
+	}
+	if sequence.Features[1].GbkLocationString != "" {
+		t.Errorf("Insert did not clear stale GbkLocationString")
+	}
+
+	if err := sequence.Insert(19, "A"); err == nil {
+		t.Errorf("Insert should fail for positions past the end of the sequence")
+	}
+}
+
+func TestSequence_EditGaps(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAAAAAAANNNNNAAAAAAAAAANNNNN"
+	sequence.Meta.Locus.Circular = true
+	sequence.Meta.Gaps = []Gap{{Start: 10, End: 15, Type: "scaffold"}, {Start: 25, End: 30}}
+
+	edits := []struct {
+		name string
+		edit func() error
+		gaps []Gap
+	}{
+		{"insert upstream", func() error { return sequence.Insert(0, "GGGGG") }, []Gap{{Start: 15, End: 20, Type: "scaffold"}, {Start: 30, End: 35}}},
+		{"insert into a gap", func() error { return sequence.Insert(17, "GG") }, []Gap{{Start: 15, End: 17, Type: "scaffold"}, {Start: 19, End: 22, Type: "scaffold"}, {Start: 32, End: 37}}},
+		{"delete", func() error { return sequence.Delete(0, 16, MarkPartial) }, []Gap{{Start: 0, End: 1, Type: "scaffold"}, {Start: 3, End: 6, Type: "scaffold"}, {Start: 16, End: 21}}},
+		{"rotate into a gap", func() error { return sequence.Rotate(18) }, []Gap{{Start: 0, End: 3}, {Start: 3, End: 4, Type: "scaffold"}, {Start: 6, End: 9, Type: "scaffold"}, {Start: 19, End: 21}}},
+	}
+	for _, test := range edits {
+		if err := test.edit(); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(test.gaps, sequence.Meta.Gaps); diff != "" {
+			t.Errorf("%s: gaps did not follow the edit (-want +got):\n%s", test.name, diff)
+		}
+	}
+}
+
+func TestSequence_DeletePolicies(t *testing.T) {
+	newSequence := func() Sequence {
+		var sequence Sequence
+		sequence.Sequence = "AAAAATTTTTGGGGGCCCCC"
+		join := Feature{Name: "join", SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 0, End: 5}, {Start: 10, End: 15}}}}
+		downstream := Feature{Name: "downstream", SequenceLocation: Location{Start: 15, End: 20}}
+		sequence.AddFeature(&join)
+		sequence.AddFeature(&downstream)
+		return sequence
+	}
+
+	// deleting the second part of the join collapses it and marks the remaining part partial.
+	sequence := newSequence()
+	if err := sequence.Delete(8, 15, MarkPartial); err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence.Features) != 2 {
+		t.Fatalf("MarkPartial should keep both features. Got %d", len(sequence.Features))
+	}
+	location := sequence.Features[0].SequenceLocation
+	if location.Join || location.Start != 0 || location.End != 5 || !location.ThreePrimePartial {
+		t.Errorf("Delete did not truncate join properly. Got: %+v", location)
+	}
+	if got := sequence.Features[1].GetSequence(); got != "CCCCC" {
+		t.Errorf("Delete did not shift downstream feature. Got: %s", got)
+	}
+
+	// the same deletion with DropBroken removes the join.
+	sequence = newSequence()
+	if err := sequence.Delete(8, 15, DropBroken); err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence.Features) != 1 || sequence.Features[0].Name != "downstream" {
+		t.Errorf("DropBroken should only keep the downstream feature. Got %d features", len(sequence.Features))
+	}
+
+	// deleting a whole feature always removes it.
+	sequence = newSequence()
+	if err := sequence.Delete(15, 20, MarkPartial); err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence.Features) != 1 || sequence.Features[0].Name != "join" {
+		t.Errorf("Delete should remove features with no bases left. Got %d features", len(sequence.Features))
+	}
+}
+
+func TestSequence_Replace(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGG"
+
+	feature := Feature{Name: "G's", SequenceLocation: Location{Start: 10, End: 15}}
+	sequence.AddFeature(&feature)
+
+	if err := sequence.Replace(5, 10, "C", MarkPartial); err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Sequence != "AAAAACGGGGG" {
+		t.Errorf("Replace produced the wrong sequence. Got: %s", sequence.Sequence)
+	}
+	if got := sequence.Features[0].GetSequence(); got != "GGGGG" {
+		t.Errorf("Replace did not shift downstream feature. Got: %s", got)
+	}
+}
+
+func ExampleSequence_Rotate() {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACC"
+	sequence.Meta.Locus.Circular = true
+
+	var feature Feature
+	feature.SequenceLocation.Start = 3
+	feature.SequenceLocation.End = 8
+	sequence.AddFeature(&feature)
+
+	// make the A's the start of the plasmid.
+	_ = sequence.Rotate(3)
+
+	fmt.Println(sequence.Sequence, sequence.Features[0].GetSequence())
+	// Output: AAAAACCGGG AAAAA
+}
+
+func TestSequence_Rotate(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACCTTTT"
+	sequence.Meta.Locus.Circular = true
+	sequence.Meta.Locus.MoleculeType = "DNA"
+
+	source := Feature{Name: "source", SequenceLocation: Location{Start: 0, End: 14}}
+	spanning := Feature{Name: "spanning", SequenceLocation: Location{Start: 12, End: 2}}
+	reverseJoin := Feature{Name: "reverse join", SequenceLocation: Location{Join: true, Complement: true, SubLocations: []Location{{Start: 1, End: 3}, {Start: 8, End: 10}}}}
+	sequence.AddFeature(&source)
+	sequence.AddFeature(&spanning)
+	sequence.AddFeature(&reverseJoin)
+
+	var expected []string
+	for _, feature := range sequence.Features {
+		expected = append(expected, feature.GetSequence())
+	}
+	hash, _ := seqhash.Hash(sequence.Sequence, "DNA", true, true)
+
+	for _, origin := range []int{2, 9, 13} {
+		rotated := sequence
+		rotated.Features = append([]Feature{}, sequence.Features...)
+		if err := rotated.Rotate(origin); err != nil {
+			t.Fatal(err)
+		}
+		if rotated.Sequence != sequence.Sequence[origin:]+sequence.Sequence[:origin] {
+			t.Errorf("Rotate(%d) produced the wrong sequence. Got: %s", origin, rotated.Sequence)
+		}
+		if got := rotated.Features[0].GetSequence(); got != rotated.Sequence {
+			t.Errorf("Rotate(%d) should keep source feature covering the whole sequence. Got: %s", origin, got)
+		}
+		for featureIndex := 1; featureIndex < len(rotated.Features); featureIndex++ {
+			feature := rotated.Features[featureIndex]
+			if got := feature.GetSequence(); got != expected[featureIndex] {
+				t.Errorf("Rotate(%d) broke feature %q. Got: %s instead of %s", origin, feature.Name, got, expected[featureIndex])
+			}
+		}
+		if rotatedHash, _ := seqhash.Hash(rotated.Sequence, "DNA", true, true); rotatedHash != hash {
+			t.Errorf("Rotate(%d) changed the seqhash of the sequence.", origin)
+		}
+	}
+
+	sequence.Meta.Locus.Circular = false
+	if err := sequence.Rotate(2); err == nil {
+		t.Errorf("Rotate should fail on linear sequences.")
+	}
+}
+
+func TestSequence_ReverseComplement(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACCTTTTA"
+	sequence.Meta.Locus.Circular = true
+
+	forward := Feature{Name: "forward", Strand: "+", SequenceLocation: Location{Start: 3, End: 8, FivePrimePartial: true}}
+	reverse := Feature{Name: "reverse", Strand: "-", SequenceLocation: Location{Start: 10, End: 14, Complement: true}}
+	join := Feature{Name: "join", SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 0, End: 2}, {Start: 8, End: 10, Complement: true}}}}
+	spanning := Feature{Name: "spanning", SequenceLocation: Location{Start: 13, End: 2}}
+	sequence.AddFeature(&forward)
+	sequence.AddFeature(&reverse)
+	sequence.AddFeature(&join)
+	sequence.AddFeature(&spanning)
+
+	var expected []string
+	for _, feature := range sequence.Features {
+		expected = append(expected, feature.GetSequence())
+	}
+
+	sequence.ReverseComplement()
+	if sequence.Sequence != "TAAAAGGTTTTTCCC" {
+		t.Errorf("ReverseComplement produced the wrong sequence. Got: %s", sequence.Sequence)
+	}
+	for featureIndex, feature := range sequence.Features {
+		if got := feature.GetSequence(); got != expected[featureIndex] {
+			t.Errorf("ReverseComplement broke feature %q. Got: %s instead of %s", feature.Name, got, expected[featureIndex])
+		}
+	}
+
+	location := sequence.Features[0].SequenceLocation
+	if location.Start != 7 || location.End != 12 || !location.Complement || location.FivePrimePartial || !location.ThreePrimePartial {
+		t.Errorf("ReverseComplement did not mirror forward feature. Got: %+v", location)
+	}
+	if sequence.Features[0].Strand != "-" || sequence.Features[1].Strand != "+" {
+		t.Errorf("ReverseComplement did not toggle strands.")
+	}
+	joinLocation := sequence.Features[2].SequenceLocation
+	if !joinLocation.Complement || joinLocation.SubLocations[0].Start != 5 || joinLocation.SubLocations[1].Start != 13 {
+		t.Errorf("ReverseComplement did not reverse join. Got: %+v", joinLocation)
+	}
+
+	// doing it twice gets us back where we started.
+	sequence.ReverseComplement()
+	if location := sequence.Features[0].SequenceLocation; location.Start != 3 || location.End != 8 || location.Complement || !location.FivePrimePartial {
+		t.Errorf("ReverseComplement twice should give back the original location. Got: %+v", location)
+	}
+}
@@ -61,8 +61,8 @@ func RemoveRepeat(repeatLen int) func(string) []Match {
 func AddMatchesToSequence(matches []Match, sequence poly.Sequence) poly.Sequence {
 	for _, match := range matches {
 		var feature poly.Feature
-		attributes := make(map[string]string)
-		attributes["label"] = match.Message
+		attributes := make(poly.Attributes)
+		attributes.Add("label", match.Message)
 		feature.Attributes = attributes
 		feature.Type = "Match"
 		feature.SequenceLocation.Start = match.Start
@@ -0,0 +1,133 @@
+package poly
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Open-Science-Global/poly/seqhash"
+)
+
+/******************************************************************************
+
+Sequence hashing begins here.
+
+Sequence.SequenceHash and Feature.SequenceHash hold seqhashes so records and
+features can be looked up and deduplicated across databases. See the seqhash
+package for how those are built.
+
+Hash fills them in for a whole record in one pass and VerifyHashes checks them
+later on, which is handy for catching features whose sequence was edited out
+from under their stored hash.
+
+Seqhash needs to know what kind of molecule it's hashing. That comes from
+the locus: proteins are "aa" coded, anything with RNA in its molecule type is
+RNA and everything else is DNA. DNA is double stranded and RNA single stranded
+unless the molecule type says otherwise with an ss- or ds- prefix.
+
+******************************************************************************/
+
+// HashFunction is the name written to SequenceHashFunction fields by Hash.
+const HashFunction = "seqhash"
+
+// Hash computes the seqhash of sequence and of every one of its features' sequences and stores them in their SequenceHash fields.
+// Features are hashed as linear sequences with the same molecule type as the record.
+// Records assembled from other records, like CON records, have nothing to hash until they're expanded and are an error,
+// as are features whose locations don't fit in the sequence. Nothing is stored unless every hash succeeds.
+func (sequence *Sequence) Hash() error {
+	if sequence.assembled() {
+		return errors.New(unexpandedMessage)
+	}
+	sequenceType, doubleStranded := moleculeType(sequence.Meta.Locus)
+
+	sequenceHash, err := seqhash.Hash(sequence.Sequence, sequenceType, sequence.Meta.Locus.Circular, doubleStranded)
+	if err != nil {
+		return err
+	}
+
+	// every feature is hashed before anything is stored so a failure leaves the record as it was.
+	featureHashes := make([]string, len(sequence.Features))
+	for featureIndex := range sequence.Features {
+		feature := &sequence.Features[featureIndex]
+		feature.ParentSequence = sequence
+		if messages := validateLocation(feature.SequenceLocation, len(sequence.Sequence), sequence.Meta.Locus.Circular); len(messages) > 0 {
+			return fmt.Errorf("feature %d: %s", featureIndex, messages[0])
+		}
+		featureHashes[featureIndex], err = seqhash.Hash(feature.GetSequence(), sequenceType, false, doubleStranded)
+		if err != nil {
+			return fmt.Errorf("feature %d: %w", featureIndex, err)
+		}
+	}
+
+	sequence.SequenceHash = sequenceHash
+	sequence.SequenceHashFunction = HashFunction
+	for featureIndex := range sequence.Features {
+		sequence.Features[featureIndex].SequenceHash = featureHashes[featureIndex]
+		sequence.Features[featureIndex].SequenceHashFunction = HashFunction
+	}
+	return nil
+}
+
+// VerifyHashes recomputes the seqhash of sequence and its features and returns an error issue for every stored hash that doesn't match.
+// Hashes that are empty or were made by some other hash function are skipped. Hashes of records assembled from other records,
+// like CON records, can't be checked until they're expanded so they get a warning instead, and hashes of features whose
+// locations don't fit in the sequence are errors.
+func (sequence Sequence) VerifyHashes() []ValidationIssue {
+	var issues []ValidationIssue
+	sequenceType, doubleStranded := moleculeType(sequence.Meta.Locus)
+	assembled := sequence.assembled()
+
+	verify := func(featureIndex int, storedHash, hashFunction string, getSequence func() (string, error), circular bool) {
+		if storedHash == "" || (hashFunction != "" && hashFunction != HashFunction) {
+			return
+		}
+		if assembled {
+			issues = append(issues, ValidationIssue{SeverityWarning, featureIndex, "could not verify hash: " + unexpandedMessage})
+			return
+		}
+		sequenceString, err := getSequence()
+		if err != nil {
+			issues = append(issues, ValidationIssue{SeverityError, featureIndex, "could not verify hash: " + err.Error()})
+			return
+		}
+		hash, err := seqhash.Hash(sequenceString, sequenceType, circular, doubleStranded)
+		switch {
+		case err != nil:
+			issues = append(issues, ValidationIssue{SeverityError, featureIndex, "could not verify hash: " + err.Error()})
+		case hash != storedHash:
+			issues = append(issues, ValidationIssue{SeverityError, featureIndex, fmt.Sprintf("stored hash %s does not match sequence hash %s", storedHash, hash)})
+		}
+	}
+
+	verify(-1, sequence.SequenceHash, sequence.SequenceHashFunction, func() (string, error) { return sequence.Sequence, nil }, sequence.Meta.Locus.Circular)
+	for featureIndex, feature := range sequence.Features {
+		feature.ParentSequence = &sequence
+		getFeatureSequence := func() (string, error) {
+			if messages := validateLocation(feature.SequenceLocation, len(sequence.Sequence), sequence.Meta.Locus.Circular); len(messages) > 0 {
+				return "", errors.New(messages[0])
+			}
+			return feature.GetSequence(), nil
+		}
+		verify(featureIndex, feature.SequenceHash, feature.SequenceHashFunction, getFeatureSequence, false)
+	}
+	return issues
+}
+
+// moleculeType returns the seqhash sequence type of a locus and whether it is double stranded.
+func moleculeType(locus Locus) (string, bool) {
+	molecule := strings.ToUpper(locus.MoleculeType)
+	switch {
+	case strings.EqualFold(locus.SequenceCoding, "aa") || molecule == "AA" || strings.Contains(molecule, "PROTEIN"):
+		return "PROTEIN", false
+	case strings.Contains(molecule, "RNA"):
+		return "RNA", strings.HasPrefix(molecule, "DS-")
+	default:
+		return "DNA", !strings.HasPrefix(molecule, "SS-")
+	}
+}
+
+/******************************************************************************
+
+Sequence hashing ends here.
+
+******************************************************************************/
@@ -0,0 +1,106 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Open-Science-Global/poly/seqhash"
+)
+
+func ExampleSequence_Hash() {
+	var sequence Sequence
+	sequence.Sequence = "ATGGAGAGCAAGTAA"
+	sequence.Meta.Locus.MoleculeType = "DNA"
+
+	cds := Feature{Type: "CDS", SequenceLocation: Location{Start: 0, End: 15}}
+	sequence.AddFeature(&cds)
+
+	_ = sequence.Hash()
+	fmt.Println(sequence.SequenceHash)
+	fmt.Println(sequence.Features[0].SequenceHash == sequence.SequenceHash)
+	// Output:
+	// v1_DLD_5c18c84a37a5668992e78ba74800921945aeeea945d8a083a51252c78ed6a997
+	// true
+}
+
+func TestSequence_Hash(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACC"
+	sequence.Meta.Locus.Circular = true
+
+	forward := Feature{SequenceLocation: Location{Start: 8, End: 3}}
+	reverse := Feature{SequenceLocation: Location{Start: 3, End: 8, Complement: true}}
+	sequence.AddFeature(&forward)
+	sequence.AddFeature(&reverse)
+
+	if err := sequence.Hash(); err != nil {
+		t.Fatal(err)
+	}
+	if expected, _ := seqhash.Hash("GGGAAAAACC", "DNA", true, true); sequence.SequenceHash != expected || sequence.SequenceHashFunction != HashFunction {
+		t.Errorf("Record hash is wrong. Got: %s %s", sequence.SequenceHash, sequence.SequenceHashFunction)
+	}
+	if expected, _ := seqhash.Hash("CCGGG", "DNA", false, true); sequence.Features[0].SequenceHash != expected {
+		t.Errorf("Origin spanning feature hash is wrong. Got: %s", sequence.Features[0].SequenceHash)
+	}
+	if issues := sequence.VerifyHashes(); len(issues) != 0 {
+		t.Errorf("Freshly hashed sequence should verify. Got: %v", issues)
+	}
+
+	// deleting a base inside the second feature should only break its hash and the record's.
+	if err := sequence.Delete(5, 6, MarkPartial); err != nil {
+		t.Fatal(err)
+	}
+	issues := sequence.VerifyHashes()
+	if len(issues) != 2 || issues[0].FeatureIndex != -1 || issues[1].FeatureIndex != 1 {
+		t.Errorf("VerifyHashes should report the record and the edited feature. Got: %v", issues)
+	}
+
+	// hashes from some other hash function are none of our business.
+	sequence.SequenceHashFunction = "md5"
+	sequence.Features[1].SequenceHash = ""
+	if issues := sequence.VerifyHashes(); len(issues) != 0 {
+		t.Errorf("VerifyHashes should skip foreign and empty hashes. Got: %v", issues)
+	}
+}
+
+func TestSequence_HashMoleculeTypes(t *testing.T) {
+	tests := []struct {
+		locus    Locus
+		sequence string
+		metadata string
+	}{
+		{Locus{MoleculeType: "mRNA"}, "AUGGCC", "v1_RLS_"},
+		{Locus{MoleculeType: "ds-RNA", Circular: true}, "AUGGCC", "v1_RCD_"},
+		{Locus{MoleculeType: "ss-DNA"}, "ATGGCC", "v1_DLS_"},
+		{Locus{SequenceCoding: "aa"}, "MKLV*", "v1_PLS_"},
+		{Locus{}, "ATGGCC", "v1_DLD_"},
+	}
+	for _, test := range tests {
+		sequence := Sequence{Sequence: test.sequence, Meta: Meta{Locus: test.locus}}
+		if err := sequence.Hash(); err != nil {
+			t.Errorf("Hash failed for %+v: %s", test.locus, err)
+			continue
+		}
+		if sequence.SequenceHash[:len(test.metadata)] != test.metadata {
+			t.Errorf("Hash for %+v should start with %s. Got: %s", test.locus, test.metadata, sequence.SequenceHash)
+		}
+	}
+
+	sequence := Sequence{Sequence: "ATG?"}
+	if err := sequence.Hash(); err == nil {
+		t.Errorf("Hash should fail on characters seqhash doesn't allow.")
+	}
+
+	// a feature that can't be hashed leaves every hash field alone.
+	sequence = Sequence{Sequence: "ATGCATGC", SequenceHash: "old", SequenceHashFunction: "md5"}
+	fits := Feature{SequenceLocation: Location{Start: 0, End: 4}, SequenceHash: "old"}
+	tooLong := Feature{SequenceLocation: Location{Start: 4, End: 20}}
+	sequence.AddFeature(&fits)
+	sequence.AddFeature(&tooLong)
+	if err := sequence.Hash(); err == nil {
+		t.Fatalf("Hash should fail on a feature past the end of the sequence.")
+	}
+	if sequence.SequenceHash != "old" || sequence.SequenceHashFunction != "md5" || sequence.Features[0].SequenceHash != "old" || sequence.Features[0].SequenceHashFunction != "" {
+		t.Errorf("A failed Hash should not store any hashes. Got record %s %s and feature %s %s", sequence.SequenceHash, sequence.SequenceHashFunction, sequence.Features[0].SequenceHash, sequence.Features[0].SequenceHashFunction)
+	}
+}
@@ -0,0 +1,228 @@
+package poly
+
+import (
+	"fmt"
+	"sort"
+)
+
+/******************************************************************************
+
+Feature hierarchy begins here.
+
+Gene models like gene -> mRNA -> exon/CDS are written in gff3 as flat lines
+tied together by ID and Parent attributes. A Hierarchy resolves those
+attributes into parent/child links so gene models can be walked.
+
+A few quirks of gff3 worth knowing:
+
+	- A feature can have more than one parent. Parent=mRNA1,mRNA2 is an exon
+	  shared by two transcripts.
+	- Discontinuous features like a CDS are written as several lines that share
+	  one ID. Every line with the same ID is the same feature so they share
+	  their parents and children.
+	- Nothing stops a file from naming a Parent that doesn't exist (an orphan)
+	  or from looping back on itself (a cycle). Both are reported rather than
+	  treated as errors so callers can decide how picky to be.
+
+Features are referred to by their index in Sequence.Features since features
+are stored by value.
+
+******************************************************************************/
+
+// Hierarchy holds the parent/child relationships between the features of a Sequence.
+type Hierarchy struct {
+	ids      map[string][]int
+	parents  [][]int
+	children [][]int
+	orphans  []int
+	cycles   [][]int
+}
+
+// Hierarchy resolves the ID and Parent attributes of a Sequence's features into a Hierarchy.
+func (sequence Sequence) Hierarchy() Hierarchy {
+	featureCount := len(sequence.Features)
+	hierarchy := Hierarchy{
+		ids:      make(map[string][]int),
+		parents:  make([][]int, featureCount),
+		children: make([][]int, featureCount),
+	}
+
+	for featureIndex, feature := range sequence.Features {
+		if id := feature.Attributes.Get("ID"); id != "" {
+			hierarchy.ids[id] = append(hierarchy.ids[id], featureIndex)
+		}
+	}
+
+	for featureIndex, feature := range sequence.Features {
+		orphan := false
+		for _, parentID := range feature.Attributes["Parent"] {
+			parentIndices, ok := hierarchy.ids[parentID]
+			if !ok {
+				orphan = true
+				continue
+			}
+			for _, parentIndex := range parentIndices {
+				hierarchy.parents[featureIndex] = appendUnique(hierarchy.parents[featureIndex], parentIndex)
+				hierarchy.children[parentIndex] = appendUnique(hierarchy.children[parentIndex], featureIndex)
+			}
+		}
+		if orphan {
+			hierarchy.orphans = append(hierarchy.orphans, featureIndex)
+		}
+	}
+
+	hierarchy.cycles = hierarchy.findCycles()
+	return hierarchy
+}
+
+// Lookup returns the indices of every feature with the given ID. Discontinuous features can have more than one.
+func (hierarchy Hierarchy) Lookup(id string) []int {
+	return append([]int{}, hierarchy.ids[id]...)
+}
+
+// Parents returns the indices of the direct parents of a feature.
+func (hierarchy Hierarchy) Parents(featureIndex int) []int {
+	return append([]int{}, hierarchy.parents[featureIndex]...)
+}
+
+// Children returns the indices of the direct children of a feature.
+func (hierarchy Hierarchy) Children(featureIndex int) []int {
+	return append([]int{}, hierarchy.children[featureIndex]...)
+}
+
+// Ancestors returns the indices of every feature above a feature, nearest first.
+func (hierarchy Hierarchy) Ancestors(featureIndex int) []int {
+	return walk(hierarchy.parents, featureIndex)
+}
+
+// Descendants returns the indices of every feature below a feature, nearest first.
+func (hierarchy Hierarchy) Descendants(featureIndex int) []int {
+	return walk(hierarchy.children, featureIndex)
+}
+
+// Roots returns the indices of features that have no parents and aren't orphans, like genes.
+func (hierarchy Hierarchy) Roots() []int {
+	var roots []int
+	orphans := make(map[int]bool, len(hierarchy.orphans))
+	for _, orphan := range hierarchy.orphans {
+		orphans[orphan] = true
+	}
+	for featureIndex, parents := range hierarchy.parents {
+		if len(parents) == 0 && !orphans[featureIndex] {
+			roots = append(roots, featureIndex)
+		}
+	}
+	return roots
+}
+
+// Orphans returns the indices of features with a Parent attribute that names an ID no feature has.
+func (hierarchy Hierarchy) Orphans() []int {
+	return append([]int{}, hierarchy.orphans...)
+}
+
+// Cycles returns every loop of features that are, through their parents, their own ancestors.
+// Each cycle is listed once as the feature indices along the loop starting from its lowest index.
+func (hierarchy Hierarchy) Cycles() [][]int {
+	cycles := make([][]int, len(hierarchy.cycles))
+	for cycleIndex, cycle := range hierarchy.cycles {
+		cycles[cycleIndex] = append([]int{}, cycle...)
+	}
+	return cycles
+}
+
+// walk does a breadth first walk over edges from start and returns every feature it reaches.
+// Features are only visited once so walking a cycle terminates.
+func walk(edges [][]int, start int) []int {
+	var visited []int
+	seen := map[int]bool{start: true}
+	queue := append([]int{}, edges[start]...)
+	for len(queue) > 0 {
+		featureIndex := queue[0]
+		queue = queue[1:]
+		if seen[featureIndex] {
+			continue
+		}
+		seen[featureIndex] = true
+		visited = append(visited, featureIndex)
+		queue = append(queue, edges[featureIndex]...)
+	}
+	return visited
+}
+
+// findCycles runs a depth first search up the parent links and records every back edge it finds as a cycle.
+func (hierarchy Hierarchy) findCycles() [][]int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(hierarchy.parents))
+	var path []int
+	var cycles [][]int
+	seenCycles := make(map[string]bool)
+
+	var visit func(featureIndex int)
+	visit = func(featureIndex int) {
+		state[featureIndex] = visiting
+		path = append(path, featureIndex)
+		for _, parentIndex := range hierarchy.parents[featureIndex] {
+			switch state[parentIndex] {
+			case unvisited:
+				visit(parentIndex)
+			case visiting:
+				// parentIndex is further down the path so everything from there to here is a loop.
+				var cycle []int
+				for pathIndex := len(path) - 1; pathIndex >= 0; pathIndex-- {
+					if path[pathIndex] == parentIndex {
+						cycle = append([]int{}, path[pathIndex:]...)
+						break
+					}
+				}
+				cycle = rotateToLowest(cycle)
+				key := fmt.Sprint(cycle)
+				if !seenCycles[key] {
+					seenCycles[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[featureIndex] = visited
+	}
+
+	for featureIndex := range hierarchy.parents {
+		if state[featureIndex] == unvisited {
+			visit(featureIndex)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// rotateToLowest rotates a cycle so it starts at its lowest feature index.
+func rotateToLowest(cycle []int) []int {
+	lowest := 0
+	for cycleIndex, featureIndex := range cycle {
+		if featureIndex < cycle[lowest] {
+			lowest = cycleIndex
+		}
+	}
+	rotated := append([]int{}, cycle[lowest:]...)
+	return append(rotated, cycle[:lowest]...)
+}
+
+func appendUnique(indices []int, index int) []int {
+	for _, existing := range indices {
+		if existing == index {
+			return indices
+		}
+	}
+	return append(indices, index)
+}
+
+/******************************************************************************
+
+Feature hierarchy ends here.
+
+******************************************************************************/
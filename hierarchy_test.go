@@ -0,0 +1,92 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// geneModel builds a small gff3 style gene model with a gene, two transcripts sharing an exon, and a two part CDS.
+func geneModel() Sequence {
+	var sequence Sequence
+	sequence.Sequence = "ATGAAACCCGGGTTTAAATAG"
+	features := []Feature{
+		{Type: "gene", Attributes: Attributes{"ID": {"gene1"}}, SequenceLocation: Location{Start: 0, End: 21}},
+		{Type: "mRNA", Attributes: Attributes{"ID": {"mRNA1"}, "Parent": {"gene1"}}, SequenceLocation: Location{Start: 0, End: 21}},
+		{Type: "mRNA", Attributes: Attributes{"ID": {"mRNA2"}, "Parent": {"gene1"}}, SequenceLocation: Location{Start: 0, End: 21}},
+		{Type: "exon", Attributes: Attributes{"Parent": {"mRNA1", "mRNA2"}}, SequenceLocation: Location{Start: 0, End: 6}},
+		{Type: "CDS", Attributes: Attributes{"ID": {"cds1"}, "Parent": {"mRNA1"}}, SequenceLocation: Location{Start: 0, End: 6}},
+		{Type: "CDS", Attributes: Attributes{"ID": {"cds1"}, "Parent": {"mRNA1"}}, SequenceLocation: Location{Start: 12, End: 21}},
+	}
+	for _, feature := range features {
+		sequence.AddFeature(&feature)
+	}
+	return sequence
+}
+
+func ExampleSequence_Hierarchy() {
+	sequence := geneModel()
+	hierarchy := sequence.Hierarchy()
+
+	for _, childIndex := range hierarchy.Children(1) {
+		fmt.Println(sequence.Features[childIndex].Type)
+	}
+	// Output:
+	// exon
+	// CDS
+	// CDS
+}
+
+func TestHierarchy(t *testing.T) {
+	sequence := geneModel()
+	hierarchy := sequence.Hierarchy()
+
+	if diff := cmp.Diff([]int{0}, hierarchy.Roots()); diff != "" {
+		t.Errorf("Roots got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 2}, hierarchy.Parents(3)); diff != "" {
+		t.Errorf("Shared exon should have both transcripts as parents. Got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{4, 5}, hierarchy.Lookup("cds1")); diff != "" {
+		t.Errorf("Lookup should find every line of a discontinuous feature. Got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 0}, hierarchy.Ancestors(5)); diff != "" {
+		t.Errorf("Ancestors got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 2, 3, 4, 5}, hierarchy.Descendants(0)); diff != "" {
+		t.Errorf("Descendants got this diff:\n%s", diff)
+	}
+	if len(hierarchy.Orphans()) != 0 || len(hierarchy.Cycles()) != 0 {
+		t.Errorf("Gene model should have no orphans or cycles. Got: %v %v", hierarchy.Orphans(), hierarchy.Cycles())
+	}
+}
+
+func TestHierarchyOrphansAndCycles(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "ATGAAACCCGGGTTTAAATAG"
+	features := []Feature{
+		{Type: "exon", Attributes: Attributes{"Parent": {"missing"}}},
+		{Type: "gene", Attributes: Attributes{"ID": {"a"}, "Parent": {"b"}}},
+		{Type: "gene", Attributes: Attributes{"ID": {"b"}, "Parent": {"a"}}},
+		{Type: "gene", Attributes: Attributes{"ID": {"self"}, "Parent": {"self"}}},
+		{Type: "exon", Attributes: Attributes{"Parent": {"a"}}},
+	}
+	for _, feature := range features {
+		sequence.AddFeature(&feature)
+	}
+	hierarchy := sequence.Hierarchy()
+
+	if diff := cmp.Diff([]int{0}, hierarchy.Orphans()); diff != "" {
+		t.Errorf("Orphans got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([][]int{{1, 2}, {3}}, hierarchy.Cycles()); diff != "" {
+		t.Errorf("Cycles got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 2}, hierarchy.Ancestors(4)); diff != "" {
+		t.Errorf("Ancestors should stop going around a cycle. Got this diff:\n%s", diff)
+	}
+	if roots := hierarchy.Roots(); len(roots) != 0 {
+		t.Errorf("Orphans and cycles should not be roots. Got: %v", roots)
+	}
+}
@@ -0,0 +1,276 @@
+package poly
+
+import "sort"
+
+/******************************************************************************
+
+Feature index begins here.
+
+Asking which features sit over a region by scanning Sequence.Features is fine
+for a plasmid and painfully slow for a genome. A FeatureIndex is an augmented
+interval tree built once from a Sequence that answers range, point and nearest
+feature queries in logarithmic time.
+
+The tree is stored as an array of intervals sorted by start. The middle of any
+slice of that array is the root of its subtree and every node remembers the
+largest end below it, which lets queries skip whole subtrees that end before
+the region of interest. Cgranges and friends use the same trick.
+
+Every location is broken down into the ranges it actually covers so features
+with joins only match where they have bases. Ranges spanning the origin of a
+circular sequence are split in two and queries with an end before their start
+wrap around the origin the same way locations do.
+
+Queries take a strand which is "+" or "-" to only match features on that
+strand, or "" to match everything. A feature is on the "-" strand if its
+location is complemented or its gff strand is "-".
+
+******************************************************************************/
+
+// FeatureIndex answers overlap queries over the features of a Sequence. Features are referred to by their index in Sequence.Features.
+type FeatureIndex struct {
+	length   int
+	circular bool
+	trees    map[string]intervalTree
+}
+
+type interval struct {
+	start        int
+	end          int
+	featureIndex int
+}
+
+// intervalTree is an implicit balanced tree over intervals sorted by start. maxEnds holds the largest end in each node's subtree.
+// prefixMaxEnds holds the largest end among intervals[:i+1] for finding the closest feature to the left of a position.
+type intervalTree struct {
+	intervals     []interval
+	maxEnds       []int
+	prefixMaxEnds []int
+}
+
+// FeatureIndex builds a FeatureIndex over a Sequence's features.
+func (sequence Sequence) FeatureIndex() FeatureIndex {
+	length := len(sequence.Sequence)
+	index := FeatureIndex{length: length, circular: sequence.Meta.Locus.Circular}
+
+	intervalsByStrand := make(map[string][]interval)
+	for featureIndex, feature := range sequence.Features {
+		strand := "+"
+		if feature.SequenceLocation.Complement || feature.Strand == "-" {
+			strand = "-"
+		}
+		for _, leaf := range leafRanges(feature.SequenceLocation, length) {
+			leaf.featureIndex = featureIndex
+			intervalsByStrand[""] = append(intervalsByStrand[""], leaf)
+			intervalsByStrand[strand] = append(intervalsByStrand[strand], leaf)
+		}
+	}
+
+	index.trees = make(map[string]intervalTree, 3)
+	for _, strand := range []string{"", "+", "-"} {
+		index.trees[strand] = newIntervalTree(intervalsByStrand[strand])
+	}
+	return index
+}
+
+// Overlapping returns the indices of features with bases in the 0-based half-open range [start, end).
+// On circular sequences an end before start wraps around the origin.
+func (index FeatureIndex) Overlapping(start, end int, strand string) []int {
+	tree := index.trees[strand]
+	var hits []interval
+	if end < start && index.circular {
+		hits = tree.overlapping(start, index.length, hits)
+		hits = tree.overlapping(0, end, hits)
+	} else {
+		hits = tree.overlapping(start, end, hits)
+	}
+	return uniqueFeatureIndices(hits)
+}
+
+// At returns the indices of features covering the 0-based position.
+func (index FeatureIndex) At(position int, strand string) []int {
+	return index.Overlapping(position, position+1, strand)
+}
+
+// Nearest returns the indices of the features closest to the 0-based position along with their distance in bases.
+// Features covering the position are at distance 0. Ties are all returned. On circular sequences distance is measured
+// in whichever direction around the origin is shorter. If there are no features distance is -1.
+func (index FeatureIndex) Nearest(position int, strand string) ([]int, int) {
+	if overlapping := index.At(position, strand); len(overlapping) > 0 {
+		return overlapping, 0
+	}
+
+	tree := index.trees[strand]
+	if len(tree.intervals) == 0 {
+		return nil, -1
+	}
+
+	type candidate struct {
+		distance  int
+		intervals []interval
+	}
+	var candidates []candidate
+
+	// closest to the right is whatever starts first after position.
+	right := sort.Search(len(tree.intervals), func(i int) bool { return tree.intervals[i].start > position })
+	if right < len(tree.intervals) {
+		candidates = append(candidates, candidate{tree.intervals[right].start - position, tree.startingAt(tree.intervals[right].start)})
+	}
+
+	// closest to the left is whatever ends last before position.
+	if leftEnd := tree.maxEndBefore(right); leftEnd > 0 {
+		candidates = append(candidates, candidate{position - leftEnd + 1, tree.endingAt(leftEnd)})
+	}
+
+	if index.circular {
+		// looking right past the origin finds the first feature of the sequence and looking left past it finds the last.
+		first := tree.intervals[0].start
+		candidates = append(candidates, candidate{first + index.length - position, tree.startingAt(first)})
+		last := tree.maxEndBefore(len(tree.intervals))
+		candidates = append(candidates, candidate{position + index.length - last + 1, tree.endingAt(last)})
+	}
+
+	distance := -1
+	var hits []interval
+	for _, candidate := range candidates {
+		switch {
+		case distance == -1 || candidate.distance < distance:
+			distance = candidate.distance
+			hits = append([]interval{}, candidate.intervals...)
+		case candidate.distance == distance:
+			hits = append(hits, candidate.intervals...)
+		}
+	}
+	return uniqueFeatureIndices(hits), distance
+}
+
+// leafRanges flattens a location into the plain ranges it covers. Remote locations and sites between bases cover nothing here.
+func leafRanges(location Location, sequenceLength int) []interval {
+	if len(location.SubLocations) > 0 {
+		var leaves []interval
+		for _, subLocation := range location.SubLocations {
+			leaves = append(leaves, leafRanges(subLocation, sequenceLength)...)
+		}
+		return leaves
+	}
+	if location.Accession != "" || location.Between {
+		return nil
+	}
+	if location.SpansOrigin() || location.End > sequenceLength {
+		end := location.End
+		if end > sequenceLength {
+			end -= sequenceLength
+		}
+		return []interval{{start: location.Start, end: sequenceLength}, {start: 0, end: end}}
+	}
+	return []interval{{start: location.Start, end: location.End}}
+}
+
+func newIntervalTree(intervals []interval) intervalTree {
+	sort.Slice(intervals, func(i, j int) bool {
+		if intervals[i].start != intervals[j].start {
+			return intervals[i].start < intervals[j].start
+		}
+		return intervals[i].end < intervals[j].end
+	})
+	tree := intervalTree{intervals: intervals, maxEnds: make([]int, len(intervals)), prefixMaxEnds: make([]int, len(intervals))}
+	tree.buildMaxEnds(0, len(intervals))
+	maxEnd := 0
+	for intervalIndex, interval := range intervals {
+		if interval.end > maxEnd {
+			maxEnd = interval.end
+		}
+		tree.prefixMaxEnds[intervalIndex] = maxEnd
+	}
+	return tree
+}
+
+// buildMaxEnds fills in maxEnds for the subtree over intervals[low:high] and returns its largest end.
+func (tree intervalTree) buildMaxEnds(low, high int) int {
+	if low >= high {
+		return 0
+	}
+	middle := (low + high) / 2
+	maxEnd := tree.intervals[middle].end
+	if leftEnd := tree.buildMaxEnds(low, middle); leftEnd > maxEnd {
+		maxEnd = leftEnd
+	}
+	if rightEnd := tree.buildMaxEnds(middle+1, high); rightEnd > maxEnd {
+		maxEnd = rightEnd
+	}
+	tree.maxEnds[middle] = maxEnd
+	return maxEnd
+}
+
+// overlapping appends every interval overlapping [start, end) to hits.
+func (tree intervalTree) overlapping(start, end int, hits []interval) []interval {
+	return tree.search(0, len(tree.intervals), start, end, hits)
+}
+
+func (tree intervalTree) search(low, high, start, end int, hits []interval) []interval {
+	if low >= high {
+		return hits
+	}
+	middle := (low + high) / 2
+	// nothing in this subtree reaches start.
+	if tree.maxEnds[middle] <= start {
+		return hits
+	}
+	hits = tree.search(low, middle, start, end, hits)
+	// everything right of middle starts at or after middle so it can only overlap if middle starts before end.
+	if tree.intervals[middle].start < end {
+		if tree.intervals[middle].end > start {
+			hits = append(hits, tree.intervals[middle])
+		}
+		hits = tree.search(middle+1, high, start, end, hits)
+	}
+	return hits
+}
+
+// maxEndBefore returns the largest end among intervals[:count].
+func (tree intervalTree) maxEndBefore(count int) int {
+	if count == 0 {
+		return 0
+	}
+	return tree.prefixMaxEnds[count-1]
+}
+
+// startingAt returns every interval starting at start.
+func (tree intervalTree) startingAt(start int) []interval {
+	var hits []interval
+	for i := sort.Search(len(tree.intervals), func(i int) bool { return tree.intervals[i].start >= start }); i < len(tree.intervals) && tree.intervals[i].start == start; i++ {
+		hits = append(hits, tree.intervals[i])
+	}
+	return hits
+}
+
+// endingAt returns every interval ending at end.
+func (tree intervalTree) endingAt(end int) []interval {
+	var hits []interval
+	for _, hit := range tree.overlapping(end-1, end, nil) {
+		if hit.end == end {
+			hits = append(hits, hit)
+		}
+	}
+	return hits
+}
+
+// uniqueFeatureIndices returns the sorted feature indices of intervals without repeats.
+func uniqueFeatureIndices(intervals []interval) []int {
+	seen := make(map[int]bool, len(intervals))
+	var featureIndices []int
+	for _, interval := range intervals {
+		if !seen[interval.featureIndex] {
+			seen[interval.featureIndex] = true
+			featureIndices = append(featureIndices, interval.featureIndex)
+		}
+	}
+	sort.Ints(featureIndices)
+	return featureIndices
+}
+
+/******************************************************************************
+
+Feature index ends here.
+
+******************************************************************************/
@@ -0,0 +1,117 @@
+package poly
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleSequence_FeatureIndex() {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGGCCCCC"
+
+	promoter := Feature{Name: "promoter", SequenceLocation: Location{Start: 0, End: 5}}
+	gene := Feature{Name: "gene", SequenceLocation: Location{Start: 5, End: 15}}
+	terminator := Feature{Name: "terminator", SequenceLocation: Location{Start: 17, End: 20}}
+	sequence.AddFeature(&promoter)
+	sequence.AddFeature(&gene)
+	sequence.AddFeature(&terminator)
+
+	index := sequence.FeatureIndex()
+	for _, featureIndex := range index.Overlapping(3, 8, "") {
+		fmt.Println(sequence.Features[featureIndex].Name)
+	}
+	// Output:
+	// promoter
+	// gene
+}
+
+func TestFeatureIndex(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGGCCCCCAAAAATTTTT"
+	sequence.Meta.Locus.Circular = true
+
+	features := []Feature{
+		{Name: "forward", SequenceLocation: Location{Start: 2, End: 6}},
+		{Name: "reverse", SequenceLocation: Location{Start: 4, End: 8, Complement: true}},
+		{Name: "gff reverse", Strand: "-", SequenceLocation: Location{Start: 20, End: 22}},
+		{Name: "spliced", SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 10, End: 12}, {Start: 16, End: 18}}}},
+		{Name: "spanning", SequenceLocation: Location{Start: 27, End: 1}},
+	}
+	for _, feature := range features {
+		sequence.AddFeature(&feature)
+	}
+	index := sequence.FeatureIndex()
+
+	tests := []struct {
+		name     string
+		got      []int
+		expected []int
+	}{
+		{"range", index.Overlapping(5, 11, ""), []int{0, 1, 3}},
+		{"forward strand", index.Overlapping(5, 11, "+"), []int{0, 3}},
+		{"reverse strand", index.Overlapping(0, 30, "-"), []int{1, 2}},
+		{"point in intron", index.At(14, ""), nil},
+		{"point in exon", index.At(17, ""), []int{3}},
+		{"point across origin", index.At(0, ""), []int{4}},
+		{"range across origin", index.Overlapping(29, 3, ""), []int{0, 4}},
+	}
+	for _, test := range tests {
+		if diff := cmp.Diff(test.expected, test.got); diff != "" {
+			t.Errorf("%s query got this diff:\n%s", test.name, diff)
+		}
+	}
+
+	nearest, distance := index.Nearest(14, "")
+	if diff := cmp.Diff([]int{3}, nearest); diff != "" || distance != 2 {
+		t.Errorf("Nearest to an intron got distance %d and this diff:\n%s", distance, diff)
+	}
+	nearest, distance = index.Nearest(24, "-")
+	if diff := cmp.Diff([]int{2}, nearest); diff != "" || distance != 3 {
+		t.Errorf("Nearest on reverse strand got distance %d and this diff:\n%s", distance, diff)
+	}
+	nearest, distance = index.Nearest(9, "-")
+	if diff := cmp.Diff([]int{1}, nearest); diff != "" || distance != 2 {
+		t.Errorf("Nearest on reverse strand got distance %d and this diff:\n%s", distance, diff)
+	}
+
+	// 2 bases right of the end of the sequence wraps around to the feature at the start.
+	sequence.Features = sequence.Features[:1]
+	index = sequence.FeatureIndex()
+	nearest, distance = index.Nearest(28, "")
+	if diff := cmp.Diff([]int{0}, nearest); diff != "" || distance != 4 {
+		t.Errorf("Nearest across origin got distance %d and this diff:\n%s", distance, diff)
+	}
+
+	if nearest, distance := (Sequence{}).FeatureIndex().Nearest(5, ""); nearest != nil || distance != -1 {
+		t.Errorf("Nearest without features should find nothing. Got: %v %d", nearest, distance)
+	}
+}
+
+func TestFeatureIndexMatchesLinearScan(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	var sequence Sequence
+	sequence.Sequence = string(make([]byte, 10000))
+	for featureIndex := 0; featureIndex < 500; featureIndex++ {
+		start := random.Intn(9900)
+		feature := Feature{SequenceLocation: Location{Start: start, End: start + 1 + random.Intn(100)}}
+		sequence.AddFeature(&feature)
+	}
+	index := sequence.FeatureIndex()
+
+	for query := 0; query < 200; query++ {
+		start := random.Intn(9990)
+		end := start + 1 + random.Intn(10)
+		var expected []int
+		for featureIndex, feature := range sequence.Features {
+			if feature.SequenceLocation.Start < end && feature.SequenceLocation.End > start {
+				expected = append(expected, featureIndex)
+			}
+		}
+		if diff := cmp.Diff(expected, index.Overlapping(start, end, "")); diff != "" {
+			t.Fatalf("Overlapping(%d, %d) does not match a linear scan. Got this diff:\n%s", start, end, diff)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package bed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/flatfile"
+)
+
+/******************************************************************************
+
+BED specific IO related things begin here.
+
+BED is the interval format genome browsers like UCSC and IGV read. Every line
+is a feature with at least three tab separated columns and at most twelve:
+
+	chrom  chromStart  chromEnd  name  score  strand  thickStart  thickEnd  itemRgb  blockCount  blockSizes  blockStarts
+	pUC19  145         1082      lacZ  0      -       145         1082      0,0,255  2           100,200,    0,737,
+
+BED coordinates are 0-based and half-open just like poly.Location so
+chromStart and chromEnd are a location's Start and End as they are. Features
+with joined locations, like spliced CDSs, are written as BED12 blocks whose
+starts are relative to chromStart.
+
+Build writes every feature of a sequence as a BED12 line. BED has no types so
+the name column is the feature's Name, label, gene or locus_tag, falling back
+on its type. The itemRgb column comes from a color attribute, written either
+as "r,g,b" or "#rrggbb", or the ApEinfo colors SnapGene and ApE use.
+
+Parse reads a BED file of any width into features and AddFeatures puts the
+features on a sequence's chrom onto it. Features are typed misc_feature and
+keep their name, color and thick range as attributes so they come back out
+of Build the way they went in.
+
+Features across the origin of circular sequences are written with an end
+past the length of the sequence like GFF3 does and are merged back into a
+single range by AddFeatures.
+
+The format is described here:
+
+https://genome.ucsc.edu/FAQ/FAQformat.html#format1
+
+******************************************************************************/
+
+// The kinds of bad line a BED file can have, wrapped in a ParseError. Use errors.Is to check for them.
+var (
+	ErrInvalidColumns     = errors.New("invalid columns")
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+	ErrInvalidBlocks      = errors.New("invalid blocks")
+)
+
+// ParseError is a problem with a specific line of a BED file. track and browser lines count towards its
+// line number.
+type ParseError = flatfile.ParseError
+
+// Parse parses a BED file into features. Every feature's Name is the chrom it is on. The first problem found in the
+// file is returned as a *ParseError.
+func Parse(file []byte) ([]poly.Feature, error) {
+	var features []poly.Feature
+	for lineIndex, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// track, browser and comment lines are for genome browsers.
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "track") || strings.HasPrefix(line, "browser") {
+			continue
+		}
+		feature, err := parseFeature(line)
+		if err != nil {
+			return nil, &ParseError{Line: lineIndex + 1, Text: line, Err: err}
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+// Read reads a BED file from path into features.
+func Read(path string) ([]poly.Feature, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(file)
+}
+
+// parseFeature parses a single BED line.
+func parseFeature(line string) (poly.Feature, error) {
+	// BED is meant to be tab separated but plenty of files use spaces.
+	var fields []string
+	if strings.Contains(line, "\t") {
+		fields = strings.Split(line, "\t")
+	} else {
+		fields = strings.Fields(line)
+	}
+	if len(fields) < 3 || len(fields) > 12 {
+		return poly.Feature{}, fmt.Errorf("%w: expected 3 to 12 columns but got %d", ErrInvalidColumns, len(fields))
+	}
+
+	feature := poly.Feature{Name: fields[0], Type: "misc_feature", Attributes: make(poly.Attributes)}
+	start, startErr := strconv.Atoi(fields[1])
+	end, endErr := strconv.Atoi(fields[2])
+	if startErr != nil || endErr != nil || start < 0 || start > end {
+		return feature, fmt.Errorf("%w: chromStart %s and chromEnd %s", ErrInvalidCoordinates, fields[1], fields[2])
+	}
+	feature.SequenceLocation = poly.Location{Start: start, End: end}
+
+	if len(fields) > 3 && fields[3] != "." && fields[3] != "" {
+		feature.Attributes["Name"] = []string{fields[3]}
+	}
+	if len(fields) > 4 && fields[4] != "." {
+		feature.Score = fields[4]
+	}
+	if len(fields) > 5 {
+		switch fields[5] {
+		case "+", ".":
+		case "-":
+			feature.SequenceLocation.Complement = true
+		default:
+			return feature, fmt.Errorf("%w: strand must be one of + - or . but got %q", ErrInvalidColumns, fields[5])
+		}
+		feature.Strand = fields[5]
+	}
+	if len(fields) > 7 {
+		thickStart, thickStartErr := strconv.Atoi(fields[6])
+		thickEnd, thickEndErr := strconv.Atoi(fields[7])
+		if thickStartErr != nil || thickEndErr != nil || thickStart > thickEnd || thickStart < start || thickEnd > end {
+			return feature, fmt.Errorf("%w: thickStart %s and thickEnd %s", ErrInvalidCoordinates, fields[6], fields[7])
+		}
+		// the thick range is only worth keeping if it isn't the whole feature.
+		if thickStart != start || thickEnd != end {
+			feature.Attributes["thick_start"] = []string{fields[6]}
+			feature.Attributes["thick_end"] = []string{fields[7]}
+		}
+	}
+	if len(fields) > 8 && fields[8] != "0" && fields[8] != "." && fields[8] != "" {
+		feature.Attributes["color"] = []string{fields[8]}
+	}
+	if len(fields) > 9 {
+		blocks, err := parseBlocks(fields[9:], start, end)
+		if err != nil {
+			return feature, err
+		}
+		if len(blocks) > 1 {
+			feature.SequenceLocation.Join = true
+			feature.SequenceLocation.SubLocations = blocks
+		}
+	}
+	return feature, nil
+}
+
+// parseBlocks parses the blockCount, blockSizes and blockStarts columns into the ranges they cover.
+func parseBlocks(fields []string, start, end int) ([]poly.Location, error) {
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%w: blockCount, blockSizes and blockStarts go together", ErrInvalidBlocks)
+	}
+	blockCount, err := strconv.Atoi(fields[0])
+	sizes := strings.Split(strings.TrimSuffix(fields[1], ","), ",")
+	starts := strings.Split(strings.TrimSuffix(fields[2], ","), ",")
+	if err != nil || blockCount < 1 || len(sizes) != blockCount || len(starts) != blockCount {
+		return nil, fmt.Errorf("%w: blockCount %s doesn't match %d sizes and %d starts", ErrInvalidBlocks, fields[0], len(sizes), len(starts))
+	}
+
+	blocks := make([]poly.Location, blockCount)
+	for blockIndex := range blocks {
+		size, sizeErr := strconv.Atoi(sizes[blockIndex])
+		blockStart, startErr := strconv.Atoi(starts[blockIndex])
+		if sizeErr != nil || startErr != nil || size < 0 || blockStart < 0 {
+			return nil, fmt.Errorf("%w: block %d has size %s and start %s", ErrInvalidBlocks, blockIndex+1, sizes[blockIndex], starts[blockIndex])
+		}
+		blocks[blockIndex] = poly.Location{Start: start + blockStart, End: start + blockStart + size}
+		if blockIndex > 0 && blocks[blockIndex].Start < blocks[blockIndex-1].End {
+			return nil, fmt.Errorf("%w: blocks overlap or are out of order", ErrInvalidBlocks)
+		}
+	}
+	// the blocks have to cover the feature from end to end.
+	if blocks[0].Start != start || blocks[blockCount-1].End != end {
+		return nil, fmt.Errorf("%w: blocks don't span chromStart to chromEnd", ErrInvalidBlocks)
+	}
+	return blocks, nil
+}
+
+// AddFeatures adds the features on the sequence's chrom onto it and returns how many were added. The chrom is the
+// sequence's Meta.Name, Locus.Name or Accession and features on other chroms are left out. An error is returned if a
+// feature runs past the end of the sequence, in which case no features are added.
+func AddFeatures(sequence *poly.Sequence, features []poly.Feature) (int, error) {
+	chrom := sequence.DisplayName()
+	sequenceLength := len(sequence.Sequence)
+
+	var added []poly.Feature
+	for _, feature := range features {
+		if feature.Name != chrom {
+			continue
+		}
+		if sequenceLength > 0 && feature.SequenceLocation.End > sequenceLength {
+			if !sequence.Meta.Locus.Circular || feature.SequenceLocation.End > 2*sequenceLength {
+				return 0, fmt.Errorf("%s ends at %d past the end of %s at %d", featureName(feature), feature.SequenceLocation.End, chrom, sequenceLength)
+			}
+			feature.SequenceLocation = wrapAtOrigin(feature.SequenceLocation, sequenceLength)
+		}
+		added = append(added, feature)
+	}
+	for _, feature := range added {
+		sequence.AddFeature(&feature)
+	}
+	return len(added), nil
+}
+
+// wrapAtOrigin turns a location that runs past the end of a circular sequence into poly's origin spanning form.
+func wrapAtOrigin(location poly.Location, sequenceLength int) poly.Location {
+	if len(location.SubLocations) == 0 {
+		location.End -= sequenceLength
+		return location
+	}
+
+	var subLocations []poly.Location
+	for _, subLocation := range location.SubLocations {
+		switch {
+		case subLocation.Start >= sequenceLength:
+			subLocations = append(subLocations, poly.Location{Start: subLocation.Start - sequenceLength, End: subLocation.End - sequenceLength})
+		case subLocation.End > sequenceLength:
+			subLocations = append(subLocations, poly.Location{Start: subLocation.Start, End: sequenceLength}, poly.Location{Start: 0, End: subLocation.End - sequenceLength})
+		default:
+			subLocations = append(subLocations, subLocation)
+		}
+	}
+	location.SubLocations = subLocations
+	location.Start = subLocations[0].Start
+	location.End = subLocations[len(subLocations)-1].End
+	return location.MergeAtOrigin(sequenceLength)
+}
+
+// Build builds a BED12 line for every feature of a sequence.
+func Build(sequence poly.Sequence) []byte {
+	var bedBuffer bytes.Buffer
+	chrom := sequence.DisplayName()
+	for _, feature := range sequence.Features {
+		bedBuffer.WriteString(buildFeatureString(chrom, len(sequence.Sequence), feature))
+	}
+	return bedBuffer.Bytes()
+}
+
+// Write writes the features of a sequence out to path as a BED12 file.
+func Write(sequence poly.Sequence, path string) {
+	_ = ioutil.WriteFile(path, Build(sequence), 0644)
+}
+
+// featureName returns what goes in the name column of a feature.
+func featureName(feature poly.Feature) string {
+	for _, attribute := range []string{"Name", "label", "gene", "locus_tag"} {
+		if name := feature.Attributes.Get(attribute); name != "" {
+			return name
+		}
+	}
+	if feature.Type != "" {
+		return feature.Type
+	}
+	return "."
+}
+
+// buildFeatureString builds the BED12 line of a single feature.
+func buildFeatureString(chrom string, sequenceLength int, feature poly.Feature) string {
+	blocks := feature.SequenceLocation.SplitAtOrigin(sequenceLength).Ranges()
+	// blocks after the origin of a circular sequence are written past its end. They're the ones on the far side of the
+	// split SplitAtOrigin made, which comes first or last depending on which way the parts are listed.
+	if feature.SequenceLocation.SpansOrigin() {
+		for blockIndex := 1; blockIndex < len(blocks); blockIndex++ {
+			var afterOrigin []poly.Location
+			if blocks[blockIndex-1].End == sequenceLength && blocks[blockIndex].Start == 0 {
+				afterOrigin = blocks[blockIndex:]
+			} else if blocks[blockIndex-1].Start == 0 && blocks[blockIndex].End == sequenceLength {
+				afterOrigin = blocks[:blockIndex]
+			}
+			for afterIndex := range afterOrigin {
+				afterOrigin[afterIndex].Start += sequenceLength
+				afterOrigin[afterIndex].End += sequenceLength
+			}
+			if afterOrigin != nil {
+				break
+			}
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Start < blocks[j].Start })
+	// BED blocks can't overlap, so join parts that do are merged into one block.
+	merged := blocks[:1]
+	for _, block := range blocks[1:] {
+		previous := &merged[len(merged)-1]
+		if block.Start < previous.End {
+			if block.End > previous.End {
+				previous.End = block.End
+			}
+			continue
+		}
+		merged = append(merged, block)
+	}
+	blocks = merged
+	chromStart := blocks[0].Start
+	chromEnd := blocks[len(blocks)-1].End
+
+	// BED scores are whole numbers between 0 and 1000.
+	score := "0"
+	if value, err := strconv.Atoi(feature.Score); err == nil && value >= 0 && value <= 1000 {
+		score = feature.Score
+	}
+
+	// BED has no "?" strand, unknown strands are written as ".".
+	strand := feature.Strand
+	switch strand {
+	case "":
+		strand = feature.SequenceLocation.Strand()
+	case "?":
+		strand = "."
+	}
+
+	thickStart, thickEnd := strconv.Itoa(chromStart), strconv.Itoa(chromEnd)
+	if feature.Attributes.Get("thick_start") != "" && feature.Attributes.Get("thick_end") != "" {
+		thickStart, thickEnd = feature.Attributes.Get("thick_start"), feature.Attributes.Get("thick_end")
+	}
+
+	var blockSizes, blockStarts strings.Builder
+	for _, block := range blocks {
+		blockSizes.WriteString(strconv.Itoa(block.End-block.Start) + ",")
+		blockStarts.WriteString(strconv.Itoa(block.Start-chromStart) + ",")
+	}
+
+	columns := []string{chrom, strconv.Itoa(chromStart), strconv.Itoa(chromEnd), featureName(feature), score, strand, thickStart, thickEnd, featureColor(feature), strconv.Itoa(len(blocks)), blockSizes.String(), blockStarts.String()}
+	return strings.Join(columns, "\t") + "\n"
+}
+
+// featureColor returns the itemRgb column of a feature. Colors can be "r,g,b" or "#rrggbb" and features without one
+// get 0, which genome browsers draw in the track's color.
+func featureColor(feature poly.Feature) string {
+	color := feature.Attributes.Get("color")
+	if color == "" {
+		color = feature.Attributes.Get("ApEinfo_fwdcolor")
+		if feature.SequenceLocation.Complement && feature.Attributes.Get("ApEinfo_revcolor") != "" {
+			color = feature.Attributes.Get("ApEinfo_revcolor")
+		}
+	}
+
+	if strings.HasPrefix(color, "#") && len(color) == 7 {
+		red, redErr := strconv.ParseUint(color[1:3], 16, 8)
+		green, greenErr := strconv.ParseUint(color[3:5], 16, 8)
+		blue, blueErr := strconv.ParseUint(color[5:7], 16, 8)
+		if redErr == nil && greenErr == nil && blueErr == nil {
+			return fmt.Sprintf("%d,%d,%d", red, green, blue)
+		}
+	}
+	if rgb := strings.Split(color, ","); len(rgb) == 3 {
+		for _, value := range rgb {
+			if number, err := strconv.Atoi(value); err != nil || number < 0 || number > 255 {
+				return "0"
+			}
+		}
+		return color
+	}
+	return "0"
+}
+
+/******************************************************************************
+
+BED specific IO related things end here.
+
+******************************************************************************/
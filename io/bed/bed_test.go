@@ -0,0 +1,176 @@
+package bed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/genbank"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ExampleBuild() {
+	sequence := poly.Sequence{Meta: poly.Meta{Name: "chr1"}, Sequence: strings.Repeat("atgc", 100)}
+	cds := poly.Feature{Type: "CDS", Strand: "-", Attributes: poly.Attributes{"gene": {"abcA"}, "color": {"#ff0000"}}}
+	cds.SequenceLocation = poly.Location{Start: 10, End: 200, Join: true, Complement: true, SubLocations: []poly.Location{{Start: 10, End: 50}, {Start: 150, End: 200}}}
+	sequence.AddFeature(&cds)
+
+	fmt.Print(string(Build(sequence)))
+	// Output: chr1	10	200	abcA	0	-	10	200	255,0,0	2	40,50,	0,140,
+}
+
+func ExampleAddFeatures() {
+	sequence := poly.Sequence{Meta: poly.Meta{Name: "chr1"}, Sequence: strings.Repeat("atgc", 100)}
+	features, _ := Parse([]byte("track name=example\nchr1\t0\t8\tstart\nchr2\t0\t8\tother\n"))
+
+	added, _ := AddFeatures(&sequence, features)
+	fmt.Println(added, sequence.Features[0].Attributes.Get("Name"), sequence.Features[0].GetSequence())
+	// Output: 1 start atgcatgc
+}
+
+func TestRoundTrip(t *testing.T) {
+	bed := "chr1\t10\t200\tabcA\t500\t-\t20\t180\t255,0,0\t3\t40,50,10,\t0,100,180,\n" +
+		"chr1\t5\t15\tsite\t0\t+\t5\t15\t0\t1\t10,\t0,\n"
+	features, err := Parse([]byte(bed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cds := features[0]
+	expectedLocation := poly.Location{Start: 10, End: 200, Join: true, Complement: true, SubLocations: []poly.Location{{Start: 10, End: 50}, {Start: 110, End: 160}, {Start: 190, End: 200}}}
+	if diff := cmp.Diff(expectedLocation, cds.SequenceLocation); diff != "" {
+		t.Errorf("BED12 blocks were not parsed into a join (-want +got):\n%s", diff)
+	}
+	expectedAttributes := poly.Attributes{"Name": {"abcA"}, "color": {"255,0,0"}, "thick_start": {"20"}, "thick_end": {"180"}}
+	if diff := cmp.Diff(expectedAttributes, cds.Attributes); diff != "" {
+		t.Errorf("BED columns were not kept as attributes (-want +got):\n%s", diff)
+	}
+
+	sequence := poly.Sequence{Meta: poly.Meta{Name: "chr1"}, Sequence: strings.Repeat("atgc", 100)}
+	if _, err := AddFeatures(&sequence, features); err != nil {
+		t.Fatal(err)
+	}
+	if built := string(Build(sequence)); built != bed {
+		t.Errorf("BED does not round trip. Got:\n%s", built)
+	}
+}
+
+func TestShortFormats(t *testing.T) {
+	features, err := Parse([]byte("# comment\nbrowser position chr1\nchr1 0 10\nchr1\t20\t30\tname\t0\t-\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 || features[0].SequenceLocation.Start != 0 || features[0].SequenceLocation.End != 10 {
+		t.Fatalf("BED3 with spaces was not parsed. Got: %+v", features)
+	}
+	if !features[1].SequenceLocation.Complement || features[1].Strand != "-" {
+		t.Errorf("BED6 strand was not parsed. Got: %+v", features[1])
+	}
+}
+
+func TestBuildUnknownStrandAndOverlappingParts(t *testing.T) {
+	sequence := poly.Sequence{Meta: poly.Meta{Name: "chr1"}, Sequence: strings.Repeat("ATGC", 10)}
+	unknown := poly.Feature{Type: "region", Strand: "?", Attributes: poly.Attributes{"Name": {"unknown"}}, SequenceLocation: poly.Location{Start: 0, End: 10}}
+	overlapping := poly.Feature{Type: "misc_feature", Attributes: poly.Attributes{"Name": {"overlapping"}}, SequenceLocation: poly.Location{SubLocations: []poly.Location{{Start: 0, End: 10}, {Start: 5, End: 15}, {Start: 20, End: 30}}}}
+	sequence.AddFeature(&unknown)
+	sequence.AddFeature(&overlapping)
+
+	features, err := Parse(Build(sequence))
+	if err != nil {
+		t.Fatalf("Built features could not be parsed back: %s", err)
+	}
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 features. Got: %+v", features)
+	}
+	if features[0].Strand != "." {
+		t.Errorf("Unknown strand should be written as \".\". Got: %q", features[0].Strand)
+	}
+	expected := []poly.Location{{Start: 0, End: 15}, {Start: 20, End: 30}}
+	if diff := cmp.Diff(expected, features[1].SequenceLocation.SubLocations); diff != "" {
+		t.Errorf("Overlapping parts were not merged (-want +got):\n%s", diff)
+	}
+}
+
+func TestOriginSpanningFeatures(t *testing.T) {
+	sequence, err := genbank.Read("../../data/puc19.gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequenceLength := len(sequence.Sequence)
+	spanning := poly.Feature{Type: "misc_feature", Attributes: poly.Attributes{"label": {"across"}}, SequenceLocation: poly.Location{Start: sequenceLength - 10, End: 5}}
+	sequence.Features = nil
+	sequence.AddFeature(&spanning)
+
+	built := string(Build(sequence))
+	expected := fmt.Sprintf("%s\t%d\t%d\tacross\t0\t+\t%d\t%d\t0\t2\t10,5,\t0,10,\n", sequence.Meta.Locus.Name, sequenceLength-10, sequenceLength+5, sequenceLength-10, sequenceLength+5)
+	if built != expected {
+		t.Errorf("Origin spanning feature should be written past the end of the sequence.\nGot:  %qWant: %q", built, expected)
+	}
+
+	features, _ := Parse([]byte(built))
+	circular := sequence
+	circular.Features = nil
+	if _, err := AddFeatures(&circular, features); err != nil {
+		t.Fatal(err)
+	}
+	location := circular.Features[0].SequenceLocation
+	if !location.SpansOrigin() || location.Start != sequenceLength-10 || location.End != 5 || len(location.SubLocations) != 0 {
+		t.Errorf("Origin spanning feature was not merged back into a single range. Got: %+v", location)
+	}
+	if diff := cmp.Diff(spanning.GetSequence(), circular.Features[0].GetSequence()); diff != "" {
+		t.Errorf("Origin spanning feature covers different bases after a round trip (-want +got):\n%s", diff)
+	}
+
+	// linear sequences can't have features past their end.
+	linear := poly.Sequence{Meta: poly.Meta{Name: sequence.Meta.Locus.Name}, Sequence: sequence.Sequence}
+	if _, err := AddFeatures(&linear, features); err == nil {
+		t.Errorf("Feature past the end of a linear sequence should be an error.")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		err  error
+	}{
+		{"too few columns", "chr1\t10", ErrInvalidColumns},
+		{"start after end", "chr1\t20\t10", ErrInvalidCoordinates},
+		{"bad strand", "chr1\t10\t20\tname\t0\tplus", ErrInvalidColumns},
+		{"thick outside feature", "chr1\t10\t20\tname\t0\t+\t5\t20", ErrInvalidCoordinates},
+		{"block count mismatch", "chr1\t10\t20\tname\t0\t+\t10\t20\t0\t2\t10,\t0,", ErrInvalidBlocks},
+		{"blocks short of end", "chr1\t10\t20\tname\t0\t+\t10\t20\t0\t1\t5,\t0,", ErrInvalidBlocks},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte("track name=test\n" + test.line + "\n"))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("%s: expected a *ParseError. Got: %v", test.name, err)
+		}
+		if parseError.Line != 2 || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q on line 2. Got: %s", test.name, test.err, err)
+		}
+	}
+}
+
+func TestBuildGenbank(t *testing.T) {
+	sequence, _ := genbank.Read("../../data/puc19.gbk")
+	features, err := Parse(Build(sequence))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != len(sequence.Features) {
+		t.Fatalf("Expected a BED line per feature. Got %d for %d features", len(features), len(sequence.Features))
+	}
+	for featureIndex, feature := range sequence.Features {
+		got := features[featureIndex]
+		got.ParentSequence = feature.ParentSequence
+		if diff := cmp.Diff(feature.GetSequence(), got.GetSequence(), cmpopts.EquateEmpty()); diff != "" && !feature.SequenceLocation.SpansOrigin() {
+			t.Errorf("Feature %d covers different bases in BED (-want +got):\n%s", featureIndex, diff)
+		}
+	}
+}
@@ -0,0 +1,766 @@
+package embl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/flatfile"
+	"github.com/Open-Science-Global/poly/io/genbank"
+	"github.com/mitchellh/go-wordwrap"
+)
+
+/******************************************************************************
+
+EMBL specific IO related things begin here.
+
+EMBL is the flat file format of the European Nucleotide Archive. It holds the
+same information as a genbank file but every line starts with a two letter
+code saying what it is:
+
+	ID   X56734; SV 1; linear; mRNA; STD; PLN; 1859 BP.
+	XX
+	AC   X56734; S46826;
+	XX
+	DE   Trifolium repens mRNA for non-cyanogenic beta-glucosidase
+	XX
+	OS   Trifolium repens (white clover)
+	OC   Eukaryota; Viridiplantae; Streptophyta; Embryophyta; Tracheophyta;
+	XX
+	RN   [1]
+	RP   1-1859
+	RA   Oxtoby E., Dunn M.A., Pancoro A., Hughes M.A.;
+	RT   "Nucleotide and derived amino acid sequence of the cyanogenic
+	RT   beta-glucosidase (linamarase) from white clover";
+	RL   Plant Mol. Biol. 17(2):209-219(1991).
+	XX
+	FH   Key             Location/Qualifiers
+	FH
+	FT   source          1..1859
+	FT                   /organism="Trifolium repens"
+	XX
+	SQ   Sequence 1859 BP; 609 A; 314 C; 355 G; 581 T; 0 other;
+	     aaacaaacca aatatggatt ttattgtagc catatttgct ctgtttgtta ttagctcatt        60
+	//
+
+The feature table is the INSDC feature table shared with genbank so
+locations are parsed and built by the genbank package.
+
+Lines are mapped onto the same poly.Meta fields the genbank parser fills in
+so EMBL and genbank files convert into each other. Lines we don't have a
+field for, like DT and CC, are kept in Meta.Other under their line code with
+one line of text per line of the file. Build writes the COMMENT and DBLINK
+sections of genbank files as CC and DR lines and drops any other key that
+isn't an EMBL line code.
+
+The full spec lives here:
+
+https://ftp.ebi.ac.uk/pub/databases/embl/doc/usrman.txt
+
+******************************************************************************/
+
+// Problems an EMBL record can have. They come back wrapped in a ParseError so use errors.Is to tell them apart.
+var (
+	ErrMalformedID     = errors.New("malformed ID line")
+	ErrInvalidLocation = errors.New("invalid feature location")
+	ErrInvalidSequence = errors.New("invalid characters in SQ")
+	ErrTruncated       = errors.New("truncated file")
+	ErrInvalidLineCode = errors.New("line does not start with a two letter code")
+)
+
+// ParseError is a problem with a specific line of an EMBL file, wrapping one of the errors above.
+type ParseError = flatfile.ParseError
+
+// Parse parses a single EMBL record into a poly.Sequence. The first problem found is returned as a *ParseError.
+func Parse(file []byte) (poly.Sequence, error) {
+	sequence, parseErrors := parse(strings.Split(string(file), "\n"), 1)
+	if len(parseErrors) > 0 {
+		return poly.Sequence{}, parseErrors[0]
+	}
+	return sequence, nil
+}
+
+// Read reads a single EMBL record from path.
+func Read(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return Parse(file)
+}
+
+// Write writes a poly.Sequence out to path as an EMBL file.
+func Write(sequence poly.Sequence, path string) {
+	_ = ioutil.WriteFile(path, Build(sequence), 0644)
+}
+
+// parse parses the lines of a single record. lines starts at line number firstLineNumber, which is only used for errors.
+// Whatever could be parsed is returned along with every problem found in the order they appear.
+func parse(lines []string, firstLineNumber int) (poly.Sequence, []*ParseError) {
+	sequence := poly.Sequence{}
+	meta := poly.Meta{Other: make(map[string]string)}
+	var parseErrors []*ParseError
+	addError := func(lineIndex int, text string, err error) {
+		parseErrors = append(parseErrors, &ParseError{Line: firstLineNumber + lineIndex, Text: text, Err: err})
+	}
+
+	var accessions []string
+	var sequenceVersion, lineage, declaredLength string
+	var featureLines []string
+	featureLineIndex := -1
+	var references []poly.Reference
+	var sequenceBuffer strings.Builder
+	idFound, inSequence, endFound := false, false, false
+
+lineLoop:
+	for lineIndex, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if inSequence && !strings.HasPrefix(line, "//") {
+			if strings.TrimLeft(line, " 0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ*-") != "" {
+				addError(lineIndex, line, ErrInvalidSequence)
+			}
+			for _, character := range line {
+				if (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') || character == '*' || character == '-' {
+					sequenceBuffer.WriteRune(character)
+				}
+			}
+			continue
+		}
+
+		code := line
+		if len(code) > 2 {
+			code = line[:2]
+		}
+		// line codes are always two characters followed by spaces so a longer word like COMMENT isn't a CO line.
+		if len(line) > 2 && line[2] != ' ' && code != "//" {
+			addError(lineIndex, line, ErrInvalidLineCode)
+			continue
+		}
+		var text string
+		if len(line) > 5 {
+			text = strings.TrimSpace(line[5:])
+		}
+
+		if !idFound && code != "ID" {
+			addError(lineIndex, line, fmt.Errorf("%w: record does not start with ID", ErrMalformedID))
+			idFound = true
+		}
+
+		switch code {
+		case "ID":
+			locus, version, err := parseID(text)
+			if err != nil {
+				addError(lineIndex, line, err)
+			}
+			meta.Locus = locus
+			sequenceVersion = version
+			idFound = true
+		case "AC":
+			for _, accession := range strings.Split(text, ";") {
+				if accession = strings.TrimSpace(accession); accession != "" {
+					accessions = append(accessions, accession)
+				}
+			}
+		case "DE":
+			meta.Definition = appendText(meta.Definition, text)
+		case "KW":
+			meta.Keywords = appendText(meta.Keywords, text)
+		case "OS":
+			meta.Source = appendText(meta.Source, text)
+		case "OC":
+			lineage = appendText(lineage, text)
+		case "RN":
+			references = append(references, poly.Reference{Index: strings.Trim(text, "[]")})
+		case "RP", "RX", "RA", "RG", "RT", "RL", "RC":
+			if len(references) == 0 {
+				references = append(references, poly.Reference{})
+			}
+			addReferenceLine(&references[len(references)-1], code, text)
+		case "CO":
+			meta.Contig += strings.Join(strings.Fields(text), "")
+		case "FT":
+			if featureLineIndex == -1 {
+				featureLineIndex = lineIndex
+			}
+			featureLines = append(featureLines, line)
+		case "SQ":
+			if fields := strings.Fields(text); len(fields) >= 2 {
+				declaredLength = fields[1]
+			}
+			inSequence = true
+		case "//":
+			endFound = true
+			break lineLoop
+		case "XX", "FH":
+			continue
+		default:
+			if meta.Other[code] != "" {
+				meta.Other[code] += "\n"
+			}
+			meta.Other[code] += text
+		}
+	}
+
+	if !idFound {
+		addError(0, "", fmt.Errorf("%w: record has no ID line", ErrMalformedID))
+	}
+	lastLineIndex := len(lines) - 1
+	for lastLineIndex > 0 && strings.TrimSpace(lines[lastLineIndex]) == "" {
+		lastLineIndex--
+	}
+	if !endFound {
+		addError(lastLineIndex, lines[lastLineIndex], fmt.Errorf("%w: missing //", ErrTruncated))
+	}
+
+	sequence.Sequence = sequenceBuffer.String()
+	if length, err := strconv.Atoi(declaredLength); inSequence && err == nil && length != len(sequence.Sequence) {
+		addError(lastLineIndex, lines[lastLineIndex], fmt.Errorf("%w: SQ declares %d bases but has %d", ErrTruncated, length, len(sequence.Sequence)))
+	}
+
+	meta.Accession = strings.Join(accessions, " ")
+	if len(accessions) > 0 && sequenceVersion != "" {
+		meta.Version = accessions[0] + "." + sequenceVersion
+	}
+	meta.Organism = scientificName(meta.Source)
+	if lineage != "" {
+		meta.Organism = appendText(meta.Organism, lineage)
+	}
+	for referenceIndex := range references {
+		reference := &references[referenceIndex]
+		reference.Authors = strings.TrimSuffix(reference.Authors, ";")
+		reference.Title = strings.Trim(strings.TrimSuffix(reference.Title, ";"), "\"")
+	}
+	meta.References = references
+	sequence.Meta = meta
+
+	features, featureErrors := getFeatures(featureLines, firstLineNumber+featureLineIndex)
+	parseErrors = append(parseErrors, featureErrors...)
+	for _, feature := range features {
+		// poly models features spanning the origin of circular sequences as a single range instead of a join.
+		if meta.Locus.Circular {
+			feature.SequenceLocation = feature.SequenceLocation.MergeAtOrigin(len(sequence.Sequence))
+		}
+		sequence.AddFeature(&feature)
+	}
+
+	sort.SliceStable(parseErrors, func(i, j int) bool { return parseErrors[i].Line < parseErrors[j].Line })
+	return sequence, parseErrors
+}
+
+// parseID parses the text of an ID line into a locus and the sequence version. Both the current ID line and the
+// one used before 2006 are understood:
+//
+//	X56734; SV 1; linear; mRNA; STD; PLN; 1859 BP.
+//	X56734  standard; RNA; PLN; 1859 BP.
+func parseID(text string) (poly.Locus, string, error) {
+	var locus poly.Locus
+	var version string
+	fields := strings.Split(strings.TrimSuffix(text, "."), ";")
+	for fieldIndex := range fields {
+		fields[fieldIndex] = strings.TrimSpace(fields[fieldIndex])
+	}
+
+	switch len(fields) {
+	case 7:
+		locus.Name = fields[0]
+		version = strings.TrimSpace(strings.TrimPrefix(fields[1], "SV"))
+		locus.Circular = fields[2] == "circular"
+		locus.Linear = fields[2] == "linear"
+		locus.MoleculeType = fields[3]
+		locus.GenbankDivision = fields[5]
+	case 4:
+		locus.Name = strings.Fields(fields[0] + " ")[0]
+		locus.MoleculeType = strings.TrimSpace(strings.TrimPrefix(fields[1], "circular"))
+		locus.Circular = strings.HasPrefix(fields[1], "circular")
+		locus.Linear = !locus.Circular
+		locus.GenbankDivision = fields[2]
+	default:
+		return locus, version, fmt.Errorf("%w: expected 7 fields separated by ; but got %d", ErrMalformedID, len(fields))
+	}
+
+	length := strings.Fields(fields[len(fields)-1])
+	if len(length) != 2 {
+		return locus, version, fmt.Errorf("%w: missing sequence length", ErrMalformedID)
+	}
+	locus.SequenceLength = length[0]
+	locus.SequenceCoding = strings.ToLower(length[1])
+	return locus, version, nil
+}
+
+// appendText joins a line of text onto text that was wrapped over several lines.
+func appendText(text, line string) string {
+	if text == "" {
+		return line
+	}
+	return text + " " + line
+}
+
+// scientificName returns the name from an OS line without the common name in parentheses after it.
+func scientificName(source string) string {
+	if open := strings.Index(source, " ("); open != -1 && strings.HasSuffix(source, ")") {
+		return source[:open]
+	}
+	return source
+}
+
+// basesRegex matches a range of bases in an RP line or in the range of a genbank reference.
+var basesRegex = regexp.MustCompile(`(\d+)\s*(?:-|to)\s*(\d+)`)
+
+// addReferenceLine adds a single R line to a reference. Ranges are stored the way genbank writes them
+// like (bases 1 to 1859) so references convert between the two formats.
+func addReferenceLine(reference *poly.Reference, code, text string) {
+	switch code {
+	case "RP":
+		var ranges []string
+		for _, match := range basesRegex.FindAllStringSubmatch(text, -1) {
+			ranges = append(ranges, match[1]+" to "+match[2])
+		}
+		reference.Range = "(bases " + strings.Join(ranges, "; ") + ")"
+	case "RX":
+		if strings.HasPrefix(text, "PUBMED;") {
+			reference.PubMed = strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(text, "PUBMED;")), ".")
+		}
+	case "RA", "RG":
+		reference.Authors = appendText(reference.Authors, text)
+	case "RT":
+		reference.Title = appendText(reference.Title, text)
+	case "RL":
+		reference.Journal = appendText(reference.Journal, text)
+	case "RC":
+		reference.Remark = appendText(reference.Remark, text)
+	}
+}
+
+// getFeatures parses the FT lines of a record. lines starts at line number firstLineNumber, which is only used for errors.
+func getFeatures(lines []string, firstLineNumber int) ([]poly.Feature, []*ParseError) {
+	var features []poly.Feature
+	var parseErrors []*ParseError
+	var qualifier string
+	var locationLineNumber int
+
+	// finishQualifier adds the last qualifier read to the last feature.
+	finishQualifier := func() {
+		if qualifier == "" || len(features) == 0 {
+			return
+		}
+		keyValue := strings.SplitN(qualifier, "=", 2)
+		key := strings.TrimPrefix(keyValue[0], "/")
+		var value string
+		if len(keyValue) == 2 {
+			value = keyValue[1]
+			if len(value) >= 2 && strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+				value = strings.ReplaceAll(value[1:len(value)-1], "\"\"", "\"")
+			}
+		}
+		features[len(features)-1].Attributes.Add(key, value)
+		qualifier = ""
+	}
+	parseLocation := func() {
+		if len(features) == 0 {
+			return
+		}
+		feature := &features[len(features)-1]
+		location, err := genbank.ParseLocation(feature.GbkLocationString)
+		if err != nil {
+			parseErrors = append(parseErrors, &ParseError{Line: locationLineNumber, Text: feature.GbkLocationString, Err: fmt.Errorf("%w: %s", ErrInvalidLocation, err)})
+		}
+		feature.SequenceLocation = location
+	}
+
+	inLocation := false
+	for lineIndex, line := range lines {
+		if len(line) <= 5 {
+			continue
+		}
+
+		// a key in column 6 starts a new feature.
+		if line[5] != ' ' {
+			finishQualifier()
+			if inLocation {
+				parseLocation()
+			}
+			fields := strings.Fields(line[5:])
+			feature := poly.Feature{Type: fields[0], Attributes: make(poly.Attributes)}
+			if len(fields) > 1 {
+				feature.GbkLocationString = strings.Join(fields[1:], "")
+			}
+			features = append(features, feature)
+			locationLineNumber = firstLineNumber + lineIndex
+			inLocation = true
+			continue
+		}
+
+		text := strings.TrimSpace(line[5:])
+		// a / starts a new qualifier unless it's inside a quoted value that wraps onto this line.
+		if strings.HasPrefix(text, "/") && strings.Count(qualifier, "\"")%2 == 0 {
+			finishQualifier()
+			if inLocation {
+				parseLocation()
+				inLocation = false
+			}
+			qualifier = text
+			continue
+		}
+
+		switch {
+		case inLocation && len(features) > 0:
+			features[len(features)-1].GbkLocationString += text
+		case strings.HasPrefix(qualifier, "/translation="):
+			qualifier += text
+		case qualifier != "":
+			qualifier += " " + text
+		}
+	}
+	finishQualifier()
+	if inLocation {
+		parseLocation()
+	}
+	return features, parseErrors
+}
+
+// otherOrder is the order lines kept in Meta.Other are written in. Build writes PR and DT after AC, OG after
+// OS and everything else after the references. Keys that aren't listed aren't EMBL line codes and are dropped.
+var otherOrder = []string{"PR", "DT", "OG", "DR", "CC", "AH", "AS"}
+
+// dblinkRegex matches one database cross reference of a genbank DBLINK section, like BioProject: PRJNA182589.
+var dblinkRegex = regexp.MustCompile(`(\S+):\s*(\S+)`)
+
+// emblOther returns the lines of other that can be written to an EMBL file keyed by their line code. Genbank's
+// COMMENT and DBLINK sections become CC and DR lines.
+func emblOther(other map[string]string) map[string]string {
+	lines := make(map[string]string)
+	add := func(code, text string) {
+		if lines[code] != "" {
+			lines[code] += "\n"
+		}
+		lines[code] += text
+	}
+	keys := make([]string, 0, len(other))
+	for key := range other {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch key {
+		case "COMMENT":
+			for _, line := range strings.Split(other[key], "\n") {
+				add("CC", wordwrap.WrapString(line, 75))
+			}
+		case "DBLINK":
+			for _, match := range dblinkRegex.FindAllStringSubmatch(other[key], -1) {
+				add("DR", match[1]+"; "+match[2]+".")
+			}
+		default:
+			for _, code := range otherOrder {
+				if key == code {
+					add(key, other[key])
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// Build builds an EMBL record out of a poly.Sequence.
+func Build(sequence poly.Sequence) []byte {
+	var emblString bytes.Buffer
+	meta := sequence.Meta
+	locus := meta.Locus
+
+	writeLines := func(code, text string, width int) {
+		if text == "" {
+			return
+		}
+		for _, line := range strings.Split(wordwrap.WrapString(text, uint(width)), "\n") {
+			emblString.WriteString(code + "   " + line + "\n")
+		}
+	}
+	other := emblOther(meta.Other)
+	writeOther := func(codes ...string) {
+		for _, code := range otherCodes(other, codes) {
+			for _, line := range strings.Split(other[code], "\n") {
+				emblString.WriteString(code + "   " + line + "\n")
+			}
+			emblString.WriteString("XX\n")
+		}
+	}
+
+	// building ID
+	name := locus.Name
+	if name == "" {
+		name = meta.Name
+	}
+	accessions := strings.Fields(meta.Accession)
+	version := "1"
+	if dot := strings.LastIndex(meta.Version, "."); dot != -1 {
+		version = meta.Version[dot+1:]
+	}
+	topology := "linear"
+	if locus.Circular {
+		topology = "circular"
+	}
+	moleculeType := locus.MoleculeType
+	if moleculeType == "" {
+		moleculeType = "unassigned DNA"
+	}
+	dataClass := "STD"
+	if meta.Contig != "" && sequence.Sequence == "" {
+		dataClass = "CON"
+	}
+	division := locus.GenbankDivision
+	if division == "" {
+		division = "UNC"
+	}
+	length := locus.SequenceLength
+	if length == "" || sequence.Sequence != "" {
+		length = strconv.Itoa(len(sequence.Sequence))
+	}
+	coding := "BP"
+	if strings.EqualFold(locus.SequenceCoding, "aa") {
+		coding = "AA"
+	}
+	emblString.WriteString(fmt.Sprintf("ID   %s; SV %s; %s; %s; %s; %s; %s %s.\nXX\n", name, version, topology, moleculeType, dataClass, division, length, coding))
+
+	if len(accessions) > 0 {
+		emblString.WriteString("AC   " + strings.Join(accessions, "; ") + ";\nXX\n")
+	}
+	writeOther("PR", "DT")
+
+	writeLines("DE", meta.Definition, 75)
+	if meta.Definition != "" {
+		emblString.WriteString("XX\n")
+	}
+	writeLines("KW", meta.Keywords, 75)
+	if meta.Keywords != "" {
+		emblString.WriteString("XX\n")
+	}
+
+	if meta.Source != "" || meta.Organism != "" {
+		source := meta.Source
+		if source == "" {
+			source = meta.Organism
+		}
+		writeLines("OS", source, 75)
+		// genbank style organisms hold the scientific name followed by the lineage.
+		lineage := strings.TrimSpace(strings.TrimPrefix(meta.Organism, scientificName(source)))
+		if strings.Contains(lineage, ";") {
+			writeLines("OC", lineage, 75)
+		}
+		emblString.WriteString("XX\n")
+	}
+	writeOther("OG")
+
+	for referenceIndex, reference := range meta.References {
+		index := reference.Index
+		if index == "" {
+			index = strconv.Itoa(referenceIndex + 1)
+		}
+		emblString.WriteString("RN   [" + index + "]\n")
+		var ranges []string
+		for _, match := range basesRegex.FindAllStringSubmatch(reference.Range, -1) {
+			ranges = append(ranges, match[1]+"-"+match[2])
+		}
+		writeLines("RP", strings.Join(ranges, ", "), 75)
+		if reference.PubMed != "" {
+			emblString.WriteString("RX   PUBMED; " + reference.PubMed + ".\n")
+		}
+		writeLines("RC", reference.Remark, 75)
+		writeLines("RA", reference.Authors+";", 75)
+		// references without a title still get an empty RT line.
+		if reference.Title == "" {
+			emblString.WriteString("RT   ;\n")
+		} else {
+			writeLines("RT", "\""+reference.Title+"\";", 75)
+		}
+		writeLines("RL", reference.Journal, 75)
+		emblString.WriteString("XX\n")
+	}
+	writeOther()
+
+	// building features. The feature table is the same as genbank's apart from the FT at the start of each line.
+	if len(sequence.Features) > 0 {
+		emblString.WriteString("FH   Key             Location/Qualifiers\nFH\n")
+		for _, feature := range sequence.Features {
+			if feature.SequenceLocation.SpansOrigin() {
+				feature.SequenceLocation = feature.SequenceLocation.SplitAtOrigin(len(sequence.Sequence))
+			}
+			featureString := strings.TrimSuffix(genbank.BuildFeatureString(feature), "\n")
+			for _, line := range strings.Split(featureString, "\n") {
+				emblString.WriteString("FT   " + strings.TrimPrefix(line, "     ") + "\n")
+			}
+		}
+		emblString.WriteString("XX\n")
+	}
+
+	if meta.Contig != "" {
+		contig := meta.Contig
+		for len(contig) > 75 {
+			cut := strings.LastIndex(contig[:75], ",")
+			if cut == -1 {
+				break
+			}
+			emblString.WriteString("CO   " + contig[:cut+1] + "\n")
+			contig = contig[cut+1:]
+		}
+		emblString.WriteString("CO   " + contig + "\n")
+	}
+
+	if sequence.Sequence != "" || meta.Contig == "" {
+		emblString.WriteString(buildSequenceString(sequence.Sequence))
+	}
+	emblString.WriteString("//\n")
+	return emblString.Bytes()
+}
+
+// otherCodes returns the codes in other that are listed in codes in otherOrder. No codes returns every code that
+// isn't written somewhere else.
+func otherCodes(other map[string]string, codes []string) []string {
+	rank := func(code string) int {
+		for index, orderedCode := range otherOrder {
+			if orderedCode == code {
+				return index
+			}
+		}
+		return len(otherOrder)
+	}
+	var found []string
+	for code := range other {
+		listed := false
+		for _, listedCode := range codes {
+			listed = listed || listedCode == code
+		}
+		if listed || (len(codes) == 0 && code != "PR" && code != "DT" && code != "OG") {
+			found = append(found, code)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if rank(found[i]) != rank(found[j]) {
+			return rank(found[i]) < rank(found[j])
+		}
+		return found[i] < found[j]
+	})
+	return found
+}
+
+// buildSequenceString builds the SQ line and the sequence lines after it. Each line holds 60 bases in groups of 10
+// followed by the position of the last base on the line.
+func buildSequenceString(sequence string) string {
+	var sequenceString strings.Builder
+	counts := make(map[rune]int)
+	for _, base := range strings.ToLower(sequence) {
+		counts[base]++
+	}
+	other := len(sequence) - counts['a'] - counts['c'] - counts['g'] - counts['t']
+	sequenceString.WriteString(fmt.Sprintf("SQ   Sequence %d BP; %d A; %d C; %d G; %d T; %d other;\n", len(sequence), counts['a'], counts['c'], counts['g'], counts['t'], other))
+
+	for lineStart := 0; lineStart < len(sequence); lineStart += 60 {
+		lineEnd := lineStart + 60
+		if lineEnd > len(sequence) {
+			lineEnd = len(sequence)
+		}
+		var groups []string
+		for groupStart := lineStart; groupStart < lineEnd; groupStart += 10 {
+			groupEnd := groupStart + 10
+			if groupEnd > lineEnd {
+				groupEnd = lineEnd
+			}
+			groups = append(groups, sequence[groupStart:groupEnd])
+		}
+		sequenceString.WriteString(fmt.Sprintf("     %-65s%10d\n", strings.Join(groups, " "), lineEnd))
+	}
+	return sequenceString.String()
+}
+
+/******************************************************************************
+
+EMBL specific IO related things end here.
+
+******************************************************************************/
+
+/******************************************************************************
+
+Multi record EMBL IO related things begin here.
+
+******************************************************************************/
+
+// Reader reads EMBL records one at a time from a stream holding any number of them. Only the record being
+// parsed is buffered so files of any size can be read.
+type Reader struct {
+	records *flatfile.Reader
+}
+
+// NewReader returns a Reader over r. Gzip compressed input is detected and decompressed on the fly.
+func NewReader(r io.Reader) (*Reader, error) {
+	records, err := flatfile.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{records: records}, nil
+}
+
+// Next reads and parses the next record. It returns io.EOF once there are no records left.
+//
+// A bad record still comes back with whatever could be read from it along with its first problem as a *ParseError
+// whose line number counts from the start of the stream. A *ParseError only concerns that record and Next can be
+// called again to carry on with the rest of the stream. Any other error comes from the underlying reader and is
+// returned again by every later call.
+func (reader *Reader) Next() (poly.Sequence, error) {
+	lines, firstLineNumber, err := reader.records.Next()
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+
+	sequence, parseErrors := parse(lines, firstLineNumber)
+	if len(parseErrors) > 0 {
+		return sequence, parseErrors[0]
+	}
+	return sequence, nil
+}
+
+// Close releases the gzip decompressor if the stream was compressed. It does not close the underlying reader.
+func (reader *Reader) Close() error {
+	return reader.records.Close()
+}
+
+// ParseMulti parses every record in an EMBL file. Records with problems are kept so one bad record doesn't lose the rest.
+func ParseMulti(file []byte) []poly.Sequence {
+	reader, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	return flatfile.ReadAll(reader.Next)
+}
+
+// ReadMulti reads every record in an EMBL file, which may be gzipped.
+func ReadMulti(path string) []poly.Sequence {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	reader, err := NewReader(file)
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+	return flatfile.ReadAll(reader.Next)
+}
+
+/******************************************************************************
+
+Multi record EMBL IO related things end here.
+
+******************************************************************************/
@@ -0,0 +1,280 @@
+package embl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/genbank"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func ExampleRead() {
+	sequence, _ := Read("../../data/sample.embl")
+	fmt.Println(sequence.Meta.Version)
+	fmt.Println(sequence.Meta.Locus.MoleculeType)
+	fmt.Println(sequence.Meta.References[1].Range)
+	// Output:
+	// PS000001.2
+	// genomic DNA
+	// (bases 1 to 100; 150 to 250)
+}
+
+func ExampleBuild() {
+	sequence, _ := Read("../../data/sample.embl")
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	path := tmpDataDir + "/sample.embl"
+	Write(sequence, path)
+	writeTestSequence, _ := Read(path)
+
+	fmt.Println(writeTestSequence.Sequence == sequence.Sequence)
+	// Output: true
+}
+
+func ExampleReader() {
+	file, _ := ioutil.ReadFile("../../data/sample.embl")
+	reader, _ := NewReader(bytes.NewReader(append(file, file...)))
+	defer reader.Close()
+
+	for {
+		sequence, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		fmt.Println(sequence.Meta.Locus.Name, len(sequence.Sequence))
+	}
+	// Output:
+	// PS000001 250
+	// PS000001 250
+}
+
+func TestParse(t *testing.T) {
+	sequence, err := Read("../../data/sample.embl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLocus := poly.Locus{Name: "PS000001", SequenceLength: "250", MoleculeType: "genomic DNA", GenbankDivision: "SYN", SequenceCoding: "bp", Circular: true}
+	if diff := cmp.Diff(expectedLocus, sequence.Meta.Locus); diff != "" {
+		t.Errorf("ID line was not parsed (-want +got):\n%s", diff)
+	}
+	if sequence.Meta.Organism != "synthetic construct other sequences; artificial sequences." {
+		t.Errorf("OS and OC lines were not joined like genbank's ORGANISM. Got: %q", sequence.Meta.Organism)
+	}
+	if sequence.Meta.Other["CC"] != "This record was written by hand to exercise the parser.\nIt has two lines of comments." {
+		t.Errorf("CC lines were not kept. Got: %q", sequence.Meta.Other["CC"])
+	}
+
+	expectedReference := poly.Reference{Index: "1", Authors: "Doe J., Roe R.", Title: "A small plasmid for testing EMBL parsers", Journal: "J. Test. Seq. 1(1):1-10(2021).", PubMed: "12345678", Range: "(bases 1 to 250)"}
+	if diff := cmp.Diff(expectedReference, sequence.Meta.References[0]); diff != "" {
+		t.Errorf("RN block was not parsed (-want +got):\n%s", diff)
+	}
+
+	cds := sequence.Features[1]
+	if cds.Attributes.Get("product") != "beta-galactosidase alpha fragment with a long product name that wraps onto a second line" {
+		t.Errorf("Wrapped qualifier was not joined. Got: %q", cds.Attributes.Get("product"))
+	}
+	if cds.Attributes.Get("note") != `contains a "quoted" word` {
+		t.Errorf("Escaped quotes were not unescaped. Got: %q", cds.Attributes.Get("note"))
+	}
+	if featureSequence := cds.GetSequence(); len(featureSequence) != 90 {
+		t.Errorf("join location was not parsed. Got %d bases", len(featureSequence))
+	}
+
+	// features across the origin of circular records are merged like genbank does.
+	origin := sequence.Features[3]
+	if !origin.SequenceLocation.SpansOrigin() || origin.SequenceLocation.Start != 230 || origin.SequenceLocation.End != 5 {
+		t.Errorf("Feature across the origin was not merged. Got: %+v", origin.SequenceLocation)
+	}
+	if _, ok := origin.Attributes["pseudo"]; !ok {
+		t.Errorf("Bare qualifier was not parsed.")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	sequence, err := Read("../../data/sample.embl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reparsed, err := Parse(Build(sequence))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequence, reparsed, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
+		t.Errorf("EMBL does not round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenbankToEMBL(t *testing.T) {
+	for _, path := range []string{"../../data/puc19.gbk", "../../data/t4_intron.gb", "../../data/phix174.gb"} {
+		gbk, err := genbank.Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		file := Build(gbk)
+		for _, line := range strings.Split(strings.TrimSuffix(string(file), "\n"), "\n") {
+			if len(line) > 2 && line[2] != ' ' {
+				t.Errorf("%s converted to EMBL has a line without a two letter code: %q", path, line)
+			}
+		}
+		embl, err := Parse(file)
+		if err != nil {
+			t.Fatalf("%s converted to EMBL does not parse: %s", path, err)
+		}
+		if embl.Meta.Contig != gbk.Meta.Contig {
+			t.Errorf("%s has CONTIG %q after converting to EMBL", path, embl.Meta.Contig)
+		}
+		if embl.Sequence != gbk.Sequence {
+			t.Errorf("%s lost its sequence converting to EMBL", path)
+		}
+		if embl.Meta.Definition != gbk.Meta.Definition || embl.Meta.Source != gbk.Meta.Source || embl.Meta.Locus.Circular != gbk.Meta.Locus.Circular {
+			t.Errorf("%s lost its meta converting to EMBL", path)
+		}
+		if len(embl.Features) != len(gbk.Features) {
+			t.Fatalf("%s has %d features but %d after converting to EMBL", path, len(gbk.Features), len(embl.Features))
+		}
+		for featureIndex, feature := range gbk.Features {
+			if diff := cmp.Diff(feature.SequenceLocation, embl.Features[featureIndex].SequenceLocation); diff != "" {
+				t.Errorf("%s feature %d changed location converting to EMBL (-want +got):\n%s", path, featureIndex, diff)
+			}
+			if diff := cmp.Diff(feature.Attributes, embl.Features[featureIndex].Attributes); diff != "" {
+				t.Errorf("%s feature %d changed qualifiers converting to EMBL (-want +got):\n%s", path, featureIndex, diff)
+			}
+		}
+
+		// and back again.
+		regbk, err := genbank.Parse(genbank.BuildNormalized(embl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regbk.Sequence != gbk.Sequence || len(regbk.Features) != len(gbk.Features) {
+			t.Errorf("%s does not survive a trip through EMBL", path)
+		}
+	}
+
+	// genbank sections with an EMBL equivalent are written under its code and the rest are dropped.
+	phix, _ := genbank.Read("../../data/phix174.gb")
+	phix.Meta.Other["BASE COUNT"] = "1 a 1 c 1 g 1 t"
+	embl, _ := Parse(Build(phix))
+	expectedOther := map[string]string{
+		"CC": "Source DNA/bacteria from Nancy Moran, University of Texas at Austin.",
+		"DR": "BioProject; PRJNA182589.\nBioSample; SAMN03379850.",
+	}
+	if diff := cmp.Diff(expectedOther, embl.Meta.Other); diff != "" {
+		t.Errorf("COMMENT and DBLINK were not converted (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	file, _ := ioutil.ReadFile("../../data/sample.embl")
+	lines := strings.Split(string(file), "\n")
+
+	tests := []struct {
+		name string
+		edit func(lines []string) []string
+		line int
+		err  error
+	}{
+		{
+			name: "malformed ID",
+			edit: func(lines []string) []string { lines[0] = "ID   PS000001; 250 BP."; return lines },
+			line: 1,
+			err:  ErrMalformedID,
+		},
+		{
+			name: "long line code",
+			edit: func(lines []string) []string { lines[30] = "COMMENT   This record was written by hand."; return lines },
+			line: 31,
+			err:  ErrInvalidLineCode,
+		},
+		{
+			name: "invalid location",
+			edit: func(lines []string) []string { lines[44] = "FT   misc_feature    complement(130..)"; return lines },
+			line: 45,
+			err:  ErrInvalidLocation,
+		},
+		{
+			name: "invalid sequence",
+			edit: func(lines []string) []string { lines[50] = "     tgggcgaact tggt!acccc" + lines[50][25:]; return lines },
+			line: 51,
+			err:  ErrInvalidSequence,
+		},
+		{
+			name: "truncated",
+			edit: func(lines []string) []string { return lines[:55] },
+			line: 55,
+			err:  ErrTruncated,
+		},
+	}
+
+	for _, test := range tests {
+		edited := test.edit(append([]string{}, lines...))
+		_, err := Parse([]byte(strings.Join(edited, "\n")))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("%s: expected a *ParseError. Got: %v", test.name, err)
+		}
+		if parseError.Line != test.line || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q on line %d. Got: %s", test.name, test.err, test.line, err)
+		}
+	}
+}
+
+func TestReaderRecordErrors(t *testing.T) {
+	file, _ := ioutil.ReadFile("../../data/sample.embl")
+	broken := strings.Replace(string(file), "complement(130..160)", "complement(130..)", 1)
+	stream := string(file) + broken + string(file)
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(stream))
+	gzipWriter.Close()
+
+	for _, input := range [][]byte{[]byte(stream), gzipped.Bytes()} {
+		reader, err := NewReader(bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var records int
+		var parseError *ParseError
+		for {
+			_, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil && !errors.As(err, &parseError) {
+				t.Fatal(err)
+			}
+			records++
+		}
+		reader.Close()
+
+		if records != 3 {
+			t.Errorf("Expected the reader to carry on past a bad record and read 3. Got %d", records)
+		}
+		recordLines := strings.Count(string(file), "\n")
+		if parseError == nil || parseError.Line != recordLines+45 {
+			t.Errorf("Expected an error on line %d of the stream. Got: %v", recordLines+45, parseError)
+		}
+	}
+
+	if sequences := ParseMulti([]byte(stream)); len(sequences) != 3 {
+		t.Errorf("ParseMulti should keep records with errors. Got %d records", len(sequences))
+	}
+}
@@ -14,9 +14,9 @@ func Example() {
 	// Poly can take in basic gff, gbk, fasta, and JSON.
 	// We call the json package "pson" (poly JSON) to prevent namespace collision with Go's standard json package.
 
-	gffInput := gff.Read("../data/ecoli-mg1655-short.gff")
-	gbkInput := genbank.Read("../data/puc19.gbk")
-	fastaInput := fasta.Read("fasta/data/base.fasta")
+	gffInput, _ := gff.Read("../data/ecoli-mg1655-short.gff")
+	gbkInput, _ := genbank.Read("../data/puc19.gbk")
+	fastaInput, _ := fasta.Read("fasta/data/base.fasta")
 	jsonInput := polyjson.Read("../data/puc19static.json")
 
 	// Poly can also output these file formats though I wouldn't try doing gbk<->gff or anything like that unless it's JSON.
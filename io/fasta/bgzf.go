@@ -0,0 +1,259 @@
+package fasta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+/******************************************************************************
+
+BGZF specific IO related things begin here.
+
+BGZF (blocked gzip) is how samtools compresses files it wants to be able to
+jump around in. A BGZF file is a plain old multi-member gzip file, so gzip and
+ReadGz can read it like any other, but every member holds at most 64 KiB of
+data and says how big it is compressed in a gzip extra field. That lets you
+walk from block to block without decompressing anything and start
+decompressing at any block you like.
+
+samtools keeps a .gzi file next to a bgzipped fasta that maps where each block
+starts in the compressed file to where it starts in the uncompressed data. The
+.fai index is exactly the same as for the uncompressed file because its offsets
+are all in uncompressed bytes. We read .gzi files if they're there and build
+the same thing by walking the blocks if they aren't.
+
+The spec lives in section 4.1 of the SAM spec:
+
+https://samtools.github.io/hts-specs/SAMv1.pdf
+
+******************************************************************************/
+
+// Errors for BGZF files. Check for them with errors.Is.
+var (
+	ErrNotBGZF           = errors.New("not a BGZF file")
+	ErrMalformedGziIndex = errors.New("malformed .gzi index")
+)
+
+const (
+	// bgzfBlockData is how much data samtools puts in a block so that it
+	// always fits in 64 KiB once compressed, even when it doesn't compress.
+	bgzfBlockData = 0xff00
+	// bgzfHeaderSize is the gzip header plus the BC extra field.
+	bgzfHeaderSize = 18
+)
+
+// bgzfEOF is the empty block every BGZF file ends with.
+var bgzfEOF = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00, 0x42, 0x43, 0x02, 0x00,
+	0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+// GziEntry is where a BGZF block starts in the compressed file and in the uncompressed data.
+type GziEntry struct {
+	CompressedOffset   int64
+	UncompressedOffset int64
+}
+
+// IsBGZF reports whether the start of a file is a BGZF block header.
+func IsBGZF(header []byte) bool {
+	return len(header) >= bgzfHeaderSize &&
+		header[0] == 0x1f && header[1] == 0x8b && header[2] == 8 && header[3]&4 != 0 &&
+		binary.LittleEndian.Uint16(header[10:]) == 6 &&
+		header[12] == 'B' && header[13] == 'C' && binary.LittleEndian.Uint16(header[14:]) == 2
+}
+
+// BuildGziIndex walks the blocks of a BGZF file and returns where each one starts.
+// Like samtools the first block, which always starts at 0 and 0, and empty blocks are left out.
+func BuildGziIndex(r io.Reader) ([]GziEntry, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, bgzfHeaderSize)
+	footer := make([]byte, 8)
+	var entries []GziEntry
+	var compressedOffset, uncompressedOffset int64
+	for {
+		if _, err := io.ReadFull(reader, header); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: block at byte %d is truncated", ErrNotBGZF, compressedOffset)
+		}
+		if !IsBGZF(header) {
+			return nil, fmt.Errorf("%w: no BGZF header at byte %d", ErrNotBGZF, compressedOffset)
+		}
+		blockSize := int64(binary.LittleEndian.Uint16(header[16:])) + 1
+		if _, err := io.CopyN(ioutil.Discard, reader, blockSize-bgzfHeaderSize-int64(len(footer))); err != nil {
+			return nil, fmt.Errorf("%w: block at byte %d is truncated", ErrNotBGZF, compressedOffset)
+		}
+		if _, err := io.ReadFull(reader, footer); err != nil {
+			return nil, fmt.Errorf("%w: block at byte %d is truncated", ErrNotBGZF, compressedOffset)
+		}
+		// empty blocks, like the one at the end of the file, have nothing to start reading from.
+		dataSize := int64(binary.LittleEndian.Uint32(footer[4:]))
+		if compressedOffset > 0 && dataSize > 0 {
+			entries = append(entries, GziEntry{CompressedOffset: compressedOffset, UncompressedOffset: uncompressedOffset})
+		}
+		compressedOffset += blockSize
+		uncompressedOffset += dataSize
+	}
+}
+
+// ParseGziIndex parses a samtools .gzi file.
+func ParseGziIndex(r io.Reader) ([]GziEntry, error) {
+	var count uint64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedGziIndex, err)
+	}
+	entries := make([]GziEntry, 0, count)
+	for entryIndex := uint64(0); entryIndex < count; entryIndex++ {
+		var offsets [2]uint64
+		if err := binary.Read(r, binary.LittleEndian, &offsets); err != nil {
+			return nil, fmt.Errorf("%w: expected %d entries but found %d", ErrMalformedGziIndex, count, entryIndex)
+		}
+		entries = append(entries, GziEntry{CompressedOffset: int64(offsets[0]), UncompressedOffset: int64(offsets[1])})
+	}
+	return entries, nil
+}
+
+// BuildGzi writes entries in the samtools .gzi format.
+func BuildGzi(entries []GziEntry) []byte {
+	var gzi bytes.Buffer
+	_ = binary.Write(&gzi, binary.LittleEndian, uint64(len(entries)))
+	for _, entry := range entries {
+		_ = binary.Write(&gzi, binary.LittleEndian, [2]uint64{uint64(entry.CompressedOffset), uint64(entry.UncompressedOffset)})
+	}
+	return gzi.Bytes()
+}
+
+// bgzfReaderAt reads uncompressed bytes out of a BGZF file by starting to decompress at the nearest block.
+type bgzfReaderAt struct {
+	file   io.ReaderAt
+	blocks []GziEntry // every block including the first, sorted by offset.
+}
+
+func newBGZFReaderAt(file io.ReaderAt, entries []GziEntry) *bgzfReaderAt {
+	blocks := append([]GziEntry{{}}, entries...)
+	return &bgzfReaderAt{file: file, blocks: blocks}
+}
+
+// ReadAt implements io.ReaderAt over the uncompressed data.
+func (reader *bgzfReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	blockIndex := sort.Search(len(reader.blocks), func(blockIndex int) bool {
+		return reader.blocks[blockIndex].UncompressedOffset > offset
+	}) - 1
+	block := reader.blocks[blockIndex]
+
+	// gzip readers carry on into the next member so reads can span blocks.
+	gzipReader, err := gzip.NewReader(io.NewSectionReader(reader.file, block.CompressedOffset, 1<<62))
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+	if _, err := io.CopyN(ioutil.Discard, gzipReader, offset-block.UncompressedOffset); err != nil {
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	n, err := io.ReadFull(gzipReader, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// BGZFWriter compresses everything written to it into BGZF blocks.
+type BGZFWriter struct {
+	w      io.Writer
+	buffer []byte
+	err    error
+}
+
+// NewBGZFWriter returns a BGZFWriter writing to w. It must be closed to write the final blocks.
+func NewBGZFWriter(w io.Writer) *BGZFWriter {
+	return &BGZFWriter{w: w, buffer: make([]byte, 0, bgzfBlockData)}
+}
+
+// Write buffers p and writes out every block that fills up.
+func (writer *BGZFWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 && writer.err == nil {
+		space := bgzfBlockData - len(writer.buffer)
+		if space > len(p) {
+			space = len(p)
+		}
+		writer.buffer = append(writer.buffer, p[:space]...)
+		p = p[space:]
+		written += space
+		if len(writer.buffer) == bgzfBlockData {
+			writer.flushBlock()
+		}
+	}
+	return written, writer.err
+}
+
+// Close writes whatever is left over followed by the BGZF end of file block. It doesn't close the underlying writer.
+func (writer *BGZFWriter) Close() error {
+	if len(writer.buffer) > 0 {
+		writer.flushBlock()
+	}
+	if writer.err == nil {
+		_, writer.err = writer.w.Write(bgzfEOF)
+	}
+	return writer.err
+}
+
+func (writer *BGZFWriter) flushBlock() {
+	var block bytes.Buffer
+	gzipWriter, _ := gzip.NewWriterLevel(&block, gzip.DefaultCompression)
+	// BSIZE is filled in once we know how big the block came out.
+	gzipWriter.Header.Extra = []byte{'B', 'C', 2, 0, 0, 0}
+	gzipWriter.Header.OS = 0xff
+	if _, err := gzipWriter.Write(writer.buffer); err != nil {
+		writer.err = err
+		return
+	}
+	if err := gzipWriter.Close(); err != nil {
+		writer.err = err
+		return
+	}
+	blockBytes := block.Bytes()
+	binary.LittleEndian.PutUint16(blockBytes[16:], uint16(len(blockBytes)-1))
+	_, writer.err = writer.w.Write(blockBytes)
+	writer.buffer = writer.buffer[:0]
+}
+
+// WriteBGZF writes fastas laid out according to options to a BGZF compressed file that can be indexed and fetched from.
+func WriteBGZF(fastas []Fasta, path string, options WriteOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	bgzfWriter := NewBGZFWriter(file)
+	writer := NewWriter(bgzfWriter, options)
+	for _, fasta := range fastas {
+		if err := writer.Write(fasta); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := bgzfWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+/******************************************************************************
+
+BGZF specific IO related things end here.
+
+******************************************************************************/
@@ -0,0 +1,422 @@
+package fasta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+FASTA index (.fai) specific IO related things begin here.
+
+Read loads every record in a fasta file into memory which is no good when you
+just want 2 kb out of a 4 GB reference genome. samtools faidx solves this with
+a .fai file that says, for every record, where its sequence starts in the file
+and how its lines are wrapped. With that you can work out exactly which bytes
+hold any stretch of sequence and read just those.
+
+A .fai file has one tab separated line per record:
+
+	NAME	LENGTH	OFFSET	LINEBASES	LINEWIDTH
+
+NAME is the header up to the first whitespace, LENGTH is how many bases the
+record has, OFFSET is the byte its first base is at, LINEBASES is how many
+bases are on each line and LINEWIDTH is how many bytes each line takes up
+including its newline. This only works if every line of a record but the last
+is the same length so, like samtools, we refuse to index files where they
+aren't.
+
+Bgzipped fasta files (see bgzf.go) use the exact same .fai as the uncompressed
+file plus a .gzi that says where to start decompressing.
+
+http://www.htslib.org/doc/faidx.html
+
+******************************************************************************/
+
+// Errors for indexing and fetching. Check for them with errors.Is.
+var (
+	ErrUnevenLines       = errors.New("lines of a record are not all the same length")
+	ErrDuplicateName     = errors.New("duplicate record name")
+	ErrMalformedIndex    = errors.New("malformed .fai index")
+	ErrUnknownSequence   = errors.New("no record with that name")
+	ErrInvalidRange      = errors.New("invalid range")
+	ErrUnsupportedGzip   = errors.New("gzip files have to be bgzipped to be indexed")
+	ErrIndexFileMismatch = errors.New("index does not match file")
+)
+
+// IndexRecord is one line of a .fai index.
+type IndexRecord struct {
+	Name      string `json:"name"`
+	Length    int64  `json:"length"`
+	Offset    int64  `json:"offset"`
+	LineBases int64  `json:"line_bases"`
+	LineWidth int64  `json:"line_width"`
+}
+
+// offsetOf returns where in the file the base at position (0-based) is.
+func (record IndexRecord) offsetOf(position int64) int64 {
+	if record.LineBases == 0 {
+		return record.Offset
+	}
+	return record.Offset + position/record.LineBases*record.LineWidth + position%record.LineBases
+}
+
+/******************************************************************************
+
+Start of .fai index functions
+
+******************************************************************************/
+
+// BuildIndex indexes an uncompressed fasta file. Errors say which line is wrong.
+func BuildIndex(r io.Reader) ([]IndexRecord, error) {
+	reader := bufio.NewReader(r)
+	var records []IndexRecord
+	seen := map[string]bool{}
+	var record *IndexRecord
+	var offset int64
+	var lineNumber int
+	// once a record has a short line or a blank line it can't have any more sequence.
+	var finished bool
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		lineNumber++
+		offset += int64(len(line))
+		width := int64(len(line))
+		bases := int64(len(bytes.TrimRight(line, "\r\n")))
+
+		switch {
+		case line[0] == '>':
+			fields := strings.Fields(string(line[1:]))
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("line %d: record has no name", lineNumber)
+			}
+			name := fields[0]
+			if seen[name] {
+				return nil, fmt.Errorf("line %d: %w: %q", lineNumber, ErrDuplicateName, name)
+			}
+			seen[name] = true
+			records = append(records, IndexRecord{Name: name, Offset: offset})
+			record = &records[len(records)-1]
+			finished = false
+		case bases == 0:
+			finished = true
+		case record == nil:
+			return nil, fmt.Errorf("line %d: sequence before the first > header", lineNumber)
+		case finished:
+			return nil, fmt.Errorf("line %d: %w: %q comes after a shorter line", lineNumber, ErrUnevenLines, record.Name)
+		default:
+			// the last line of the file doesn't need a newline.
+			lastLine := err == io.EOF
+			if record.LineBases == 0 {
+				record.LineBases = bases
+				record.LineWidth = width
+			} else if bases > record.LineBases || (width-bases != record.LineWidth-record.LineBases && !lastLine) {
+				return nil, fmt.Errorf("line %d: %w: %q", lineNumber, ErrUnevenLines, record.Name)
+			}
+			if bases < record.LineBases {
+				finished = true
+			}
+			record.Length += bases
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return records, nil
+}
+
+// ParseIndex parses a .fai file.
+func ParseIndex(r io.Reader) ([]IndexRecord, error) {
+	var records []IndexRecord
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("%w: line %d has %d columns instead of 5", ErrMalformedIndex, lineNumber, len(fields))
+		}
+		record := IndexRecord{Name: fields[0]}
+		for fieldIndex, value := range []*int64{&record.Length, &record.Offset, &record.LineBases, &record.LineWidth} {
+			number, err := strconv.ParseInt(fields[fieldIndex+1], 10, 64)
+			if err != nil || number < 0 {
+				return nil, fmt.Errorf("%w: line %d column %d is not a number: %q", ErrMalformedIndex, lineNumber, fieldIndex+2, fields[fieldIndex+1])
+			}
+			*value = number
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// ReadIndex reads a .fai file.
+func ReadIndex(path string) ([]IndexRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseIndex(file)
+}
+
+// BuildIndexFile writes records in the .fai format.
+func BuildIndexFile(records []IndexRecord) []byte {
+	var index bytes.Buffer
+	for _, record := range records {
+		fmt.Fprintf(&index, "%s\t%d\t%d\t%d\t%d\n", record.Name, record.Length, record.Offset, record.LineBases, record.LineWidth)
+	}
+	return index.Bytes()
+}
+
+// WriteIndex indexes the fasta file at path and writes path.fai next to it like samtools faidx does.
+// Bgzipped files also get a path.gzi. Plain gzipped files can't be indexed.
+func WriteIndex(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bgzf, err := isBGZFFile(file)
+	if err != nil {
+		return err
+	}
+	var records []IndexRecord
+	if bgzf {
+		gziEntries, err := BuildGziIndex(file)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		if records, err = BuildIndex(gzipReader); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path+".gzi", BuildGzi(gziEntries), 0644); err != nil {
+			return err
+		}
+	} else if records, err = BuildIndex(file); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+".fai", BuildIndexFile(records), 0644)
+}
+
+// isBGZFFile checks the start of a file for a BGZF header and rewinds it.
+// Gzipped files that aren't BGZF are an error since nothing can be fetched from them.
+func isBGZFFile(file *os.File) (bool, error) {
+	header := make([]byte, bgzfHeaderSize)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	if IsBGZF(header[:n]) {
+		return true, nil
+	}
+	if n >= 2 && header[0] == 0x1f && header[1] == 0x8b {
+		return false, ErrUnsupportedGzip
+	}
+	return false, nil
+}
+
+/******************************************************************************
+
+Start of indexed fetch functions
+
+******************************************************************************/
+
+// IndexedFasta fetches sequence out of a fasta file using its .fai index without reading the rest of the file.
+type IndexedFasta struct {
+	Records []IndexRecord
+	file    *os.File
+	reader  io.ReaderAt
+	lookup  map[string]int
+}
+
+// OpenIndexed opens a fasta or bgzipped fasta file for fetching.
+// path.fai (and path.gzi for bgzipped files) are used if they exist, otherwise
+// the file is indexed in memory. Use WriteIndex to save the index for next time.
+func OpenIndexed(path string) (*IndexedFasta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := openIndexed(file, path)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return indexed, nil
+}
+
+func openIndexed(file *os.File, path string) (*IndexedFasta, error) {
+	bgzf, err := isBGZFFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed := &IndexedFasta{file: file, reader: file, lookup: map[string]int{}}
+	if bgzf {
+		gziEntries, err := readGziIndex(path)
+		if os.IsNotExist(err) {
+			gziEntries, err = BuildGziIndex(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		indexed.reader = newBGZFReaderAt(file, gziEntries)
+	}
+
+	indexed.Records, err = ReadIndex(path + ".fai")
+	if os.IsNotExist(err) {
+		var r io.Reader = io.NewSectionReader(file, 0, 1<<62)
+		if bgzf {
+			if r, err = gzip.NewReader(r); err != nil {
+				return nil, err
+			}
+		}
+		indexed.Records, err = BuildIndex(r)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for recordIndex, record := range indexed.Records {
+		indexed.lookup[record.Name] = recordIndex
+	}
+	return indexed, nil
+}
+
+func readGziIndex(path string) ([]GziEntry, error) {
+	file, err := os.Open(path + ".gzi")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseGziIndex(file)
+}
+
+// Fetch returns the bases from start up to but not including end (0-based like the rest of poly) of the record called name.
+func (indexed *IndexedFasta) Fetch(name string, start, end int64) (string, error) {
+	recordIndex, ok := indexed.lookup[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownSequence, name)
+	}
+	record := indexed.Records[recordIndex]
+	if start < 0 || end > record.Length || start > end {
+		return "", fmt.Errorf("%w: %d to %d of %q which is %d long", ErrInvalidRange, start, end, name, record.Length)
+	}
+	if start == end {
+		return "", nil
+	}
+
+	first := record.offsetOf(start)
+	last := record.offsetOf(end - 1)
+	raw := make([]byte, last-first+1)
+	if n, err := indexed.reader.ReadAt(raw, first); n < len(raw) {
+		return "", fmt.Errorf("%w: %q is cut short: %v", ErrIndexFileMismatch, name, err)
+	}
+
+	// everything between the bases should be line endings.
+	sequence := make([]byte, 0, end-start)
+	for _, character := range raw {
+		if character != '\n' && character != '\r' {
+			sequence = append(sequence, character)
+		}
+	}
+	if int64(len(sequence)) != end-start || bytes.IndexByte(sequence, '>') != -1 {
+		return "", fmt.Errorf("%w: %q does not have the line lengths the index says it does", ErrIndexFileMismatch, name)
+	}
+	return string(sequence), nil
+}
+
+// FetchRecord returns the whole record called name.
+func (indexed *IndexedFasta) FetchRecord(name string) (Fasta, error) {
+	recordIndex, ok := indexed.lookup[name]
+	if !ok {
+		return Fasta{}, fmt.Errorf("%w: %q", ErrUnknownSequence, name)
+	}
+	sequence, err := indexed.Fetch(name, 0, indexed.Records[recordIndex].Length)
+	return Fasta{Name: name, Sequence: sequence}, err
+}
+
+// FetchRegion fetches a samtools style region like chr1:1,001-2,000 where
+// positions are 1-based and inclusive. A region without a range is the whole
+// record and one without an end runs to the end of the record.
+func (indexed *IndexedFasta) FetchRegion(region string) (string, error) {
+	name, start, end, err := indexed.parseRegion(region)
+	if err != nil {
+		return "", err
+	}
+	return indexed.Fetch(name, start, end)
+}
+
+// parseRegion turns a samtools style region into a name and 0-based half open range.
+func (indexed *IndexedFasta) parseRegion(region string) (string, int64, int64, error) {
+	// names can have colons in them so the whole region is tried as a name first.
+	if recordIndex, ok := indexed.lookup[region]; ok {
+		return region, 0, indexed.Records[recordIndex].Length, nil
+	}
+	colon := strings.LastIndex(region, ":")
+	if colon == -1 {
+		return "", 0, 0, fmt.Errorf("%w: %q", ErrUnknownSequence, region)
+	}
+	name := region[:colon]
+	recordIndex, ok := indexed.lookup[name]
+	if !ok {
+		return "", 0, 0, fmt.Errorf("%w: %q", ErrUnknownSequence, name)
+	}
+
+	rangeString := strings.ReplaceAll(region[colon+1:], ",", "")
+	startString, endString := rangeString, ""
+	if dash := strings.Index(rangeString, "-"); dash != -1 {
+		startString, endString = rangeString[:dash], rangeString[dash+1:]
+	}
+	start, err := strconv.ParseInt(startString, 10, 64)
+	if err != nil || start < 1 {
+		return "", 0, 0, fmt.Errorf("%w: %q", ErrInvalidRange, region)
+	}
+	end := indexed.Records[recordIndex].Length
+	if endString != "" {
+		if end, err = strconv.ParseInt(endString, 10, 64); err != nil {
+			return "", 0, 0, fmt.Errorf("%w: %q", ErrInvalidRange, region)
+		}
+	}
+	return name, start - 1, end, nil
+}
+
+// Close closes the underlying file.
+func (indexed *IndexedFasta) Close() error {
+	return indexed.file.Close()
+}
+
+/******************************************************************************
+
+FASTA index (.fai) specific IO related things end here.
+
+******************************************************************************/
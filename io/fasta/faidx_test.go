@@ -0,0 +1,273 @@
+package fasta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// ExampleOpenIndexed shows how to pull a region out of a fasta file without reading all of it.
+func ExampleOpenIndexed() {
+	indexed, _ := OpenIndexed("data/faidx.fasta.gz")
+	defer indexed.Close()
+
+	sequence, _ := indexed.Fetch("chrM", 65, 75)
+	region, _ := indexed.FetchRegion("chrM:66-75")
+	fmt.Println(sequence)
+	fmt.Println(region)
+	// Output:
+	// ACTGCGCGTC
+	// ACTGCGCGTC
+}
+
+// ExampleWriteIndex shows how to write a samtools compatible .fai index.
+func ExampleWriteIndex() {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	path := tmpDataDir + "/faidx.fasta"
+	fastas, _ := Read("data/faidx.fasta")
+	_ = WriteWithOptions(fastas, path, WriteOptions{LineWidth: 80})
+	_ = WriteIndex(path)
+
+	index, _ := ioutil.ReadFile(path + ".fai")
+	fmt.Print(string(index))
+	// Output:
+	// pOpen_v3	2686	35	80	81
+	// chrM	1000	2780	80	81
+	// short	7	3800	7	8
+}
+
+func TestBuildIndex(t *testing.T) {
+	expected, err := ReadIndex("data/faidx.fasta.fai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := os.Open("data/faidx.fasta")
+	defer file.Close()
+	records, err := BuildIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, records); diff != "" {
+		t.Errorf("Index doesn't match samtools (-want +got):\n%s", diff)
+	}
+
+	// windows line endings count towards the line width and the last line doesn't need a newline.
+	records, err = BuildIndex(strings.NewReader(">a desc\r\nACG\r\nTA\r\n>b\r\nAC\r\nGT\r\nA"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []IndexRecord{{Name: "a", Length: 5, Offset: 9, LineBases: 3, LineWidth: 5}, {Name: "b", Length: 5, Offset: 22, LineBases: 2, LineWidth: 4}}
+	if diff := cmp.Diff(expected, records); diff != "" {
+		t.Errorf("CRLF file was not indexed (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildIndexErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		fasta string
+		err   error
+	}{
+		{name: "short line in the middle", fasta: ">a\nACGT\nAC\nACGT\n", err: ErrUnevenLines},
+		{name: "long line", fasta: ">a\nACGT\nACGTA\n", err: ErrUnevenLines},
+		{name: "blank line in the middle", fasta: ">a\nACGT\n\nACGT\n", err: ErrUnevenLines},
+		{name: "mixed line endings", fasta: ">a\nACGT\r\nACGT\nAC\n", err: ErrUnevenLines},
+		{name: "duplicate names", fasta: ">a\nACGT\n>a\nACGT\n", err: ErrDuplicateName},
+	}
+	for _, test := range tests {
+		if _, err := BuildIndex(strings.NewReader(test.fasta)); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q. Got: %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	fastas, _ := Read("data/faidx.fasta")
+
+	for _, path := range []string{"data/faidx.fasta", "data/faidx.fasta.gz"} {
+		indexed, err := OpenIndexed(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, fasta := range fastas {
+			name := strings.Fields(fasta.Name)[0]
+			length := int64(len(fasta.Sequence))
+			// every range that starts or ends on or around a line or block boundary.
+			for _, start := range []int64{0, 1, 59, 60, 61, 69, 70, 999, 1000, length - 1, length} {
+				for _, end := range []int64{start, start + 1, start + 60, start + 1001, length} {
+					if start < 0 || start > length || end > length || end < start {
+						continue
+					}
+					sequence, err := indexed.Fetch(name, start, end)
+					if err != nil {
+						t.Fatalf("%s: %s %d-%d: %s", path, name, start, end, err)
+					}
+					if sequence != fasta.Sequence[start:end] {
+						t.Errorf("%s: %s %d-%d came back wrong", path, name, start, end)
+					}
+				}
+			}
+
+			record, err := indexed.FetchRecord(name)
+			if err != nil || record.Sequence != fasta.Sequence {
+				t.Errorf("%s: whole record %s came back wrong: %v", path, name, err)
+			}
+		}
+
+		if _, err := indexed.Fetch("chrM", 10, 1001); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("%s: expected ErrInvalidRange past the end. Got: %v", path, err)
+		}
+		if _, err := indexed.Fetch("chr1", 0, 1); !errors.Is(err, ErrUnknownSequence) {
+			t.Errorf("%s: expected ErrUnknownSequence. Got: %v", path, err)
+		}
+		indexed.Close()
+	}
+}
+
+func TestFetchRegion(t *testing.T) {
+	indexed, err := OpenIndexed("data/faidx.fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indexed.Close()
+	chrM, _ := indexed.FetchRecord("chrM")
+
+	tests := []struct {
+		region   string
+		expected string
+	}{
+		{region: "chrM", expected: chrM.Sequence},
+		{region: "chrM:1-10", expected: chrM.Sequence[:10]},
+		{region: "chrM:901", expected: chrM.Sequence[900:]},
+		{region: "chrM:1-1,000", expected: chrM.Sequence},
+	}
+	for _, test := range tests {
+		sequence, err := indexed.FetchRegion(test.region)
+		if err != nil {
+			t.Fatalf("%s: %s", test.region, err)
+		}
+		if sequence != test.expected {
+			t.Errorf("%s came back wrong", test.region)
+		}
+	}
+
+	for _, region := range []string{"chrM:0-10", "chrM:ten", "chrM:10-5", "chrX:1-10"} {
+		if _, err := indexed.FetchRegion(region); err == nil {
+			t.Errorf("Expected an error fetching %q", region)
+		}
+	}
+}
+
+func TestBGZF(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// big enough to need several blocks.
+	var fastas []Fasta
+	original, _ := Read("data/faidx.fasta")
+	for copyIndex := 0; copyIndex < 4; copyIndex++ {
+		for _, fasta := range original {
+			fastas = append(fastas, Fasta{Name: fmt.Sprintf("copy%d_%s", copyIndex, fasta.Name), Sequence: strings.Repeat(fasta.Sequence, 20)})
+		}
+	}
+	path := tmpDataDir + "/big.fasta.gz"
+	if err := WriteBGZF(fastas, path, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// it's still just gzip.
+	if gzipped, _ := ReadGz(path); !cmp.Equal(fastas, gzipped) {
+		t.Errorf("BGZF file doesn't read back as gzip")
+	}
+
+	if err := WriteIndex(path); err != nil {
+		t.Fatal(err)
+	}
+	gziEntries, err := readGziIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gziEntries) < 2 {
+		t.Errorf("Expected the file to be split into several blocks. Got %d", len(gziEntries)+1)
+	}
+	for _, entry := range gziEntries {
+		if entry.UncompressedOffset%bgzfBlockData != 0 {
+			t.Errorf("Blocks should hold %d bytes each. Got one starting at %d", bgzfBlockData, entry.UncompressedOffset)
+		}
+	}
+
+	indexed, err := OpenIndexed(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indexed.Close()
+	// across the first block boundary.
+	for recordIndex, record := range indexed.Records {
+		if record.Offset > bgzfBlockData-100 || record.Offset+record.Length < bgzfBlockData+100 {
+			continue
+		}
+		start := bgzfBlockData - 100 - record.Offset
+		sequence, err := indexed.Fetch(record.Name, start, start+200)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sequence != fastas[recordIndex].Sequence[start:start+200] {
+			t.Errorf("Fetch across a block boundary came back wrong")
+		}
+	}
+
+	// the .gzi samtools would have written.
+	python, _ := ioutil.ReadFile("data/faidx.fasta.gz")
+	entries, err := BuildGziIndex(bytes.NewReader(python))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []GziEntry{{435, 1000}, {833, 2000}, {1262, 3000}}
+	if diff := cmp.Diff(expected, entries); diff != "" {
+		t.Errorf("Wrong .gzi index (-want +got):\n%s", diff)
+	}
+	reparsed, err := ParseGziIndex(bytes.NewReader(BuildGzi(entries)))
+	if err != nil || !cmp.Equal(entries, reparsed) {
+		t.Errorf(".gzi index does not round trip: %v", err)
+	}
+}
+
+func TestPlainGzipIsRejected(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	fastas, _ := Read("data/faidx.fasta")
+	_, _ = gzipWriter.Write(Build(fastas))
+	gzipWriter.Close()
+	path := tmpDataDir + "/plain.fasta.gz"
+	_ = ioutil.WriteFile(path, gzipped.Bytes(), 0644)
+
+	if err := WriteIndex(path); !errors.Is(err, ErrUnsupportedGzip) {
+		t.Errorf("Expected ErrUnsupportedGzip. Got: %v", err)
+	}
+	if _, err := OpenIndexed(path); !errors.Is(err, ErrUnsupportedGzip) {
+		t.Errorf("Expected ErrUnsupportedGzip. Got: %v", err)
+	}
+}
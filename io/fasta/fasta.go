@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
 	"strings"
+
+	"github.com/Open-Science-Global/poly/io/flatfile"
 )
 
 /******************************************************************************
@@ -42,6 +45,14 @@ Keoni
 
 ******************************************************************************/
 
+// ErrMissingHeader is wrapped in a ParseError when sequence turns up before any header. Check for it with errors.Is.
+var (
+	ErrMissingHeader = errors.New("sequence before the first > header")
+)
+
+// ParseError is a problem with a specific line of a fasta file.
+type ParseError = flatfile.ParseError
+
 // Fasta is a struct representing a single Fasta file element with a Name and its corresponding Sequence.
 type Fasta struct {
 	Name     string `json:"name"`
@@ -49,35 +60,50 @@ type Fasta struct {
 }
 
 // Parse parses a given Fasta file into an array of Fasta structs. Internally, it uses ParseFastaConcurrent.
-func Parse(r io.Reader) []Fasta {
+// Records read before an error are returned along with it.
+func Parse(r io.Reader) ([]Fasta, error) {
 	fastas := make(chan Fasta, 1000) // A buffer is used so that the functions runs as it is appending to outputFastas
-	go ParseConcurrent(r, fastas)
+	done := make(chan error, 1)
+	go func() { done <- ParseConcurrent(r, fastas) }()
 
 	var outputFastas []Fasta
 	for fasta := range fastas {
 		outputFastas = append(outputFastas, fasta)
 	}
-	return outputFastas
+	return outputFastas, <-done
 }
 
 // ParseConcurrent concurrently parses a given Fasta file in an io.Reader into a channel of Fasta structs.
-func ParseConcurrent(r io.Reader, sequences chan<- Fasta) {
+// The channel is closed when the file ends or at the first error, which is returned.
+func ParseConcurrent(r io.Reader, sequences chan<- Fasta) error {
+	defer close(sequences)
+
 	// Initialize necessary variables
 	var sequenceLines []string
 	var name string
+	var lineNumber int
 	start := true
 
-	// Start the scanner
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
+	// bufio.Scanner can't read lines longer than 64 KB, which whole chromosomes on one line are, so lines are read whole.
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && line == "" {
+			break
+		}
+		lineNumber++
+		line = strings.TrimRight(line, "\r\n")
 		switch {
 		// if there's nothing on this line skip this iteration of the loop
 		case len(line) == 0:
-			continue
 		// if it's a comment skip this line
 		case line[0:1] == ";":
-			continue
+		// sequence with nowhere to go
+		case line[0:1] != ">" && start:
+			return &ParseError{Line: lineNumber, Text: line, Err: ErrMissingHeader}
 		// start of a fasta line
 		case line[0:1] != ">":
 			sequenceLines = append(sequenceLines, line)
@@ -97,14 +123,19 @@ func ParseConcurrent(r io.Reader, sequences chan<- Fasta) {
 			name = line[1:]
 			start = false
 		}
+		if err == io.EOF {
+			break
+		}
 	}
 	// Add final sequence in file to channel
-	sequence := strings.Join(sequenceLines, "")
-	newFasta := Fasta{
-		Name:     name,
-		Sequence: sequence}
-	sequences <- newFasta
-	close(sequences)
+	if !start {
+		sequence := strings.Join(sequenceLines, "")
+		newFasta := Fasta{
+			Name:     name,
+			Sequence: sequence}
+		sequences <- newFasta
+	}
+	return nil
 }
 
 /******************************************************************************
@@ -113,32 +144,59 @@ Start of  Read functions
 
 ******************************************************************************/
 
-// ReadGzConcurrent concurrently reads a gzipped Fasta file into a Fasta channel.
-func ReadGzConcurrent(path string, sequences chan<- Fasta) {
-	file, _ := os.Open(path)
-	r, _ := gzip.NewReader(file)
-	go ParseConcurrent(r, sequences)
+// ReadGzConcurrent reads a gzipped Fasta file into a Fasta channel, closing it when the file ends or at the first error,
+// which is returned. It blocks until the whole file has been read so run it in its own goroutine.
+func ReadGzConcurrent(path string, sequences chan<- Fasta) error {
+	file, err := os.Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	defer r.Close()
+	return ParseConcurrent(r, sequences)
 }
 
-// ReadConcurrent concurrently reads a flat Fasta file into a Fasta channel.
-func ReadConcurrent(path string, sequences chan<- Fasta) {
-	file, _ := os.Open(path)
-	go ParseConcurrent(file, sequences)
+// ReadConcurrent reads a flat Fasta file into a Fasta channel, closing it when the file ends or at the first error,
+// which is returned. It blocks until the whole file has been read so run it in its own goroutine.
+func ReadConcurrent(path string, sequences chan<- Fasta) error {
+	file, err := os.Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	defer file.Close()
+	return ParseConcurrent(file, sequences)
 }
 
 // ReadGz reads a gzipped  file into an array of Fasta structs.
-func ReadGz(path string) []Fasta {
-	file, _ := os.Open(path)
-	r, _ := gzip.NewReader(file)
-	fastas := Parse(r)
-	return fastas
+func ReadGz(path string) ([]Fasta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
 }
 
 // Read reads a  file into an array of Fasta structs
-func Read(path string) []Fasta {
-	file, _ := os.Open(path)
-	fastas := Parse(file)
-	return fastas
+func Read(path string) ([]Fasta, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
 }
 
 /******************************************************************************
@@ -147,20 +205,101 @@ Start of  Write functions
 
 ******************************************************************************/
 
+// WriteOptions control how sequences are laid out when they're written. The zero value writes every sequence on one line as it is.
+type WriteOptions struct {
+	// LineWidth wraps sequences after this many characters. samtools and most everything else uses 60. 0 doesn't wrap.
+	LineWidth int
+	// Uppercase writes every sequence in upper case. Lower case bases are how
+	// repeats are softmasked in reference genomes so by default case is kept.
+	Uppercase bool
+}
+
 // Build writes a Fasta struct to a  string.
 func Build(fastas []Fasta) []byte {
+	return BuildWithOptions(fastas, WriteOptions{})
+}
+
+// BuildWithOptions writes Fasta structs to a  string laid out according to options.
+func BuildWithOptions(fastas []Fasta, options WriteOptions) []byte {
 	var fastaString bytes.Buffer
+	writer := NewWriter(&fastaString, options)
 	for _, fasta := range fastas {
-		fastaString.WriteString(">")
-		fastaString.WriteString(fasta.Name)
-		fastaString.WriteString("\n")
-		fastaString.WriteString(fasta.Sequence)
-		fastaString.WriteString("\n")
+		_ = writer.Write(fasta)
 	}
+	_ = writer.Flush()
 	return fastaString.Bytes()
 }
 
 // Write writes a  string to a file.
-func Write(fastas []Fasta, path string) {
-	_ = ioutil.WriteFile(path, Build(fastas), 0644)
+func Write(fastas []Fasta, path string) error {
+	return WriteWithOptions(fastas, path, WriteOptions{})
+}
+
+// WriteWithOptions writes a  string laid out according to options to a file.
+func WriteWithOptions(fastas []Fasta, path string, options WriteOptions) error {
+	return ioutil.WriteFile(path, BuildWithOptions(fastas, options), 0644)
+}
+
+// Writer writes Fasta structs to an io.Writer one at a time so that they never all have to be in memory.
+type Writer struct {
+	w       *bufio.Writer
+	options WriteOptions
+}
+
+// NewWriter returns a Writer that writes to w. Flush it when you're done.
+func NewWriter(w io.Writer, options WriteOptions) *Writer {
+	return &Writer{w: bufio.NewWriter(w), options: options}
+}
+
+// Write writes a single Fasta struct.
+func (writer *Writer) Write(fasta Fasta) error {
+	sequence := fasta.Sequence
+	if writer.options.Uppercase {
+		sequence = strings.ToUpper(sequence)
+	}
+
+	writer.w.WriteString(">")
+	writer.w.WriteString(fasta.Name)
+	writer.w.WriteString("\n")
+	lineWidth := writer.options.LineWidth
+	if lineWidth <= 0 {
+		lineWidth = len(sequence)
+	}
+	for lineStart := 0; lineStart < len(sequence); lineStart += lineWidth {
+		lineEnd := lineStart + lineWidth
+		if lineEnd > len(sequence) {
+			lineEnd = len(sequence)
+		}
+		writer.w.WriteString(sequence[lineStart:lineEnd])
+		writer.w.WriteString("\n")
+	}
+	// empty sequences still get a line so they look like they always have.
+	if len(sequence) == 0 {
+		writer.w.WriteString("\n")
+	}
+	// bufio.Writer remembers the first error so checking once covers every write above.
+	_, err := writer.w.WriteString("")
+	return err
+}
+
+// Flush writes anything still buffered to the underlying io.Writer.
+func (writer *Writer) Flush() error {
+	return writer.w.Flush()
+}
+
+// WriteConcurrent writes Fasta structs to w as they come in over a channel
+// until it's closed. If writing fails the rest of the channel is drained so
+// whatever is sending to it doesn't get stuck, and the first error is returned.
+func WriteConcurrent(w io.Writer, sequences <-chan Fasta, options WriteOptions) error {
+	writer := NewWriter(w, options)
+	var err error
+	for fasta := range sequences {
+		if err == nil {
+			err = writer.Write(fasta)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
 }
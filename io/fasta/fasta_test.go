@@ -2,13 +2,21 @@ package fasta
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 // ExampleRead shows basic usage for Read.
 func ExampleRead() {
-	fastas := Read("data/base.fasta")
+	fastas, _ := Read("data/base.fasta")
 	fmt.Println(fastas[0].Name)
 	// Output: gi|5524211|gb|AAD44166.1| cytochrome b [Elephas maximus maximus]
 }
@@ -16,7 +24,7 @@ func ExampleRead() {
 // ExampleParse shows basic usage for Parse.
 func ExampleParse() {
 	file, _ := os.Open("data/base.fasta")
-	fastas := Parse(file)
+	fastas, _ := Parse(file)
 
 	fmt.Println(fastas[0].Name)
 	// Output: gi|5524211|gb|AAD44166.1| cytochrome b [Elephas maximus maximus]
@@ -24,8 +32,8 @@ func ExampleParse() {
 
 // ExampleBuild shows basic usage for Build
 func ExampleBuild() {
-	fastas := Read("data/base.fasta") // get example data
-	fasta := Build(fastas)            // build a fasta byte array
+	fastas, _ := Read("data/base.fasta") // get example data
+	fasta := Build(fastas)               // build a fasta byte array
 	firstLine := string(bytes.Split(fasta, []byte("\n"))[0])
 
 	fmt.Println(firstLine)
@@ -34,9 +42,9 @@ func ExampleBuild() {
 
 // ExampleWrite shows basic usage of the  writer.
 func ExampleWrite() {
-	fastas := Read("data/base.fasta")       // get example data
-	Write(fastas, "data/test.fasta")        // write it out again
-	testSequence := Read("data/test.fasta") // read it in again
+	fastas, _ := Read("data/base.fasta")       // get example data
+	_ = Write(fastas, "data/test.fasta")       // write it out again
+	testSequence, _ := Read("data/test.fasta") // read it in again
 
 	os.Remove("data/test.fasta") // getting rid of test file
 
@@ -46,7 +54,7 @@ func ExampleWrite() {
 
 // ExampleReadGz shows basic usage for ReadGz on a gzip'd file.
 func ExampleReadGz() {
-	fastas := ReadGz("data/uniprot_1mb_test.fasta.gz")
+	fastas, _ := ReadGz("data/uniprot_1mb_test.fasta.gz")
 	var name string
 	for _, fasta := range fastas {
 		name = fasta.Name
@@ -59,11 +67,15 @@ func ExampleReadGz() {
 // ExampleReadGzConcurrent shows how to use the concurrent  parser for larger files.
 func ExampleReadGzConcurrent() {
 	fastas := make(chan Fasta, 1000)
-	go ReadGzConcurrent("data/uniprot_1mb_test.fasta.gz", fastas)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadGzConcurrent("data/uniprot_1mb_test.fasta.gz", fastas) }()
 	var name string
 	for fasta := range fastas {
 		name = fasta.Name
 	}
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
 
 	fmt.Println(name)
 	// Output: sp|P86857|AGP_MYTCA Alanine and glycine-rich protein (Fragment) OS=Mytilus californianus OX=6549 PE=1 SV=1
@@ -72,12 +84,174 @@ func ExampleReadGzConcurrent() {
 // ExampleReadConcurrent shows how to use the concurrent  parser for decompressed fasta files.
 func ExampleReadConcurrent() {
 	fastas := make(chan Fasta, 100)
-	go ReadConcurrent("data/base.fasta", fastas)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadConcurrent("data/base.fasta", fastas) }()
 	var name string
 	for fasta := range fastas {
 		name = fasta.Name
 	}
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
 
 	fmt.Println(name)
 	// Output: MCHU - Calmodulin - Human, rabbit, bovine, rat, and chicken
 }
+
+// ExampleWriteWithOptions shows how to wrap lines and drop softmasking when writing.
+func ExampleWriteWithOptions() {
+	fastas := []Fasta{{Name: "masked", Sequence: "ACGTacgtacgtACGTAC"}}
+	fmt.Print(string(BuildWithOptions(fastas, WriteOptions{LineWidth: 8})))
+	fmt.Print(string(BuildWithOptions(fastas, WriteOptions{LineWidth: 8, Uppercase: true})))
+	// Output:
+	// >masked
+	// ACGTacgt
+	// acgtACGT
+	// AC
+	// >masked
+	// ACGTACGT
+	// ACGTACGT
+	// AC
+}
+
+// ExampleWriteConcurrent shows how to write fasta records as they're made without holding them all in memory.
+func ExampleWriteConcurrent() {
+	fastas := make(chan Fasta, 100)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadGzConcurrent("data/uniprot_1mb_test.fasta.gz", fastas) }()
+
+	var output bytes.Buffer
+	_ = WriteConcurrent(&output, fastas, WriteOptions{LineWidth: 60})
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
+	written, _ := Parse(&output)
+
+	fmt.Println(len(written))
+	// Output: 12205
+}
+
+func TestParseLongLines(t *testing.T) {
+	// far past bufio.Scanner's 64 KB limit.
+	chromosome := strings.Repeat("ACGTN", 100000)
+	file := ">chr1\n" + chromosome + "\n>chr2\r\n" + chromosome + "\r\n"
+	fastas, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fastas) != 2 || fastas[0].Sequence != chromosome || fastas[1].Sequence != chromosome || fastas[1].Name != "chr2" {
+		t.Errorf("Long lines were not parsed")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(strings.NewReader("; a comment\n\nACGT\n>a\nACGT\n"))
+	var parseError *ParseError
+	if !errors.As(err, &parseError) || parseError.Line != 3 || !errors.Is(err, ErrMissingHeader) {
+		t.Errorf("Expected ErrMissingHeader on line 3. Got: %v", err)
+	}
+
+	// read errors aren't swallowed and whatever was read before them is kept.
+	readError := errors.New("disk on fire")
+	fastas, err := Parse(io.MultiReader(strings.NewReader(">a\nACGT\n>b\nAC"), iotest.ErrReader(readError)))
+	if !errors.Is(err, readError) {
+		t.Errorf("Expected the read error to be returned. Got: %v", err)
+	}
+	if len(fastas) != 1 {
+		t.Errorf("Expected the record before the error. Got %d records", len(fastas))
+	}
+
+	if fastas, err := Parse(strings.NewReader("")); err != nil || len(fastas) != 0 {
+		t.Errorf("An empty file should have no records. Got %d and %v", len(fastas), err)
+	}
+	if _, err := Read("data/missing.fasta"); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file error. Got: %v", err)
+	}
+	if _, err := ReadGz("data/base.fasta"); err == nil {
+		t.Errorf("Expected an error reading a flat file as gzip")
+	}
+	fastaChannel := make(chan Fasta)
+	if err := ReadConcurrent("data/missing.fasta", fastaChannel); err == nil {
+		t.Errorf("Expected an error reading a missing file concurrently")
+	}
+	if _, ok := <-fastaChannel; ok {
+		t.Errorf("ReadConcurrent should close the channel when it fails")
+	}
+}
+
+func TestReadConcurrentErrors(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	// a gzip file that is cut off part way through only fails once it has been partly read.
+	gzipped, _ := ioutil.ReadFile("data/uniprot_1mb_test.fasta.gz")
+	truncatedPath := tmpDataDir + "/truncated.fasta.gz"
+	_ = ioutil.WriteFile(truncatedPath, gzipped[:len(gzipped)/2], 0644)
+	fastas := make(chan Fasta, 100)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadGzConcurrent(truncatedPath, fastas) }()
+	var records int
+	for range fastas {
+		records++
+	}
+	if err := <-errs; err == nil || records == 0 {
+		t.Errorf("Expected records followed by an error reading a truncated gzip file. Got %d records and %v", records, err)
+	}
+
+	malformedPath := tmpDataDir + "/malformed.fasta"
+	_ = ioutil.WriteFile(malformedPath, []byte("ACGT\n>a\nACGT\n"), 0644)
+	fastas = make(chan Fasta, 100)
+	if err := ReadConcurrent(malformedPath, fastas); !errors.Is(err, ErrMissingHeader) {
+		t.Errorf("Expected ErrMissingHeader from ReadConcurrent. Got: %v", err)
+	}
+}
+
+func TestBuildWithOptions(t *testing.T) {
+	fastas, _ := Read("data/base.fasta")
+	for _, lineWidth := range []int{0, 1, 60, 70, 80} {
+		built := BuildWithOptions(fastas, WriteOptions{LineWidth: lineWidth})
+		for _, line := range strings.Split(string(built), "\n") {
+			if lineWidth > 0 && !strings.HasPrefix(line, ">") && len(line) > lineWidth {
+				t.Fatalf("Line longer than %d: %q", lineWidth, line)
+			}
+		}
+		reparsed, err := Parse(bytes.NewReader(built))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(fastas, reparsed); diff != "" {
+			t.Errorf("Wrapping at %d does not round trip (-want +got):\n%s", lineWidth, diff)
+		}
+	}
+
+	if built := string(Build([]Fasta{{Name: "empty"}})); built != ">empty\n\n" {
+		t.Errorf("Empty sequences should still get a line. Got: %q", built)
+	}
+}
+
+func TestWriteConcurrentErrors(t *testing.T) {
+	fastas := make(chan Fasta)
+	go func() {
+		for fastaIndex := 0; fastaIndex < 1000; fastaIndex++ {
+			fastas <- Fasta{Name: "a", Sequence: strings.Repeat("A", 1000)}
+		}
+		close(fastas)
+	}()
+
+	// the channel has to be drained even though the writer fails, otherwise this hangs.
+	writeError := errors.New("disk full")
+	if err := WriteConcurrent(failingWriter{writeError}, fastas, WriteOptions{}); !errors.Is(err, writeError) {
+		t.Errorf("Expected the write error. Got: %v", err)
+	}
+
+	if err := Write(nil, "data/missing/test.fasta"); err == nil {
+		t.Errorf("Expected an error writing into a missing directory")
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (writer failingWriter) Write(p []byte) (int, error) { return 0, writer.err }
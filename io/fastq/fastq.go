@@ -0,0 +1,443 @@
+package fastq
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Open-Science-Global/poly/io/flatfile"
+)
+
+/******************************************************************************
+
+FASTQ specific IO related things begin here.
+
+FASTQ is FASTA with a quality score for every base. It's what comes off of
+sequencers so it's what we get back when we send plasmids off for whole
+plasmid sequencing on nanopore or Illumina machines.
+
+Each read is four parts:
+
+	@identifier optional description
+	SEQUENCE
+	+
+	QUALITY
+
+The quality line has one character per base. Each character is a Phred score
+(-10 * log10 of the probability the base call is wrong) plus an offset so that
+it's printable. Everything made in the last decade uses an offset of 33
+(Phred+33) but old Illumina pipelines (1.3 to 1.7) used 64 (Phred+64) so both
+are decoded here. Quality strings are kept exactly as they were read and only
+decoded when you ask for scores so files round trip untouched.
+
+The original Sanger format allowed sequence and quality to wrap over several
+lines. Nobody writes them like that anymore but they are parsed anyway.
+
+Sequencers stuff per-read metadata into the description after the identifier.
+Nanopore writes key=value pairs like runid=... read=12 ch=105 while Illumina
+(CASAVA 1.8 and up) writes read:is_filtered:control_number:index. Both are
+pulled out into Fastq.Optionals.
+
+Like the fasta parser this one can run concurrently so that runs which are far
+too big to fit into RAM can be processed as they're read.
+
+https://en.wikipedia.org/wiki/FASTQ_format
+https://doi.org/10.1093/nar/gkp1137
+
+******************************************************************************/
+
+// Encoding is the ASCII offset added to Phred scores in a quality string.
+type Encoding int
+
+// The two offsets anyone has ever used in the wild.
+const (
+	Phred33 Encoding = 33
+	Phred64 Encoding = 64
+)
+
+// Problems a FASTQ read can have. They are wrapped in a ParseError naming the line so check them with errors.Is.
+var (
+	ErrMissingHeader    = errors.New("read does not start with @")
+	ErrMissingSeparator = errors.New("sequence is not followed by a + line")
+	ErrQualityLength    = errors.New("quality and sequence lengths differ")
+	ErrInvalidQuality   = errors.New("invalid quality character")
+	ErrTruncated        = errors.New("truncated file")
+)
+
+// ParseError is a problem with a specific line of a FASTQ file. Line counts every line of the file, not reads.
+type ParseError = flatfile.ParseError
+
+// Fastq is a single read from a FASTQ file.
+type Fastq struct {
+	Identifier  string            `json:"identifier"`
+	Description string            `json:"description"`
+	Optionals   map[string]string `json:"optionals"`
+	Sequence    string            `json:"sequence"`
+	Quality     string            `json:"quality"`
+}
+
+/******************************************************************************
+
+Start of FASTQ Parse functions
+
+******************************************************************************/
+
+// Parse parses a given FASTQ file into an array of Fastq structs. Internally, it uses ParseConcurrent.
+func Parse(r io.Reader) ([]Fastq, error) {
+	reads := make(chan Fastq, 1000) // A buffer is used so that the functions runs as it is appending to outputReads
+	done := make(chan error, 1)
+	go func() { done <- ParseConcurrent(r, reads) }()
+
+	var outputReads []Fastq
+	for read := range reads {
+		outputReads = append(outputReads, read)
+	}
+	return outputReads, <-done
+}
+
+// ParseConcurrent concurrently parses a given FASTQ file in an io.Reader into a channel of Fastq structs.
+// The channel is closed when the file ends or at the first malformed read, whose error is returned.
+func ParseConcurrent(r io.Reader, reads chan<- Fastq) error {
+	defer close(reads)
+
+	reader := bufio.NewReader(r)
+	var lineNumber int
+	// reads are long enough that bufio.Scanner's default limit is too small so lines are read whole.
+	readLine := func() (string, bool, error) {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", false, err
+		}
+		if err == io.EOF && line == "" {
+			return "", false, nil
+		}
+		lineNumber++
+		return strings.TrimRight(line, "\r\n"), true, nil
+	}
+
+	for {
+		header, ok, err := readLine()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if header == "" {
+			continue
+		}
+		if header[0] != '@' {
+			return &ParseError{Line: lineNumber, Text: header, Err: ErrMissingHeader}
+		}
+		headerLine := lineNumber
+		read := parseHeader(header[1:])
+
+		// sequence runs until the + separator.
+		var sequence strings.Builder
+		for {
+			line, ok, err := readLine()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &ParseError{Line: headerLine, Text: header, Err: fmt.Errorf("%w: read has no + line", ErrTruncated)}
+			}
+			if strings.HasPrefix(line, "+") {
+				if separator := line[1:]; separator != "" && separator != header[1:] {
+					return &ParseError{Line: lineNumber, Text: line, Err: fmt.Errorf("%w: + line does not repeat the header", ErrMissingSeparator)}
+				}
+				break
+			}
+			if strings.HasPrefix(line, "@") && sequence.Len() > 0 {
+				return &ParseError{Line: lineNumber, Text: line, Err: ErrMissingSeparator}
+			}
+			sequence.WriteString(strings.TrimSpace(line))
+		}
+		read.Sequence = sequence.String()
+
+		// quality runs until it is as long as the sequence. It can't be
+		// split on @ like the sequence can because @ is a quality score.
+		var quality strings.Builder
+		for quality.Len() < len(read.Sequence) {
+			line, ok, err := readLine()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &ParseError{Line: lineNumber, Err: fmt.Errorf("%w: read %s is missing quality scores", ErrTruncated, read.Identifier)}
+			}
+			quality.WriteString(line)
+		}
+		read.Quality = quality.String()
+		if len(read.Quality) != len(read.Sequence) {
+			return &ParseError{Line: lineNumber, Text: read.Identifier, Err: fmt.Errorf("%w: %d bases but %d scores", ErrQualityLength, len(read.Sequence), len(read.Quality))}
+		}
+		for _, character := range read.Quality {
+			if character < '!' || character > '~' {
+				return &ParseError{Line: lineNumber, Text: read.Identifier, Err: fmt.Errorf("%w: %q", ErrInvalidQuality, character)}
+			}
+		}
+
+		reads <- read
+	}
+}
+
+// illuminaFields are the names of the colon separated fields CASAVA 1.8 and up write after the identifier.
+var illuminaFields = []string{"read", "is_filtered", "control_number", "index"}
+
+// parseHeader splits a header line (without its @) into a Fastq with its identifier, description and optionals.
+func parseHeader(header string) Fastq {
+	read := Fastq{Optionals: map[string]string{}}
+	fields := strings.Fields(header)
+	if len(fields) == 0 {
+		return read
+	}
+	read.Identifier = fields[0]
+	read.Description = strings.TrimSpace(strings.TrimPrefix(header, fields[0]))
+
+	for _, field := range fields[1:] {
+		if equals := strings.Index(field, "="); equals > 0 {
+			read.Optionals[field[:equals]] = field[equals+1:]
+			continue
+		}
+		// Illumina descriptions look like 1:N:0:ATCACG.
+		if parts := strings.Split(field, ":"); len(parts) == len(illuminaFields) && (parts[1] == "Y" || parts[1] == "N") {
+			for partIndex, part := range parts {
+				read.Optionals[illuminaFields[partIndex]] = part
+			}
+		}
+	}
+	return read
+}
+
+/******************************************************************************
+
+Start of FASTQ Read functions
+
+******************************************************************************/
+
+// ReadGzConcurrent reads a gzipped FASTQ file into a Fastq channel, closing it when the file ends or at the first error,
+// which is returned. It blocks until the whole file has been read so run it in its own goroutine.
+func ReadGzConcurrent(path string, reads chan<- Fastq) error {
+	file, err := os.Open(path)
+	if err != nil {
+		close(reads)
+		return err
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		close(reads)
+		return err
+	}
+	defer r.Close()
+	return ParseConcurrent(r, reads)
+}
+
+// ReadConcurrent reads a flat FASTQ file into a Fastq channel, closing it when the file ends or at the first error,
+// which is returned. It blocks until the whole file has been read so run it in its own goroutine.
+func ReadConcurrent(path string, reads chan<- Fastq) error {
+	file, err := os.Open(path)
+	if err != nil {
+		close(reads)
+		return err
+	}
+	defer file.Close()
+	return ParseConcurrent(file, reads)
+}
+
+// ReadGz reads a gzipped FASTQ file into an array of Fastq structs.
+func ReadGz(path string) ([]Fastq, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// Read reads a FASTQ file into an array of Fastq structs.
+func Read(path string) ([]Fastq, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+/******************************************************************************
+
+Start of FASTQ Write functions
+
+******************************************************************************/
+
+// Build writes Fastq structs to a FASTQ string. If a read has no Description one is written from its Optionals.
+func Build(reads []Fastq) []byte {
+	var fastqString bytes.Buffer
+	for _, read := range reads {
+		fastqString.WriteString("@")
+		fastqString.WriteString(read.Identifier)
+		if description := buildDescription(read); description != "" {
+			fastqString.WriteString(" ")
+			fastqString.WriteString(description)
+		}
+		fastqString.WriteString("\n")
+		fastqString.WriteString(read.Sequence)
+		fastqString.WriteString("\n+\n")
+		fastqString.WriteString(read.Quality)
+		fastqString.WriteString("\n")
+	}
+	return fastqString.Bytes()
+}
+
+// buildDescription returns a read's Description or, failing that, its Optionals as sorted key=value pairs.
+func buildDescription(read Fastq) string {
+	if read.Description != "" || len(read.Optionals) == 0 {
+		return read.Description
+	}
+	keys := make([]string, 0, len(read.Optionals))
+	for key := range read.Optionals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for keyIndex, key := range keys {
+		pairs[keyIndex] = key + "=" + read.Optionals[key]
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Write writes Fastq structs to a file.
+func Write(reads []Fastq, path string) error {
+	return ioutil.WriteFile(path, Build(reads), 0644)
+}
+
+/******************************************************************************
+
+Start of FASTQ quality functions
+
+******************************************************************************/
+
+// DecodeQuality turns a quality string into Phred scores.
+func DecodeQuality(quality string, encoding Encoding) ([]int, error) {
+	scores := make([]int, len(quality))
+	for qualityIndex := 0; qualityIndex < len(quality); qualityIndex++ {
+		score := int(quality[qualityIndex]) - int(encoding)
+		if score < 0 || quality[qualityIndex] > '~' {
+			return nil, fmt.Errorf("%w: %q at position %d is not Phred+%d", ErrInvalidQuality, quality[qualityIndex], qualityIndex+1, encoding)
+		}
+		scores[qualityIndex] = score
+	}
+	return scores, nil
+}
+
+// EncodeQuality turns Phred scores into a quality string. Scores are clamped to what the encoding can print.
+func EncodeQuality(scores []int, encoding Encoding) string {
+	quality := make([]byte, len(scores))
+	for scoreIndex, score := range scores {
+		if score < 0 {
+			score = 0
+		}
+		if maximum := '~' - int(encoding); score > maximum {
+			score = maximum
+		}
+		quality[scoreIndex] = byte(score + int(encoding))
+	}
+	return string(quality)
+}
+
+// DetectEncoding guesses the encoding of some reads from the lowest quality character in them.
+// Phred+64 can't go below @ (64) and real Phred+33 data almost always does so anything below ; (59) is Phred+33.
+func DetectEncoding(reads []Fastq) Encoding {
+	lowest := byte('~')
+	for _, read := range reads {
+		for qualityIndex := 0; qualityIndex < len(read.Quality); qualityIndex++ {
+			if read.Quality[qualityIndex] < lowest {
+				lowest = read.Quality[qualityIndex]
+			}
+		}
+	}
+	if lowest < ';' {
+		return Phred33
+	}
+	return Phred64
+}
+
+// MeanQuality returns the mean quality of a read as a Phred score.
+// Phred scores are logarithmic so, like nanopore's own tools, the error probabilities are averaged rather than the scores.
+func MeanQuality(read Fastq, encoding Encoding) (float64, error) {
+	scores, err := DecodeQuality(read.Quality, encoding)
+	if err != nil {
+		return 0, err
+	}
+	if len(scores) == 0 {
+		return 0, nil
+	}
+	var errorSum float64
+	for _, score := range scores {
+		errorSum += math.Pow(10, float64(score)/-10)
+	}
+	return -10 * math.Log10(errorSum/float64(len(scores))), nil
+}
+
+// TrimQuality trims low quality bases from both ends of a read.
+// It uses the running sum algorithm from BWA and cutadapt: each end is cut at
+// the point that minimises the sum of (score - threshold) over the bases
+// removed, so a lone good base doesn't stop a bad tail from being trimmed.
+func TrimQuality(read Fastq, threshold int, encoding Encoding) (Fastq, error) {
+	scores, err := DecodeQuality(read.Quality, encoding)
+	if err != nil {
+		return read, err
+	}
+
+	end := len(scores)
+	var sum, minimum int
+	for scoreIndex := len(scores) - 1; scoreIndex >= 0; scoreIndex-- {
+		sum += scores[scoreIndex] - threshold
+		if sum > 0 {
+			break
+		}
+		if sum < minimum {
+			minimum = sum
+			end = scoreIndex
+		}
+	}
+
+	start := 0
+	sum, minimum = 0, 0
+	for scoreIndex := 0; scoreIndex < end; scoreIndex++ {
+		sum += scores[scoreIndex] - threshold
+		if sum > 0 {
+			break
+		}
+		if sum < minimum {
+			minimum = sum
+			start = scoreIndex + 1
+		}
+	}
+
+	trimmed := read
+	trimmed.Sequence = read.Sequence[start:end]
+	trimmed.Quality = read.Quality[start:end]
+	return trimmed, nil
+}
+
+/******************************************************************************
+
+FASTQ specific IO related things end here.
+
+******************************************************************************/
@@ -0,0 +1,311 @@
+package fastq
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// ExampleRead shows basic usage for Read.
+func ExampleRead() {
+	reads, _ := Read("data/nanopore.fastq")
+	fmt.Println(reads[0].Identifier)
+	fmt.Println(reads[0].Optionals["ch"])
+	// Output:
+	// 0d8a4f8e-1f2e-4c3b-9a8d-6e5f4a3b2c0d
+	// 105
+}
+
+// ExampleReadGz shows basic usage for ReadGz.
+func ExampleReadGz() {
+	reads, _ := ReadGz("data/illumina.fastq.gz")
+	fmt.Println(len(reads))
+	fmt.Println(reads[0].Optionals["index"])
+	// Output:
+	// 4
+	// ATCACG
+}
+
+// ExampleReadConcurrent shows how to use the concurrent parser for decompressed FASTQ files.
+func ExampleReadConcurrent() {
+	reads := make(chan Fastq, 100)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadConcurrent("data/nanopore.fastq", reads) }()
+	var bases int
+	for read := range reads {
+		bases += len(read.Sequence)
+	}
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(bases)
+	// Output: 180
+}
+
+// ExampleReadGzConcurrent shows how to use the concurrent parser for gzipped FASTQ files.
+func ExampleReadGzConcurrent() {
+	reads := make(chan Fastq, 100)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadGzConcurrent("data/illumina.fastq.gz", reads) }()
+	var identifier string
+	for read := range reads {
+		identifier = read.Identifier
+	}
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
+
+	fmt.Println(identifier)
+	// Output: M00123:45:000000000-A1B2C:1:1101:15982:1382
+}
+
+// ExampleBuild shows basic usage for Build.
+func ExampleBuild() {
+	reads, _ := Read("data/nanopore.fastq")
+	file, _ := ioutil.ReadFile("data/nanopore.fastq")
+	fmt.Println(string(Build(reads)) == string(file))
+	// Output: true
+}
+
+// ExampleWrite shows basic usage of the writer.
+func ExampleWrite() {
+	reads, _ := Read("data/nanopore.fastq")
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	path := tmpDataDir + "/test.fastq"
+	_ = Write(reads, path)
+	writtenReads, _ := Read(path)
+	fmt.Println(writtenReads[2].Sequence == reads[2].Sequence)
+	// Output: true
+}
+
+// ExampleMeanQuality shows how to filter reads by their mean quality.
+func ExampleMeanQuality() {
+	reads, _ := Read("data/nanopore.fastq")
+	for _, read := range reads {
+		quality, _ := MeanQuality(read, Phred33)
+		fmt.Printf("%s %.1f\n", read.Optionals["read"], quality)
+	}
+	// Output:
+	// 12 11.3
+	// 19 11.3
+	// 26 10.0
+}
+
+// ExampleTrimQuality shows how to trim the low quality tail off of a read.
+func ExampleTrimQuality() {
+	reads, _ := Read("data/nanopore.fastq")
+	trimmed, _ := TrimQuality(reads[0], 7, Phred33)
+	fmt.Println(len(reads[0].Sequence), len(trimmed.Sequence))
+	// Output: 60 52
+}
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected Fastq
+	}{
+		{
+			header:   "read1",
+			expected: Fastq{Identifier: "read1", Optionals: map[string]string{}},
+		},
+		{
+			header: "M00123:45:000000000-A1B2C:1:1101:15589:1331 2:Y:18:ATCACG+GTTTCG",
+			expected: Fastq{
+				Identifier:  "M00123:45:000000000-A1B2C:1:1101:15589:1331",
+				Description: "2:Y:18:ATCACG+GTTTCG",
+				Optionals:   map[string]string{"read": "2", "is_filtered": "Y", "control_number": "18", "index": "ATCACG+GTTTCG"},
+			},
+		},
+		{
+			header: "r2 runid=abc ch=7 barcode=barcode01 a free text note",
+			expected: Fastq{
+				Identifier:  "r2",
+				Description: "runid=abc ch=7 barcode=barcode01 a free text note",
+				Optionals:   map[string]string{"runid": "abc", "ch": "7", "barcode": "barcode01"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if diff := cmp.Diff(test.expected, parseHeader(test.header)); diff != "" {
+			t.Errorf("%q was not parsed (-want +got):\n%s", test.header, diff)
+		}
+	}
+}
+
+func TestParseMultiline(t *testing.T) {
+	// Sanger FASTQ can wrap sequence and quality, and quality lines can start with @ or +.
+	file := "@read1\nACGT\nACGT\n+read1\n@@II\n+III\n\n@read2 x=1\nAC\r\n+\r\nII\r\n"
+	reads, err := Parse(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Fastq{
+		{Identifier: "read1", Optionals: map[string]string{}, Sequence: "ACGTACGT", Quality: "@@II+III"},
+		{Identifier: "read2", Description: "x=1", Optionals: map[string]string{"x": "1"}, Sequence: "AC", Quality: "II"},
+	}
+	if diff := cmp.Diff(expected, reads); diff != "" {
+		t.Errorf("Wrapped reads were not parsed (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		line int
+		err  error
+	}{
+		{name: "missing header", file: "@r1\nA\n+\nI\nr2\nA\n+\nI\n", line: 5, err: ErrMissingHeader},
+		{name: "missing separator", file: "@r1\nACGT\n@r2\nA\n+\nI\n", line: 3, err: ErrMissingSeparator},
+		{name: "mismatched separator", file: "@r1\nACGT\n+r2\nIIII\n", line: 3, err: ErrMissingSeparator},
+		{name: "quality too long", file: "@r1\nACGT\n+\nIIIII\n", line: 4, err: ErrQualityLength},
+		{name: "invalid quality", file: "@r1\nACGT\n+\nII I\n", line: 4, err: ErrInvalidQuality},
+		{name: "truncated quality", file: "@r1\nACGT\n+\n", line: 3, err: ErrTruncated},
+		{name: "truncated separator", file: "@r1\nACGT\n", line: 1, err: ErrTruncated},
+	}
+
+	for _, test := range tests {
+		reads, err := Parse(strings.NewReader(test.file))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("%s: expected a *ParseError. Got: %v", test.name, err)
+		}
+		if parseError.Line != test.line || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q on line %d. Got: %s", test.name, test.err, test.line, err)
+		}
+		if test.name == "missing header" && len(reads) != 1 {
+			t.Errorf("Reads before the error should still be returned. Got %d", len(reads))
+		}
+	}
+}
+
+func TestReadMissingFile(t *testing.T) {
+	if _, err := Read("data/missing.fastq"); err == nil {
+		t.Errorf("Read should fail on a missing file")
+	}
+	reads := make(chan Fastq)
+	if err := ReadGzConcurrent("data/nanopore.fastq", reads); err == nil {
+		t.Errorf("ReadGzConcurrent should fail on a file that isn't gzipped")
+	}
+	if _, ok := <-reads; ok {
+		t.Errorf("ReadGzConcurrent should close the channel when it fails")
+	}
+}
+
+func TestReadConcurrentErrors(t *testing.T) {
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	gzipped, _ := ioutil.ReadFile("data/illumina.fastq.gz")
+	truncatedPath := tmpDataDir + "/truncated.fastq.gz"
+	_ = ioutil.WriteFile(truncatedPath, gzipped[:len(gzipped)-10], 0644)
+	reads := make(chan Fastq, 100)
+	if err := ReadGzConcurrent(truncatedPath, reads); err == nil {
+		t.Errorf("Expected an error reading a truncated gzip file")
+	}
+
+	malformedPath := tmpDataDir + "/malformed.fastq"
+	_ = ioutil.WriteFile(malformedPath, []byte("read1\nACGT\n+\nIIII\n"), 0644)
+	reads = make(chan Fastq, 100)
+	if err := ReadConcurrent(malformedPath, reads); !errors.Is(err, ErrMissingHeader) {
+		t.Errorf("Expected ErrMissingHeader from ReadConcurrent. Got: %v", err)
+	}
+}
+
+func TestBuildOptionals(t *testing.T) {
+	read := Fastq{Identifier: "r1", Optionals: map[string]string{"ch": "7", "barcode": "barcode01"}, Sequence: "ACGT", Quality: "IIII"}
+	expected := "@r1 barcode=barcode01 ch=7\nACGT\n+\nIIII\n"
+	if built := string(Build([]Fastq{read})); built != expected {
+		t.Errorf("Optionals were not written to the header. Got: %q", built)
+	}
+}
+
+func TestQualityEncodings(t *testing.T) {
+	scores := []int{0, 2, 20, 30, 40, 41}
+	phred33 := EncodeQuality(scores, Phred33)
+	phred64 := EncodeQuality(scores, Phred64)
+	if phred33 != "!#5?IJ" || phred64 != "@BT^hi" {
+		t.Errorf("Scores were not encoded. Got: %q and %q", phred33, phred64)
+	}
+
+	for _, test := range []struct {
+		quality  string
+		encoding Encoding
+	}{{phred33, Phred33}, {phred64, Phred64}} {
+		decoded, err := DecodeQuality(test.quality, test.encoding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(scores, decoded); diff != "" {
+			t.Errorf("Phred+%d did not round trip (-want +got):\n%s", test.encoding, diff)
+		}
+		if detected := DetectEncoding([]Fastq{{Quality: test.quality}}); detected != test.encoding {
+			t.Errorf("Expected Phred+%d to be detected. Got Phred+%d", test.encoding, detected)
+		}
+	}
+
+	// Phred+33 data can't be decoded as Phred+64.
+	if _, err := DecodeQuality(phred33, Phred64); !errors.Is(err, ErrInvalidQuality) {
+		t.Errorf("Expected ErrInvalidQuality decoding Phred+33 as Phred+64. Got: %v", err)
+	}
+	if clamped := EncodeQuality([]int{-5, 100}, Phred64); clamped != "@~" {
+		t.Errorf("Out of range scores were not clamped. Got: %q", clamped)
+	}
+}
+
+func TestMeanQuality(t *testing.T) {
+	// one base at Q10 and one at Q30 average out to an error rate of 0.0505, not Q20.
+	quality, err := MeanQuality(Fastq{Quality: "+?"}, Phred33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := -10 * math.Log10(0.0505); math.Abs(quality-expected) > 1e-9 {
+		t.Errorf("Expected a mean quality of %f. Got %f", expected, quality)
+	}
+	if quality, _ := MeanQuality(Fastq{}, Phred33); quality != 0 {
+		t.Errorf("Empty reads should have a mean quality of 0. Got %f", quality)
+	}
+}
+
+func TestTrimQuality(t *testing.T) {
+	tests := []struct {
+		quality  string
+		expected string
+	}{
+		// scores:  2 2 30 30 30 30 2 2
+		{quality: "##????##", expected: "????"},
+		// a base right at the threshold in a bad tail doesn't stop trimming.
+		{quality: "????#+##", expected: "????"},
+		// but a good one does.
+		{quality: "????#?##", expected: "????#?"},
+		{quality: "????????", expected: "????????"},
+		{quality: "########", expected: ""},
+	}
+
+	for _, test := range tests {
+		read := Fastq{Sequence: strings.Repeat("A", len(test.quality)), Quality: test.quality}
+		trimmed, err := TrimQuality(read, 10, Phred33)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trimmed.Quality != test.expected || len(trimmed.Sequence) != len(test.expected) {
+			t.Errorf("Expected %q to trim to %q. Got %q", test.quality, test.expected, trimmed.Quality)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package flatfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+)
+
+/******************************************************************************
+
+Flat file related things begin here.
+
+Most of the formats poly reads are plain text read a line at a time, and the
+parsers for them report problems the same way: as a ParseError saying which
+line of the file the problem is on. Each parser package names the type after
+itself (genbank.ParseError, gff.ParseError, ...) so callers never need to
+import this package, but they are all the same type.
+
+GenBank and EMBL files also share a layout. Any number of records follow one
+another and each one ends with a line starting with //. Reader splits a stream
+of those into records without reading more than one into memory at a time,
+which is what lets the genbank and embl readers get through multi-gigabyte
+release files.
+
+******************************************************************************/
+
+// ParseError is a problem with a specific line of a file.
+type ParseError struct {
+	Line int    // 1-based line number the problem was found on.
+	Text string // the offending text, usually the whole line.
+	Err  error  // one of the parser package's Err values, possibly wrapped with more detail.
+}
+
+func (parseError *ParseError) Error() string {
+	if parseError.Text == "" {
+		return fmt.Sprintf("line %d: %s", parseError.Line, parseError.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %q", parseError.Line, parseError.Err, parseError.Text)
+}
+
+// Unwrap returns the underlying error so errors.Is can see through a ParseError.
+func (parseError *ParseError) Unwrap() error {
+	return parseError.Err
+}
+
+// Reader splits a stream into records that end with a // line. Only the record being read is buffered.
+type Reader struct {
+	reader     *bufio.Reader
+	gzipReader *gzip.Reader
+	lineNumber int
+	skipUntil  string
+	err        error
+}
+
+// NewReader returns a Reader over r. Gzip compressed input is detected and decompressed on the fly.
+func NewReader(r io.Reader) (*Reader, error) {
+	bufferedReader := bufio.NewReader(r)
+	reader := &Reader{reader: bufferedReader}
+
+	// gzip streams always start with the magic bytes 1f 8b.
+	magic, _ := bufferedReader.Peek(2)
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(bufferedReader)
+		if err != nil {
+			return nil, err
+		}
+		reader.gzipReader = gzipReader
+		reader.reader = bufio.NewReader(gzipReader)
+	}
+	return reader, nil
+}
+
+// SkipUntil drops every line before the first one starting with prefix, like the release header at the top of
+// the flat files on the NCBI FTP server.
+func (reader *Reader) SkipUntil(prefix string) {
+	reader.skipUntil = prefix
+}
+
+// Next returns the lines of the next record, without their line endings, and the line number of its first line
+// counting from the start of the stream. It returns io.EOF once there are no records left.
+//
+// A final record missing its // line is still returned so the parser can report it as truncated. Any other error
+// comes from the underlying reader and is returned again by every later call.
+func (reader *Reader) Next() ([]string, int, error) {
+	if reader.err != nil {
+		return nil, 0, reader.err
+	}
+
+	var lines []string
+	firstLineNumber := 0
+	for {
+		line, err := reader.reader.ReadString('\n')
+		if line != "" {
+			reader.lineNumber++
+			line = strings.TrimSuffix(line, "\n")
+			if reader.skipUntil != "" && strings.HasPrefix(line, reader.skipUntil) {
+				reader.skipUntil = ""
+			}
+			// blank lines between records and anything being skipped aren't part of any record.
+			if len(lines) == 0 && (strings.TrimSpace(line) == "" || reader.skipUntil != "") {
+				continue
+			}
+			if len(lines) == 0 {
+				firstLineNumber = reader.lineNumber
+			}
+			lines = append(lines, line)
+			if strings.HasPrefix(line, "//") || strings.TrimSpace(line) == "//" {
+				break
+			}
+		}
+		if err != nil {
+			reader.err = err
+			if err != io.EOF || len(lines) == 0 {
+				return nil, 0, err
+			}
+			break
+		}
+	}
+	return lines, firstLineNumber, nil
+}
+
+// Close releases the gzip decompressor if the stream was compressed. It does not close the underlying reader.
+func (reader *Reader) Close() error {
+	if reader.gzipReader != nil {
+		return reader.gzipReader.Close()
+	}
+	return nil
+}
+
+// ReadAll calls next until it runs out of records and returns every one of them. Records that come back with a
+// *ParseError are kept so one bad record doesn't lose the rest. Any other error stops reading.
+func ReadAll(next func() (poly.Sequence, error)) []poly.Sequence {
+	var sequences []poly.Sequence
+	for {
+		sequence, err := next()
+		if err == io.EOF {
+			break
+		}
+		var parseError *ParseError
+		if err != nil && !errors.As(err, &parseError) {
+			break
+		}
+		sequences = append(sequences, sequence)
+	}
+	return sequences
+}
+
+/******************************************************************************
+
+Flat file related things end here.
+
+******************************************************************************/
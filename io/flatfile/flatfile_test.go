@@ -0,0 +1,95 @@
+package flatfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type record struct {
+	Lines           []string
+	FirstLineNumber int
+}
+
+func readRecords(t *testing.T, reader *Reader) []record {
+	var records []record
+	for {
+		lines, firstLineNumber, err := reader.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record{lines, firstLineNumber})
+	}
+}
+
+func TestReader(t *testing.T) {
+	file := "RELEASE HEADER\n\nLOCUS one\nORIGIN\n//\n\n\nLOCUS two\r\n//\nLOCUS three\nORIGIN"
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(file))
+	gzipWriter.Close()
+
+	for name, input := range map[string][]byte{"plain": []byte(file), "gzip": gzipped.Bytes()} {
+		reader, err := NewReader(bytes.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.SkipUntil("LOCUS")
+		expected := []record{
+			{[]string{"LOCUS one", "ORIGIN", "//"}, 3},
+			{[]string{"LOCUS two\r", "//"}, 8},
+			// the last record is cut off but still returned for the parser to complain about.
+			{[]string{"LOCUS three", "ORIGIN"}, 10},
+		}
+		if diff := cmp.Diff(expected, readRecords(t, reader)); diff != "" {
+			t.Errorf("%s: records were not split (-want +got):\n%s", name, diff)
+		}
+		if err := reader.Close(); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte(strings.Repeat("LOCUS\n//\n", 1000)))
+	gzipWriter.Close()
+
+	reader, err := NewReader(bytes.NewReader(gzipped.Bytes()[:gzipped.Len()/2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for err == nil {
+		_, _, err = reader.Next()
+	}
+	if err == io.EOF {
+		t.Fatal("Expected an error reading a truncated gzip stream")
+	}
+	if _, _, again := reader.Next(); again != err {
+		t.Errorf("Expected the same error again. Got: %v", again)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	errBad := errors.New("bad line")
+	var err error = &ParseError{Line: 3, Text: "oops", Err: errBad}
+	if err.Error() != `line 3: bad line: "oops"` {
+		t.Errorf("Unexpected message: %s", err)
+	}
+	if !errors.Is(err, errBad) {
+		t.Errorf("Expected errors.Is to see through a ParseError")
+	}
+	if message := (&ParseError{Line: 1, Err: errBad}).Error(); message != "line 1: bad line" {
+		t.Errorf("Unexpected message without text: %s", message)
+	}
+}
@@ -0,0 +1,201 @@
+package genbank
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/transform"
+)
+
+/******************************************************************************
+
+Contig and gap handling begins here.
+
+Big assemblies on NCBI are often stored as CON records. Instead of an ORIGIN
+they have a CONTIG line saying how to stitch the record together out of other
+records and gaps:
+
+	CONTIG      join(AAAA01000001.1:1..1000,gap(100),
+	            complement(AAAA01000002.1:1..2000))
+
+ExpandContig fills in the sequence of a CON record from component records you
+already have locally. Gaps are filled with Ns like NCBI does.
+
+gap(100) is a gap of 100 bases, gap(unk100) is a gap of unknown length that
+is written as 100 Ns and gap() is a gap of unknown length that we also write
+as 100 Ns since that's what INSDC uses for gaps of unknown length.
+
+Gaps that are annotated as assembly_gap or gap features are kept in
+Meta.Gaps as well so nobody has to dig through qualifiers to find them.
+
+******************************************************************************/
+
+// unknownGapLength is the number of Ns written for a gap of unknown length.
+const unknownGapLength = 100
+
+// contigPart is one piece of a CONTIG join, either a location in a component record or a gap.
+type contigPart struct {
+	location      poly.Location
+	gap           bool
+	gapLength     int
+	unknownLength bool
+}
+
+// ExpandContig fills in the sequence of a record with a CONTIG line from its component records. Components are matched
+// by their VERSION, like AAAA01000001.1, or by their ACCESSION if the CONTIG doesn't say which version it wants.
+// The returned sequence has Meta.Gaps for every gap in the CONTIG. An error is returned if a component is missing or
+// too short or if the expanded sequence doesn't match the length in the LOCUS line.
+func ExpandContig(sequence poly.Sequence, components []poly.Sequence) (poly.Sequence, error) {
+	if sequence.Meta.Contig == "" {
+		return sequence, fmt.Errorf("%s has no CONTIG to expand", sequence.Meta.Locus.Name)
+	}
+	parts, err := parseContig(sequence.Meta.Contig)
+	if err != nil {
+		return sequence, err
+	}
+
+	componentsByAccession := make(map[string]string)
+	for _, component := range components {
+		for _, accession := range []string{component.Meta.Accession, component.Meta.Version, component.Meta.Locus.Name} {
+			if _, ok := componentsByAccession[accession]; accession != "" && !ok {
+				componentsByAccession[accession] = component.Sequence
+			}
+		}
+	}
+
+	var sequenceBuilder strings.Builder
+	var gaps []poly.Gap
+	for _, part := range parts {
+		if part.gap {
+			start := sequenceBuilder.Len()
+			sequenceBuilder.WriteString(strings.Repeat("n", part.gapLength))
+			gaps = append(gaps, poly.Gap{Start: start, End: sequenceBuilder.Len(), UnknownLength: part.unknownLength})
+			continue
+		}
+
+		location := part.location
+		componentSequence, ok := componentsByAccession[location.Accession]
+		if !ok {
+			componentSequence, ok = componentsByAccession[strings.Split(location.Accession, ".")[0]]
+		}
+		if !ok {
+			return sequence, fmt.Errorf("component %s of %s was not supplied", location.Accession, sequence.Meta.Locus.Name)
+		}
+		if location.End > len(componentSequence) {
+			return sequence, fmt.Errorf("component %s is %d bases long but CONTIG needs it to be at least %d", location.Accession, len(componentSequence), location.End)
+		}
+		partSequence := componentSequence[location.Start:location.End]
+		if location.Complement {
+			partSequence = transform.ReverseComplement(partSequence)
+		}
+		sequenceBuilder.WriteString(partSequence)
+	}
+
+	if declaredLength, err := strconv.Atoi(sequence.Meta.Locus.SequenceLength); err == nil && declaredLength != sequenceBuilder.Len() {
+		return sequence, fmt.Errorf("CONTIG of %s expands to %d bases but LOCUS declares %d", sequence.Meta.Locus.Name, sequenceBuilder.Len(), declaredLength)
+	}
+
+	// gaps that were already annotated as features keep their annotation.
+	expanded := sequence
+	expanded.Sequence = sequenceBuilder.String()
+	expanded.Meta.Gaps = append([]poly.Gap{}, sequence.Meta.Gaps...)
+	for _, gap := range gaps {
+		annotated := false
+		for _, annotatedGap := range sequence.Meta.Gaps {
+			if annotatedGap.Start == gap.Start && annotatedGap.End == gap.End {
+				annotated = true
+				break
+			}
+		}
+		if !annotated {
+			expanded.Meta.Gaps = append(expanded.Meta.Gaps, gap)
+		}
+	}
+
+	// features point back at the sequence they belong to so they need to be added to the expanded copy.
+	expanded.Features = nil
+	for _, feature := range sequence.Features {
+		expanded.AddFeature(&feature)
+	}
+	return expanded, nil
+}
+
+// parseContig splits a CONTIG join into its parts.
+func parseContig(contig string) ([]contigPart, error) {
+	if !strings.HasPrefix(contig, "join(") || !strings.HasSuffix(contig, ")") {
+		return nil, fmt.Errorf("CONTIG %q is not a join", contig)
+	}
+
+	var parts []contigPart
+	for _, partString := range splitTopLevel(contig[len("join(") : len(contig)-1]) {
+		if strings.HasPrefix(partString, "gap(") && strings.HasSuffix(partString, ")") {
+			part := contigPart{gap: true, gapLength: unknownGapLength, unknownLength: true}
+			if gapLength := partString[len("gap(") : len(partString)-1]; gapLength != "" {
+				part.unknownLength = strings.HasPrefix(gapLength, "unk")
+				length, err := strconv.Atoi(strings.TrimPrefix(gapLength, "unk"))
+				if err != nil || length < 0 {
+					return nil, fmt.Errorf("invalid gap %q in CONTIG", partString)
+				}
+				part.gapLength = length
+			}
+			parts = append(parts, part)
+			continue
+		}
+
+		location, err := ParseLocation(partString)
+		if err != nil {
+			return nil, err
+		}
+		if location.Accession == "" || len(location.SubLocations) > 0 {
+			return nil, fmt.Errorf("CONTIG part %q is not a range of another record", partString)
+		}
+		parts = append(parts, contigPart{location: location})
+	}
+	return parts, nil
+}
+
+// splitTopLevel splits a comma separated list without splitting inside parentheses.
+func splitTopLevel(list string) []string {
+	var items []string
+	depth, itemStart := 0, 0
+	for index, character := range list {
+		switch character {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, list[itemStart:index])
+				itemStart = index + 1
+			}
+		}
+	}
+	return append(items, list[itemStart:])
+}
+
+// getGaps collects the assembly_gap and gap features of a record as typed gaps.
+func getGaps(features []poly.Feature) []poly.Gap {
+	var gaps []poly.Gap
+	for _, feature := range features {
+		if feature.Type != "assembly_gap" && feature.Type != "gap" {
+			continue
+		}
+		gaps = append(gaps, poly.Gap{
+			Start:           feature.SequenceLocation.Start,
+			End:             feature.SequenceLocation.End,
+			UnknownLength:   feature.Attributes.Get("estimated_length") == "unknown",
+			Type:            feature.Attributes.Get("gap_type"),
+			LinkageEvidence: feature.Attributes["linkage_evidence"],
+		})
+	}
+	return gaps
+}
+
+/******************************************************************************
+
+Contig and gap handling ends here.
+
+******************************************************************************/
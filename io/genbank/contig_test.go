@@ -0,0 +1,167 @@
+package genbank
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/google/go-cmp/cmp"
+)
+
+// contigGbk is a CON record built out of the two records in componentsGbk.
+const contigGbk = `LOCUS       SCAF01                    35 bp    DNA     linear   CON 01-JAN-2021
+DEFINITION  A scaffold assembled from two contigs.
+ACCESSION   SCAF01
+VERSION     SCAF01.1
+KEYWORDS    WGS.
+FEATURES             Location/Qualifiers
+     source          1..35
+                     /organism="synthetic construct"
+     assembly_gap    11..15
+                     /estimated_length=5
+                     /gap_type="within scaffold"
+                     /linkage_evidence="paired-ends"
+CONTIG      join(CTG01.1:1..10,gap(5),
+            complement(CTG02.1:3..12),gap(unk10))
+//
+`
+
+const componentsGbk = `LOCUS       CTG01                     20 bp    DNA     linear   SYN 01-JAN-2021
+ACCESSION   CTG01
+VERSION     CTG01.1
+ORIGIN
+        1 atgaaaccca tgaaacccaa
+//
+LOCUS       CTG02                     12 bp    DNA     linear   SYN 01-JAN-2021
+ACCESSION   CTG02
+VERSION     CTG02.1
+ORIGIN
+        1 ttggggcccc aa
+//
+`
+
+func ExampleExpandContig() {
+	sequence, _ := Parse([]byte(contigGbk))
+	components := ParseMulti([]byte(componentsGbk))
+
+	expanded, _ := ExpandContig(sequence, components)
+	fmt.Println(expanded.Sequence)
+	for _, gap := range expanded.Meta.Gaps {
+		fmt.Println(gap.Start, gap.End, gap.UnknownLength, gap.Type)
+	}
+	// Output:
+	// atgaaacccannnnnttggggccccnnnnnnnnnn
+	// 10 15 false within scaffold
+	// 25 35 true
+}
+
+func TestParseContig(t *testing.T) {
+	sequence, err := ParseStrict([]byte(contigGbk))
+	if err != nil {
+		t.Fatalf("CON record should parse strictly before it is expanded. Got: %s", err)
+	}
+	if sequence.Meta.Contig != "join(CTG01.1:1..10,gap(5),complement(CTG02.1:3..12),gap(unk10))" {
+		t.Errorf("CONTIG was not parsed. Got: %q", sequence.Meta.Contig)
+	}
+	if _, ok := sequence.Meta.Other["CONTIG"]; ok {
+		t.Errorf("CONTIG should not be kept in Meta.Other.")
+	}
+
+	expectedGaps := []poly.Gap{{Start: 10, End: 15, Type: "within scaffold", LinkageEvidence: []string{"paired-ends"}}}
+	if diff := cmp.Diff(expectedGaps, sequence.Meta.Gaps); diff != "" {
+		t.Errorf("assembly_gap feature was not turned into a gap (-want +got):\n%s", diff)
+	}
+
+	// CON records have no ORIGIN and Build shouldn't add one.
+	if built := string(Build(sequence)); built != contigGbk {
+		t.Errorf("CON record does not round trip. Got:\n%s", built)
+	}
+
+	// long CONTIGs written fresh wrap after commas.
+	sequence.Meta.Contig = "join(" + strings.Repeat("CTG01.1:1..10,", 10) + "gap(5))"
+	reparsed, _ := Parse(BuildNormalized(sequence))
+	if reparsed.Meta.Contig != sequence.Meta.Contig {
+		t.Errorf("Edited CONTIG does not round trip. Got: %q", reparsed.Meta.Contig)
+	}
+}
+
+func TestExpandContigErrors(t *testing.T) {
+	sequence, _ := Parse([]byte(contigGbk))
+	components := ParseMulti([]byte(componentsGbk))
+
+	if _, err := ExpandContig(sequence, components[:1]); err == nil || !strings.Contains(err.Error(), "CTG02.1") {
+		t.Errorf("Missing component should be reported. Got: %v", err)
+	}
+
+	shortComponents := ParseMulti([]byte(componentsGbk))
+	shortComponents[1].Sequence = "ttgg"
+	if _, err := ExpandContig(sequence, shortComponents); err == nil {
+		t.Errorf("Component shorter than the CONTIG needs should be an error.")
+	}
+
+	sequence.Meta.Locus.SequenceLength = "36"
+	if _, err := ExpandContig(sequence, components); err == nil {
+		t.Errorf("CONTIG that doesn't match the LOCUS length should be an error.")
+	}
+
+	sequence.Meta.Contig = "join(CTG01.1:1..10,gap(five))"
+	if _, err := ExpandContig(sequence, components); err == nil {
+		t.Errorf("Invalid gap should be an error.")
+	}
+}
+
+func TestHashContig(t *testing.T) {
+	sequence, _ := Parse([]byte(contigGbk))
+
+	// there's nothing to hash until the record is expanded.
+	if err := sequence.Hash(); err == nil {
+		t.Errorf("Hashing a CON record that hasn't been expanded should be an error.")
+	}
+	sequence.Features[0].SequenceHash = "v1_DLD_0000000000000000000000000000000000000000000000000000000000000000"
+	issues := sequence.VerifyHashes()
+	if len(issues) != 1 || issues[0].Severity != poly.SeverityWarning || issues[0].FeatureIndex != 0 {
+		t.Errorf("Stored hashes of a CON record should only be warned about. Got: %v", issues)
+	}
+
+	expanded, err := ExpandContig(sequence, ParseMulti([]byte(componentsGbk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := expanded.Hash(); err != nil {
+		t.Fatal(err)
+	}
+	if issues := expanded.VerifyHashes(); len(issues) != 0 {
+		t.Errorf("Expanded CON record should verify. Got: %v", issues)
+	}
+
+	// a feature that doesn't fit is an error rather than a panic.
+	expanded.Features[0].SequenceLocation.End = 40
+	if err := expanded.Hash(); err == nil {
+		t.Errorf("Hashing a feature past the end of the sequence should be an error.")
+	}
+	if issues := expanded.VerifyHashes(); len(issues) != 1 || issues[0].Severity != poly.SeverityError {
+		t.Errorf("Verifying a feature past the end of the sequence should be an error. Got: %v", issues)
+	}
+}
+
+func TestParseWGS(t *testing.T) {
+	master := `LOCUS       AAAA00000000             500 rc    DNA     linear   CON 01-JAN-2021
+DEFINITION  A whole genome shotgun master record.
+FEATURES             Location/Qualifiers
+     source          1..500
+WGS         AAAA01000001-AAAA01000500
+WGS_SCAFLD  AAAA01S000001-AAAA01S000010
+//
+`
+	sequence, err := Parse([]byte(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sequence.Meta.WGS != "AAAA01000001-AAAA01000500" || sequence.Meta.WGSScaffold != "AAAA01S000001-AAAA01S000010" {
+		t.Errorf("WGS lines were not parsed. Got: %q and %q", sequence.Meta.WGS, sequence.Meta.WGSScaffold)
+	}
+	if built := string(Build(sequence)); built != master {
+		t.Errorf("WGS master record does not round trip. Got:\n%s", built)
+	}
+}
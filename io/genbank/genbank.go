@@ -2,14 +2,20 @@ package genbank
 
 import (
 	"bytes"
-	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/flatfile"
 	"github.com/mitchellh/go-wordwrap"
 )
 
@@ -19,8 +25,32 @@ GBK specific IO related things begin here.
 
 ******************************************************************************/
 
+// Problems a genbank file can have, reported wrapped in a ParseError so check for them with errors.Is.
+var (
+	ErrMalformedLocus  = errors.New("malformed LOCUS line")
+	ErrInvalidLocation = errors.New("invalid feature location")
+	ErrInvalidSequence = errors.New("invalid characters in ORIGIN")
+	ErrTruncated       = errors.New("truncated file")
+)
+
+// ParseError is a problem with a specific line of a genbank file. Its Err is one of the errors above and
+// its Line counts from the start of the stream when it comes from a Reader.
+type ParseError = flatfile.ParseError
+
 // Parse takes in a string representing a gbk/gb/genbank file and parses it into an Sequence object.
-func Parse(file []byte) poly.Sequence {
+// The first problem found in the file is returned as a *ParseError. Use ParseLenient to get a
+// sequence out of a file with problems.
+func Parse(file []byte) (poly.Sequence, error) {
+	sequence, parseErrors := ParseLenient(file)
+	if len(parseErrors) > 0 {
+		return poly.Sequence{}, parseErrors[0]
+	}
+	return sequence, nil
+}
+
+// ParseLenient parses a gbk/gb/genbank file like Parse but carries on past problems, returning them
+// as warnings in the order they appear in the file along with whatever could be parsed.
+func ParseLenient(file []byte) (poly.Sequence, []*ParseError) {
 
 	gbk := string(file)
 	lines := strings.Split(gbk, "\n")
@@ -35,25 +65,35 @@ func Parse(file []byte) poly.Sequence {
 	// Create sequence struct
 	sequence := poly.Sequence{}
 
+	var parseErrors []*ParseError
+	var locusFound, originFound bool
+	endLineNumber := 0 // line number of the closing //.
+
+lineLoop:
 	for numLine := 0; numLine < len(lines); numLine++ {
 		line := lines[numLine]
 		splitLine := strings.Split(line, " ")
 		subLines := lines[numLine+1:]
+		// genbank line numbers start at 1 and subLines start on the line after this one.
+		lineNumber := numLine + 1
 
-		// This is to keep the cursor from scrolling to the bottom another time after GetSequence() is called.
-		// Break has to be in scope and can't be called within switch statement.
-		// Otherwise it will just break the switch which is redundant.
-		sequenceBreakFlag := false
-		if sequenceBreakFlag {
-			break
+		head := strings.TrimSpace(splitLine[0])
+		if head != "" && !locusFound && head != "LOCUS" {
+			parseErrors = append(parseErrors, &ParseError{Line: lineNumber, Text: line, Err: fmt.Errorf("%w: file does not start with LOCUS", ErrMalformedLocus)})
+			locusFound = true
 		}
 
-		switch strings.TrimSpace(splitLine[0]) {
+		switch head {
 
 		case "":
 			continue
 		case "LOCUS":
-			meta.Locus = parseLocus(line)
+			locus, err := parseLocus(line)
+			if err != nil {
+				parseErrors = append(parseErrors, &ParseError{Line: lineNumber, Text: line, Err: err})
+			}
+			meta.Locus = locus
+			locusFound = true
 		case "DEFINITION":
 			meta.Definition = joinSubLines(splitLine, subLines)
 		case "ACCESSION":
@@ -67,152 +107,512 @@ func Parse(file []byte) poly.Sequence {
 		case "REFERENCE":
 			meta.References = append(meta.References, getReference(splitLine, subLines))
 			continue
+		case "CONTIG":
+			// contigs are locations so the spaces left where lines were joined mean nothing.
+			meta.Contig = strings.Join(strings.Fields(joinSubLines(splitLine, subLines)), "")
+		case "WGS":
+			meta.WGS = joinSubLines(splitLine, subLines)
+		case "WGS_SCAFLD":
+			meta.WGSScaffold = joinSubLines(splitLine, subLines)
 		case "FEATURES":
-			features = getFeatures(subLines)
+			var featureErrors []*ParseError
+			features, featureErrors = getFeatures(subLines, lineNumber+1)
+			parseErrors = append(parseErrors, featureErrors...)
 		case "ORIGIN":
-			sequence.Sequence = getSequence(subLines)
-			sequenceBreakFlag = true
+			var sequenceErrors []*ParseError
+			sequence.Sequence, endLineNumber, sequenceErrors = getSequence(subLines, lineNumber+1)
+			parseErrors = append(parseErrors, sequenceErrors...)
+			originFound = true
+			break lineLoop
+		case "//":
+			endLineNumber = lineNumber
+			break lineLoop
 		default:
 			if quickMetaCheck(line) {
-				key := strings.TrimSpace(splitLine[0])
-				meta.Other[key] = joinSubLines(splitLine, subLines)
+				key := sectionKey(line)
+				meta.Other[key] = joinSubLines([]string{key, strings.TrimPrefix(line, key)}, subLines)
 			}
 		}
 
 	}
+	meta.GbkSections = getSections(lines)
+
+	// the last line number of the file for reporting anything missing from the end.
+	lastLineNumber := len(lines)
+	if lastLineNumber > 1 && lines[lastLineNumber-1] == "" {
+		lastLineNumber--
+	}
+	if !locusFound {
+		parseErrors = append(parseErrors, &ParseError{Line: 1, Err: fmt.Errorf("%w: file has no LOCUS line", ErrMalformedLocus)})
+	}
+	if endLineNumber == 0 {
+		parseErrors = append(parseErrors, &ParseError{Line: lastLineNumber, Text: lines[lastLineNumber-1], Err: fmt.Errorf("%w: missing //", ErrTruncated)})
+	}
+	if declaredLength, err := strconv.Atoi(meta.Locus.SequenceLength); originFound && err == nil && declaredLength != len(sequence.Sequence) {
+		parseErrors = append(parseErrors, &ParseError{Line: lastLineNumber, Text: lines[lastLineNumber-1], Err: fmt.Errorf("%w: LOCUS declares %d %s but ORIGIN has %d", ErrTruncated, declaredLength, meta.Locus.SequenceCoding, len(sequence.Sequence))})
+	}
+	sort.SliceStable(parseErrors, func(i, j int) bool { return parseErrors[i].Line < parseErrors[j].Line })
 
 	// add meta to annotated sequence
 	sequence.Meta = meta
 
+	// poly models features spanning the origin of circular sequences as a single range instead of a join.
+	if meta.Locus.Circular {
+		for featureIndex := range features {
+			features[featureIndex].SequenceLocation = features[featureIndex].SequenceLocation.MergeAtOrigin(len(sequence.Sequence))
+		}
+	}
+
 	// add features to annotated sequence with pointer to annotated sequence in each feature
 	for _, feature := range features {
 		sequence.AddFeature(&feature)
 	}
+	sequence.Meta.Gaps = getGaps(features)
 
-	return sequence
+	return sequence, parseErrors
 }
 
 // Build builds a GBK string to be written out to db or file.
+//
+// Sequences read from genbank files remember how they were laid out in Meta.GbkSections and each
+// Feature.GbkFeatureString. Build writes any section or feature that hasn't changed since it was read
+// exactly as it was, so COMMENT blocks, DBLINKs, qualifier order and line wrapping all survive a
+// Parse and Build round trip. Anything that has changed or wasn't read from genbank is written out fresh.
 func Build(sequence poly.Sequence) []byte {
+	return build(sequence, true)
+}
+
+// BuildNormalized builds a GBK string like Build but ignores how the sequence was laid out when it was
+// read, writing every section and feature in poly's own layout.
+func BuildNormalized(sequence poly.Sequence) []byte {
+	return build(sequence, false)
+}
+
+func build(sequence poly.Sequence, keepLayout bool) []byte {
 	var gbkString bytes.Buffer
-	locus := sequence.Meta.Locus
-	var shape string
+	meta := sequence.Meta
 
-	if locus.Circular {
-		shape = "circular"
-	} else if locus.Linear {
-		shape = "linear"
+	// sequences from other formats like gff don't always fill in the locus.
+	if meta.Locus.Name == "" {
+		meta.Locus.Name = meta.Name
+	}
+	if meta.Locus.SequenceLength == "" {
+		meta.Locus.SequenceLength = strconv.Itoa(len(sequence.Sequence))
 	}
 
-	fivespace := generateWhiteSpace(subMetaIndex)
+	referenceIndex := 0
+	for _, section := range buildSections(meta, sequence.Sequence != "", keepLayout) {
+		lines := strings.Split(section.raw, "\n")
+		var unchanged bool
+		var sectionString string
 
-	// building locus
-	locusData := locus.Name + fivespace + locus.SequenceLength + " bp" + fivespace + locus.MoleculeType + fivespace + shape + fivespace + locus.GenbankDivision + fivespace + locus.ModificationDate
-	locusString := "LOCUS       " + locusData + "\n"
-	gbkString.WriteString(locusString)
+		switch section.key {
+		case "LOCUS":
+			locus, _ := parseLocus(lines[0])
+			unchanged = locus == meta.Locus
+			sectionString = buildLocusString(meta.Locus)
+		case "SOURCE":
+			source, organism := getSourceOrganism(strings.Split(lines[0], " "), lines[1:])
+			unchanged = source == meta.Source && organism == meta.Organism
+			sectionString = buildMetaString("SOURCE", meta.Source) + buildMetaString("  ORGANISM", meta.Organism)
+		case "REFERENCE":
+			reference := meta.References[referenceIndex]
+			referenceIndex++
+			unchanged = getReference(strings.Split(lines[0], " "), lines[1:]) == reference
+			sectionString = buildReferenceString(referenceIndex, reference)
+		case "FEATURES":
+			unchanged = true
+			sectionString = "FEATURES             Location/Qualifiers\n"
+		case "ORIGIN":
+			unchanged = true
+			sectionString = "ORIGIN\n"
+		case "BASE COUNT":
+			// the counts are worked out again so they still add up after the sequence has been edited.
+			value := metaValue(meta, section.key)
+			sectionString = buildMetaString(section.key, value)
+			if sequence.Sequence != "" {
+				value = baseCount(sequence.Sequence)
+				sectionString = "BASE COUNT" + value + "\n"
+			}
+			baseCountString := joinSubLines([]string{section.key, strings.TrimPrefix(lines[0], section.key)}, lines[1:])
+			unchanged = strings.Join(strings.Fields(baseCountString), " ") == strings.Join(strings.Fields(value), " ")
+		case "CONTIG":
+			contig := joinSubLines([]string{section.key, strings.TrimPrefix(lines[0], section.key)}, lines[1:])
+			unchanged = strings.Join(strings.Fields(contig), "") == meta.Contig
+			sectionString = "CONTIG      " + wrapLocation(meta.Contig, 12) + "\n"
+		default:
+			value := metaValue(meta, section.key)
+			unchanged = joinSubLines([]string{section.key, strings.TrimPrefix(lines[0], section.key)}, lines[1:]) == value
+			sectionString = buildMetaString(section.key, value)
+		}
 
-	// building other standard meta features
-	definitionString := buildMetaString("DEFINITION", sequence.Meta.Definition)
-	gbkString.WriteString(definitionString)
+		if section.raw != "" && unchanged {
+			gbkString.WriteString(section.raw + "\n")
+		} else {
+			gbkString.WriteString(sectionString)
+		}
 
-	accessionString := buildMetaString("ACCESSION", sequence.Meta.Accession)
-	gbkString.WriteString(accessionString)
+		switch section.key {
+		case "FEATURES":
+			for _, feature := range groupFeatures(sequence) {
+				if keepLayout && featureUnchanged(feature) {
+					gbkString.WriteString(feature.GbkFeatureString + "\n")
+					continue
+				}
+				// gff and gtf say where the first codon of a CDS starts with its phase instead of /codon_start.
+				if feature.Type == "CDS" && (feature.Phase == "1" || feature.Phase == "2") && len(feature.SequenceLocation.SubLocations) == 0 && feature.Attributes.Get("codon_start") == "" {
+					phase, _ := strconv.Atoi(feature.Phase)
+					feature.Attributes = copyAttributes(feature.Attributes)
+					feature.Attributes["codon_start"] = []string{strconv.Itoa(phase + 1)}
+				}
+				// genbank writes features spanning the origin as join(x..end,1..y).
+				if feature.SequenceLocation.SpansOrigin() {
+					feature.SequenceLocation = feature.SequenceLocation.SplitAtOrigin(len(sequence.Sequence))
+				}
+				gbkString.WriteString(BuildFeatureString(feature))
+			}
+		case "ORIGIN":
+			gbkString.WriteString(buildSequenceString(sequence.Sequence))
+		}
+	}
 
-	versionString := buildMetaString("VERSION", sequence.Meta.Version)
-	gbkString.WriteString(versionString)
+	// finish genbank file with "//" on newline (again a genbank convention)
+	gbkString.WriteString("//\n")
 
-	keywordsString := buildMetaString("KEYWORDS", sequence.Meta.Keywords)
-	gbkString.WriteString(keywordsString)
+	return gbkString.Bytes()
+}
 
-	sourceString := buildMetaString("SOURCE", sequence.Meta.Source)
-	gbkString.WriteString(sourceString)
+// sectionOrder is the order genbank sections are written in. Sections that aren't listed go where "" is.
+var sectionOrder = []string{"LOCUS", "DEFINITION", "ACCESSION", "VERSION", "DBLINK", "KEYWORDS", "SEGMENT", "SOURCE", "REFERENCE", "COMMENT", "PRIMARY", "", "FEATURES", "BASE COUNT", "CONTIG", "WGS", "WGS_SCAFLD", "ORIGIN"}
 
-	organismString := buildMetaString("  ORGANISM", sequence.Meta.Organism)
-	gbkString.WriteString(organismString)
+func sectionRank(key string) int {
+	for rank, sectionKey := range sectionOrder {
+		if sectionKey == key {
+			return rank
+		}
+	}
+	return sectionRank("")
+}
 
-	// building references
-	// TODO: could use reflection to get keys and make more general.
-	for referenceIndex, reference := range sequence.Meta.References {
-		referenceData := strconv.Itoa(referenceIndex+1) + "  " + reference.Range
-		referenceString := buildMetaString("REFERENCE", referenceData)
-		gbkString.WriteString(referenceString)
+// section is a top level genbank section waiting to be written. raw is how it was read or empty for sections written fresh.
+type section struct {
+	key string
+	raw string
+}
 
-		if reference.Authors != "" {
-			authorsString := buildMetaString("  AUTHORS", reference.Authors)
-			gbkString.WriteString(authorsString)
+// buildSections lists every section meta needs written in order. Sections from meta.GbkSections keep their place and
+// anything new is slotted in where sectionOrder says it goes.
+func buildSections(meta poly.Meta, hasSequence, keepLayout bool) []section {
+	// sections that were read without a value are only kept when writing the original layout.
+	readKeys := make(map[string]bool)
+	if keepLayout {
+		for _, raw := range meta.GbkSections {
+			readKeys[sectionKey(raw)] = true
 		}
+	}
 
-		if reference.Title != "" {
-			titleString := buildMetaString("  TITLE", reference.Title)
-			gbkString.WriteString(titleString)
+	counts := map[string]int{"LOCUS": 1, "REFERENCE": len(meta.References), "FEATURES": 1}
+	// records assembled from other records have no sequence of their own to write.
+	if hasSequence || (meta.Contig == "" && meta.WGS == "" && meta.WGSScaffold == "") {
+		counts["ORIGIN"] = 1
+	}
+	for _, key := range []string{"DEFINITION", "ACCESSION", "VERSION", "KEYWORDS", "CONTIG", "WGS", "WGS_SCAFLD"} {
+		if metaValue(meta, key) != "" || readKeys[key] {
+			counts[key] = 1
 		}
+	}
+	if meta.Source != "" || meta.Organism != "" || readKeys["SOURCE"] {
+		counts["SOURCE"] = 1
+	}
+	for key := range meta.Other {
+		counts[key] = 1
+	}
 
-		if reference.Journal != "" {
-			journalString := buildMetaString("  JOURNAL", reference.Journal)
-			gbkString.WriteString(journalString)
+	var sections []section
+	if keepLayout {
+		for _, raw := range meta.GbkSections {
+			key := sectionKey(raw)
+			if counts[key] > 0 {
+				sections = append(sections, section{key, raw})
+				counts[key]--
+			}
 		}
+	}
 
-		if reference.PubMed != "" {
-			pubMedString := buildMetaString("  PUBMED", reference.PubMed)
-			gbkString.WriteString(pubMedString)
+	var missingKeys []string
+	for key, count := range counts {
+		if count > 0 {
+			missingKeys = append(missingKeys, key)
 		}
+	}
+	sort.Slice(missingKeys, func(i, j int) bool {
+		if sectionRank(missingKeys[i]) != sectionRank(missingKeys[j]) {
+			return sectionRank(missingKeys[i]) < sectionRank(missingKeys[j])
+		}
+		return missingKeys[i] < missingKeys[j]
+	})
+	for _, key := range missingKeys {
+		for count := 0; count < counts[key]; count++ {
+			position := len(sections)
+			for sectionIndex, section := range sections {
+				if sectionRank(section.key) > sectionRank(key) {
+					position = sectionIndex
+					break
+				}
+			}
+			sections = append(sections[:position], append([]section{{key: key}}, sections[position:]...)...)
+		}
+	}
+	return sections
+}
 
+// metaValue returns the value of a section that is stored as a single string in meta.
+func metaValue(meta poly.Meta, key string) string {
+	switch key {
+	case "DEFINITION":
+		return meta.Definition
+	case "ACCESSION":
+		return meta.Accession
+	case "VERSION":
+		return meta.Version
+	case "KEYWORDS":
+		return meta.Keywords
+	case "CONTIG":
+		return meta.Contig
+	case "WGS":
+		return meta.WGS
+	case "WGS_SCAFLD":
+		return meta.WGSScaffold
 	}
+	return meta.Other[key]
+}
 
-	// building other meta fields that are catch all
-	otherKeys := make([]string, 0, len(sequence.Meta.Other))
-	for key := range sequence.Meta.Other {
-		otherKeys = append(otherKeys, key)
+// baseCount counts the bases of sequence the way genbank's BASE COUNT line lists them.
+func baseCount(sequence string) string {
+	counts := make(map[rune]int)
+	for _, base := range strings.ToLower(sequence) {
+		counts[base]++
+	}
+	count := fmt.Sprintf("%9d a%7d c%7d g%7d t", counts['a'], counts['c'], counts['g'], counts['t'])
+	if others := len(sequence) - counts['a'] - counts['c'] - counts['g'] - counts['t']; others > 0 {
+		count += fmt.Sprintf("%7d others", others)
 	}
+	return count
+}
 
-	for _, otherKey := range otherKeys {
-		otherString := buildMetaString(otherKey, sequence.Meta.Other[otherKey])
-		gbkString.WriteString(otherString)
+// featureUnchanged reports whether feature still says the same thing as the GbkFeatureString it was read from.
+func featureUnchanged(feature poly.Feature) bool {
+	if feature.GbkFeatureString == "" {
+		return false
 	}
+	features, _ := getFeatures(strings.Split(feature.GbkFeatureString, "\n"), 1)
+	if len(features) != 1 {
+		return false
+	}
+	original := features[0]
+	return original.Type == feature.Type && original.GbkLocationString == feature.GbkLocationString && reflect.DeepEqual(original.Attributes, feature.Attributes)
+}
 
-	// start writing features section.
-	gbkString.WriteString("FEATURES             Location/Qualifiers\n")
+// groupFeatures lays out gff3 style gene models the way genbank expects them using the sequence's feature hierarchy.
+// Lines that share an ID are merged into a single joined feature, transcripts take the joined location of their exons,
+// children inherit /gene and /locus_tag from their ancestors and every feature is followed by its descendants.
+// Only sequences read from gff or gtf files are regrouped. Anything else, including genbank records that happen to
+// have /ID or /Parent qualifiers of their own, is returned as is.
+func groupFeatures(sequence poly.Sequence) []poly.Feature {
+	if !fromFeatureTable(sequence) {
+		return sequence.Features
+	}
+	inHierarchy := false
 	for _, feature := range sequence.Features {
-		gbkString.WriteString(BuildFeatureString(feature))
+		if feature.Attributes.Get("ID") != "" || len(feature.Attributes["Parent"]) > 0 {
+			inHierarchy = true
+			break
+		}
+	}
+	if !inHierarchy {
+		return sequence.Features
 	}
 
-	// start writing sequence section.
-	gbkString.WriteString("ORIGIN\n")
+	hierarchy := sequence.Hierarchy()
+	features := make([]poly.Feature, len(sequence.Features))
+	merged := make([]bool, len(sequence.Features)) // true for every line after the first of a multi line feature.
 
-	// iterate over every character in sequence range.
-	for index, base := range sequence.Sequence {
-		// if 60th character add newline then whitespace and index number and space before adding next base.
-		if index%60 == 0 {
-			if index != 0 {
-				gbkString.WriteString("\n")
+	for featureIndex, feature := range sequence.Features {
+		feature.Attributes = copyAttributes(feature.Attributes)
+		parts := hierarchy.Lookup(feature.Attributes.Get("ID"))
+		if len(parts) > 1 {
+			if parts[0] != featureIndex {
+				merged[featureIndex] = true
 			}
-			lineNumberString := strconv.Itoa(index + 1)          // genbank indexes at 1 for some reason
-			leadingWhiteSpaceLength := 9 - len(lineNumberString) // <- I wish I was kidding
-			for i := 0; i < leadingWhiteSpaceLength; i++ {
-				gbkString.WriteString(" ")
+			feature.SequenceLocation = joinLocations(sequence.Features, parts)
+			feature.GbkLocationString = ""
+		}
+		if feature.Strand == "-" && !feature.SequenceLocation.Complement {
+			feature.SequenceLocation.Complement = true
+			feature.GbkLocationString = ""
+		}
+		features[featureIndex] = feature
+	}
+
+	for featureIndex := range features {
+		feature := &features[featureIndex]
+
+		// transcripts in gff3 span their whole gene while genbank expects the exons they're spliced from.
+		var exons []int
+		for _, childIndex := range hierarchy.Children(featureIndex) {
+			if features[childIndex].Type == "exon" {
+				exons = append(exons, childIndex)
+			}
+		}
+		if len(exons) > 0 && !isTranscript(feature.Type) {
+			exons = nil
+		}
+		if len(exons) > 0 {
+			feature.SequenceLocation = joinLocations(sequence.Features, exons)
+			feature.SequenceLocation.Complement = feature.Strand == "-"
+			feature.GbkLocationString = ""
+		}
+
+		// genbank ties gene models together with shared /gene and /locus_tag qualifiers instead of IDs.
+		if feature.Type == "gene" && feature.Attributes.Get("gene") == "" && feature.Attributes.Get("Name") != "" {
+			feature.Attributes["gene"] = []string{feature.Attributes.Get("Name")}
+		}
+		for _, qualifier := range []string{"gene", "locus_tag"} {
+			if feature.Attributes.Get(qualifier) != "" {
+				continue
+			}
+			for _, ancestorIndex := range hierarchy.Ancestors(featureIndex) {
+				ancestor := sequence.Features[ancestorIndex]
+				value := ancestor.Attributes.Get(qualifier)
+				if value == "" && qualifier == "gene" && ancestor.Type == "gene" {
+					value = ancestor.Attributes.Get("Name")
+				}
+				if value != "" {
+					feature.Attributes[qualifier] = []string{value}
+					break
+				}
 			}
-			gbkString.WriteString(lineNumberString + " ")
-			gbkString.WriteRune(base)
-			// if base index is divisible by ten add a space (genbank convention)
-		} else if index%10 == 0 {
-			gbkString.WriteString(" ")
-			gbkString.WriteRune(base)
-			// else just add the base.
-		} else {
-			gbkString.WriteRune(base)
 		}
 	}
-	// finish genbank file with "//" on newline (again a genbank convention)
-	gbkString.WriteString("\n//")
 
-	return gbkString.Bytes()
+	for featureIndex := range features {
+		delete(features[featureIndex].Attributes, "ID")
+		delete(features[featureIndex].Attributes, "Parent")
+	}
+
+	// write every feature followed by its descendants so gene, mRNA and CDS sit next to each other.
+	grouped := make([]poly.Feature, 0, len(features))
+	written := make([]bool, len(features))
+	var writeFeature func(featureIndex int)
+	writeFeature = func(featureIndex int) {
+		if written[featureIndex] {
+			return
+		}
+		written[featureIndex] = true
+		if !merged[featureIndex] {
+			grouped = append(grouped, features[featureIndex])
+		}
+		for _, childIndex := range hierarchy.Children(featureIndex) {
+			writeFeature(childIndex)
+		}
+	}
+	for featureIndex := range features {
+		if len(hierarchy.Parents(featureIndex)) == 0 {
+			writeFeature(featureIndex)
+		}
+	}
+	// features caught in a cycle have parents but can't be reached from a root.
+	for featureIndex := range features {
+		writeFeature(featureIndex)
+	}
+	return grouped
+}
+
+// fromFeatureTable reports whether every feature of sequence came from a gff or gtf line. Those parsers always fill in
+// Strand from the strand column while genbank, EMBL and SnapGene leave it empty.
+func fromFeatureTable(sequence poly.Sequence) bool {
+	for _, feature := range sequence.Features {
+		if feature.Strand == "" {
+			return false
+		}
+	}
+	return len(sequence.Features) > 0
+}
+
+// joinLocations joins the locations of features into one location ordered by start.
+func joinLocations(features []poly.Feature, featureIndices []int) poly.Location {
+	if len(featureIndices) == 1 {
+		return features[featureIndices[0]].SequenceLocation
+	}
+	var location poly.Location
+	location.Join = true
+	for _, featureIndex := range featureIndices {
+		subLocation := features[featureIndex].SequenceLocation
+		subLocation.Complement = false
+		location.SubLocations = append(location.SubLocations, subLocation)
+		location.FivePrimePartial = location.FivePrimePartial || subLocation.FivePrimePartial
+		location.ThreePrimePartial = location.ThreePrimePartial || subLocation.ThreePrimePartial
+	}
+	sort.SliceStable(location.SubLocations, func(i, j int) bool {
+		return location.SubLocations[i].Start < location.SubLocations[j].Start
+	})
+	location.Start = location.SubLocations[0].Start
+	location.End = location.SubLocations[len(location.SubLocations)-1].End
+	return location
+}
+
+func isTranscript(featureType string) bool {
+	switch featureType {
+	case "mRNA", "transcript", "primary_transcript", "ncRNA", "lnc_RNA", "rRNA", "tRNA", "misc_RNA", "precursor_RNA":
+		return true
+	}
+	return false
+}
+
+func copyAttributes(attributes poly.Attributes) poly.Attributes {
+	attributesCopy := make(poly.Attributes, len(attributes))
+	for key, values := range attributes {
+		attributesCopy[key] = append([]string{}, values...)
+	}
+	return attributesCopy
 }
 
 // Read reads a Gbk from path and parses into an Annotated sequence struct.
-func Read(path string) poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	sequence := Parse(file)
-	return sequence
+func Read(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return Parse(file)
+}
+
+// ReadLenient reads a Gbk from path and parses it with ParseLenient.
+func ReadLenient(path string) (poly.Sequence, []*ParseError, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, nil, err
+	}
+	sequence, parseErrors := ParseLenient(file)
+	return sequence, parseErrors, nil
+}
+
+// ParseStrict parses a gbk file with Parse and then validates it. Parse errors are returned as they are and
+// a sequence with validation errors is returned along with a *poly.ValidationError listing every issue found.
+func ParseStrict(file []byte) (poly.Sequence, error) {
+	sequence, err := Parse(file)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return sequence, sequence.ValidateStrict()
+}
+
+// ReadStrict reads a gbk file from path like Read and then validates it like ParseStrict.
+func ReadStrict(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return ParseStrict(file)
 }
 
 // Write takes an Sequence struct and a path string and writes out a gff to that path.
@@ -284,7 +684,7 @@ func quickMetaCheck(line string) bool {
 	if len(line) == 0 {
 		return flag
 	}
-	if string(line[metaIndex]) != " " && string(line[0:2]) != "//" {
+	if string(line[metaIndex]) != " " && !strings.HasPrefix(line, "//") {
 		flag = true
 	}
 	return flag
@@ -305,6 +705,9 @@ func quickSubMetaCheck(line string) bool {
 
 func quickFeatureCheck(line string) bool {
 	flag := false
+	if len(line) <= subMetaIndex {
+		return flag
+	}
 
 	if string(line[metaIndex]) == " " && string(line[subMetaIndex]) != " " {
 		flag = true
@@ -314,8 +717,11 @@ func quickFeatureCheck(line string) bool {
 
 func quickQualifierCheck(line string) bool {
 	flag := false
+	if len(line) <= qualifierIndex {
+		return flag
+	}
 
-	if string(line[metaIndex]) == " " && string(line[subMetaIndex]) == " " && (string(line[qualifierIndex]) == "/" || string(line[optinalQualifierIndex]) == "/") {
+	if string(line[metaIndex]) == " " && string(line[subMetaIndex]) == " " && (string(line[qualifierIndex]) == "/" || (len(line) > optinalQualifierIndex && string(line[optinalQualifierIndex]) == "/")) {
 		flag = true
 	}
 	return flag
@@ -324,6 +730,9 @@ func quickQualifierCheck(line string) bool {
 
 func quickQualifierSubLineCheck(line string) bool {
 	flag := false
+	if len(line) <= qualifierIndex {
+		return flag
+	}
 
 	if string(line[metaIndex]) == " " && string(line[subMetaIndex]) == " " && string(line[qualifierIndex]) != "/" && string(line[qualifierIndex-1]) == " " {
 		flag = true
@@ -331,6 +740,12 @@ func quickQualifierSubLineCheck(line string) bool {
 	return flag
 }
 
+// quickLocationSubLineCheck checks for a line carrying on a location. Unlike qualifier sub lines the
+// qualifiers themselves can't follow, wherever they're indented to.
+func quickLocationSubLineCheck(line string) bool {
+	return quickQualifierSubLineCheck(line) && !strings.HasPrefix(strings.TrimSpace(line), "/")
+}
+
 // checks for only top level features in genbankTopLevelFeatures array
 func topLevelFeatureCheck(featureString string) bool {
 	flag := false
@@ -344,11 +759,11 @@ func topLevelFeatureCheck(featureString string) bool {
 	return flag
 }
 
-// parses locus from provided string.
-func parseLocus(locusString string) poly.Locus {
+// parses locus from provided string. Returns an error wrapping ErrMalformedLocus if it has no name or length.
+func parseLocus(locusString string) (poly.Locus, error) {
 	locus := poly.Locus{}
 
-	basePairRegex, _ := regexp.Compile(` \d* \w{2} `)
+	basePairRegex, _ := regexp.Compile(` \d+ \w{2}\b`)
 	circularRegex, _ := regexp.Compile(` circular `)
 	linearRegex, _ := regexp.Compile(` linear `)
 
@@ -363,6 +778,9 @@ func parseLocus(locusString string) poly.Locus {
 		}
 	}
 
+	if len(filteredLocusSplit) < 2 {
+		return locus, fmt.Errorf("%w: missing name", ErrMalformedLocus)
+	}
 	locus.Name = filteredLocusSplit[1]
 
 	// sequence length and coding
@@ -407,7 +825,10 @@ func parseLocus(locusString string) poly.Locus {
 	// ModificationDate
 	locus.ModificationDate = ModificationDateRegex.FindString(locusString)
 
-	return locus
+	if locus.SequenceLength == "" {
+		return locus, fmt.Errorf("%w: missing sequence length", ErrMalformedLocus)
+	}
+	return locus, nil
 }
 
 // really important helper function. It finds sublines of a feature and joins them.
@@ -415,7 +836,7 @@ func joinSubLines(splitLine, subLines []string) string {
 	base := strings.TrimSpace(strings.Join(splitLine[1:], " "))
 
 	for _, subLine := range subLines {
-		if !quickMetaCheck(subLine) && !quickSubMetaCheck(subLine) {
+		if !quickMetaCheck(subLine) && !quickSubMetaCheck(subLine) && !strings.HasPrefix(subLine, "//") {
 			base = strings.TrimSpace(strings.TrimSpace(base) + " " + strings.TrimSpace(subLine))
 		} else {
 			break
@@ -430,7 +851,7 @@ func getSourceOrganism(splitLine, subLines []string) (string, string) {
 	var organism string
 	for numSubLine, subLine := range subLines {
 		headString := strings.Split(strings.TrimSpace(subLine), " ")[0]
-		if string(subLine[0]) == " " && headString != "ORGANISM" {
+		if len(subLine) > 0 && string(subLine[0]) == " " && headString != "ORGANISM" {
 			source = strings.TrimSpace(strings.TrimSpace(source) + " " + strings.TrimSpace(subLine))
 		} else {
 			organismSubLines := subLines[numSubLine+1:]
@@ -477,12 +898,11 @@ func getReference(splitLine, subLines []string) poly.Reference {
 	return reference
 }
 
-func getFeatures(lines []string) []poly.Feature {
+// getFeatures parses the feature table. lines starts at line number firstLineNumber of the file, which is only used for errors.
+func getFeatures(lines []string, firstLineNumber int) ([]poly.Feature, []*ParseError) {
 	lineIndex := 0
 	features := []poly.Feature{}
-
-	// regex to remove quotes and slashes from qualifiers
-	reg, _ := regexp.Compile("[\"/\n]+")
+	var parseErrors []*ParseError
 
 	// go through every line.
 	for lineIndex < len(lines) {
@@ -495,6 +915,8 @@ func getFeatures(lines []string) []poly.Feature {
 		}
 
 		feature := poly.Feature{}
+		featureLineNumber := firstLineNumber + lineIndex
+		featureLineIndex := lineIndex
 
 		// split the current line for feature type and location fields.
 		splitLine := strings.Split(strings.TrimSpace(line), " ")
@@ -504,160 +926,139 @@ func getFeatures(lines []string) []poly.Feature {
 		// feature.GbkLocationString is the string used by GBK to denote location
 		feature.GbkLocationString = strings.TrimSpace(splitLine[len(splitLine)-1])
 
-		// Check if the location string is multiple lines.
-		nextLineNum := 0
-		for {
-			nextLineNum++
-			nextLine := lines[lineIndex+nextLineNum]
-			// Check if the next line is not a qualifier, it is part of the
-			// GbkLocation String
-			if !strings.Contains(nextLine, "/") {
-				feature.GbkLocationString = feature.GbkLocationString + strings.TrimSpace(nextLine)
-			} else {
-				break
-			}
+		// long location strings wrap onto the following lines before any qualifiers start.
+		lineIndex++
+		for lineIndex < len(lines) && quickLocationSubLineCheck(lines[lineIndex]) {
+			feature.GbkLocationString += strings.TrimSpace(lines[lineIndex])
+			lineIndex++
 		}
-		feature.SequenceLocation = parseLocation(feature.GbkLocationString)
 
-		// initialize attributes.
-		feature.Attributes = make(map[string]string)
+		// features with bad locations keep their GbkLocationString but get an empty SequenceLocation.
+		location, err := ParseLocation(feature.GbkLocationString)
+		if err != nil {
+			parseErrors = append(parseErrors, &ParseError{Line: featureLineNumber, Text: feature.GbkLocationString, Err: fmt.Errorf("%w: %s", ErrInvalidLocation, err)})
+		}
+		feature.SequenceLocation = location
 
-		// end of feature declaration line. Bump to next line and begin looking for qualifiers.
-		lineIndex++
-		line = lines[lineIndex+nextLineNum-1]
+		// initialize attributes.
+		feature.Attributes = make(poly.Attributes)
 
 		// loop through potential qualifiers. Break if not a qualifier or sub line.
 		// Definition of qualifiers here: http://www.insdc.org/files/feature_table.html#3.3
-		for {
-			// make sure what we're parsing is a qualifier. Break if not.
-			// keeping out of normal if else pattern because of phantom brackets that are hard to trace.
-			if !quickQualifierCheck(line) {
-				break
-			}
-
-			qualifier := line
+		for lineIndex < len(lines) && quickQualifierCheck(lines[lineIndex]) {
+			line = lines[lineIndex]
+			qualifier := strings.TrimSpace(line)
 			qualifierKey := strings.TrimSpace(strings.Split(line, "=")[0])
 
 			// end of qualifier declaration line. Bump to next line and begin looking for qualifier sublines.
 			lineIndex++
-			line = lines[lineIndex]
 
-			// loop through any potential continuing lines of qualifiers. Break if not.
-			for {
-				// keeping out of normal if else pattern because of phantom brackets that are hard to trace.
-				if !quickQualifierSubLineCheck(line) {
-					break
-				}
+			// loop through any potential continuing lines of qualifiers.
+			for lineIndex < len(lines) && quickQualifierSubLineCheck(lines[lineIndex]) {
 				//append to current qualifier
-				// qualifier += strings.TrimSpace(line)
 				if qualifierKey != "/translation" {
-					qualifier += " " + strings.TrimSpace(line)
+					qualifier += " " + strings.TrimSpace(lines[lineIndex])
 				} else {
-					qualifier += strings.TrimSpace(line)
+					qualifier += strings.TrimSpace(lines[lineIndex])
 				}
-
-				// nextline
 				lineIndex++
-				line = lines[lineIndex]
 			}
-			//add qualifier to feature.
-			attributeSplit := strings.Split(reg.ReplaceAllString(qualifier, ""), "=")
-			attributeLabel := strings.TrimSpace(attributeSplit[0])
+			//add qualifier to feature. Values can hold = and / themselves so only the first = splits and only the
+			//surrounding quotes are removed. Quotes inside values are escaped by doubling them.
+			attributeSplit := strings.SplitN(strings.TrimSpace(qualifier), "=", 2)
+			attributeLabel := strings.TrimPrefix(strings.TrimSpace(attributeSplit[0]), "/")
 			var attributeValue string
-			// This if-statement is not tested, and panics when run. Not sure why
-			// it is still here - KG 19 Dec 2020
-			if len(attributeSplit) < 2 {
-				attributeValue = ""
-			} else {
+			if len(attributeSplit) == 2 {
 				attributeValue = strings.TrimSpace(attributeSplit[1])
+				if len(attributeValue) >= 2 && strings.HasPrefix(attributeValue, "\"") && strings.HasSuffix(attributeValue, "\"") {
+					attributeValue = strings.ReplaceAll(attributeValue[1:len(attributeValue)-1], "\"\"", "\"")
+				}
 			}
-			feature.Attributes[attributeLabel] = attributeValue
+			feature.Attributes.Add(attributeLabel, attributeValue)
 		}
 
+		feature.GbkFeatureString = joinRawLines(lines[featureLineIndex:lineIndex])
+
 		//append the parsed feature to the features list to be returned.
 		features = append(features, feature)
 
 	}
-	return features
+	return features, parseErrors
 }
 
-// takes every line after origin feature and removes anything that isn't in the alphabet. Returns sequence string.
-func getSequence(subLines []string) string {
+// getSequence takes every line after ORIGIN up to the closing // and removes anything that isn't in the alphabet.
+// lines starts at line number firstLineNumber of the file. Returns the sequence string, the line number of the
+// closing // or 0 if it is missing, and errors for lines holding anything but positions and bases.
+func getSequence(lines []string, firstLineNumber int) (string, int, []*ParseError) {
 	var sequenceBuffer bytes.Buffer
+	var parseErrors []*ParseError
 	reg, err := regexp.Compile("[^a-zA-Z]+")
 	if err != nil {
 		log.Fatal(err)
 	}
-	for _, subLine := range subLines {
-		sequenceBuffer.WriteString(subLine)
+	for lineIndex, line := range lines {
+		if strings.HasPrefix(line, "//") {
+			return reg.ReplaceAllString(sequenceBuffer.String(), ""), firstLineNumber + lineIndex, parseErrors
+		}
+		if strings.TrimLeft(line, " 0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ*-\r") != "" {
+			parseErrors = append(parseErrors, &ParseError{Line: firstLineNumber + lineIndex, Text: line, Err: ErrInvalidSequence})
+		}
+		sequenceBuffer.WriteString(line)
 	}
-	sequence := reg.ReplaceAllString(sequenceBuffer.String(), "")
-	return sequence
+	return reg.ReplaceAllString(sequenceBuffer.String(), ""), 0, parseErrors
 }
 
-func parseLocation(locationString string) poly.Location {
-	var location poly.Location
-	if !(strings.ContainsAny(locationString, "(")) { // Case checks for simple expression of x..x
-		if !(strings.ContainsAny(locationString, ".")) { //Case checks for simple expression x
-			position, _ := strconv.Atoi(locationString)
-			location = poly.Location{Start: position, End: position}
-		} else {
-			// to remove FivePrimePartial and ThreePrimePartial indicators from start and end before converting to int.
-			partialRegex, _ := regexp.Compile("<|>")
-			startEndSplit := strings.Split(locationString, "..")
-			start, _ := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[0], ""))
-			end, _ := strconv.Atoi(partialRegex.ReplaceAllString(startEndSplit[1], ""))
-			location = poly.Location{Start: start - 1, End: end}
-		}
+// sectionKey returns the keyword a top level line of a genbank file starts with. BASE COUNT is the only one with a space.
+func sectionKey(line string) string {
+	if strings.HasPrefix(line, "BASE COUNT") {
+		return "BASE COUNT"
+	}
+	return strings.TrimSpace(strings.Split(line, " ")[0])
+}
 
-	} else {
-		firstOuterParentheses := strings.Index(locationString, "(")
-		expression := locationString[firstOuterParentheses+1 : strings.LastIndex(locationString, ")")]
-		switch command := locationString[0:firstOuterParentheses]; command {
-		case "join":
-			location.Join = true
-			// This case checks for join(complement(x..x),complement(x..x)), or any more complicated derivatives
-			if strings.ContainsAny(expression, "(") {
-				firstInnerParentheses := strings.Index(expression, "(")
-				ParenthesesCount := 1
-				comma := 0
-				for i := 1; ParenthesesCount > 0; i++ { // "(" is at 0, so we start at 1
-					comma = i
-					switch expression[firstInnerParentheses+i] {
-					case []byte("(")[0]:
-						ParenthesesCount++
-					case []byte(")")[0]:
-						ParenthesesCount--
-					}
-				}
-				location.SubLocations = append(location.SubLocations, parseLocation(expression[:firstInnerParentheses+comma+1]), parseLocation(expression[2+firstInnerParentheses+comma:]))
-			} else { // This is the default join(x..x,x..x)
-				for _, numberRange := range strings.Split(expression, ",") {
-					location.SubLocations = append(location.SubLocations, parseLocation(numberRange))
-				}
+// getSections collects the top level sections up to ORIGIN for Meta.GbkSections. A section is a line starting
+// with a keyword and every indented line after it. FEATURES and ORIGIN only keep their own line since the
+// features and sequence that follow them are kept elsewhere.
+func getSections(lines []string) []string {
+	var sections []string
+	var section []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, "//") {
+			break
+		}
+		if !quickMetaCheck(line) {
+			if section != nil {
+				section = append(section, line)
 			}
-
-		case "complement":
-			subLocation := parseLocation(expression)
-			subLocation.Complement = true
-			location.SubLocations = append(location.SubLocations, subLocation)
+			continue
 		}
-	}
 
-	if strings.Contains(locationString, "<") {
-		location.FivePrimePartial = true
+		if section != nil {
+			sections = append(sections, joinRawLines(section))
+			section = nil
+		}
+		switch sectionKey(line) {
+		case "FEATURES":
+			sections = append(sections, joinRawLines([]string{line}))
+		case "ORIGIN":
+			return append(sections, joinRawLines([]string{line}))
+		default:
+			section = []string{line}
+		}
 	}
-
-	if strings.Contains(locationString, ">") {
-		location.ThreePrimePartial = true
+	if section != nil {
+		sections = append(sections, joinRawLines(section))
 	}
+	return sections
+}
 
-	// if excess root node then trim node. Maybe should just be handled with second arg?
-	if location.Start == 0 && location.End == 0 && !location.Join && !location.Complement {
-		location = location.SubLocations[0]
+// joinRawLines joins lines as they were read, dropping the carriage returns of files with windows line endings.
+func joinRawLines(lines []string) string {
+	trimmedLines := make([]string, len(lines))
+	for lineIndex, line := range lines {
+		trimmedLines[lineIndex] = strings.TrimSuffix(line, "\r")
 	}
-
-	return location
+	return strings.Join(trimmedLines, "\n")
 }
 
 // buildMetaString is a helper function to build the meta section of genbank files.
@@ -682,35 +1083,6 @@ func buildMetaString(name string, data string) string {
 	return returnData
 }
 
-// BuildLocationString is a recursive function that takes a location object and creates a gbk location string for Build()
-func BuildLocationString(location poly.Location) string {
-
-	var locationString string
-
-	if location.Complement {
-		location.Complement = false
-		locationString = "complement(" + BuildLocationString(location) + ")"
-
-	} else if location.Join {
-		locationString = "join("
-		for _, sublocation := range location.SubLocations {
-			locationString += BuildLocationString(sublocation) + ","
-		}
-		locationString = strings.TrimSuffix(locationString, ",") + ")"
-	} else {
-
-		locationString = strconv.Itoa(location.Start+1) + ".." + strconv.Itoa(location.End)
-		if location.FivePrimePartial {
-			locationString = "<" + locationString
-		}
-
-		if location.ThreePrimePartial {
-			locationString += ">"
-		}
-	}
-	return locationString
-}
-
 // BuildFeatureString is a helper function to build gbk feature strings for Build()
 func BuildFeatureString(feature poly.Feature) string {
 	whiteSpaceTrailLength := 16 - len(feature.Type) // I wish I was kidding.
@@ -722,21 +1094,147 @@ func BuildFeatureString(feature poly.Feature) string {
 	} else {
 		location = BuildLocationString(feature.SequenceLocation)
 	}
-	featureHeader := generateWhiteSpace(subMetaIndex) + feature.Type + whiteSpaceTrail + location + "\n"
+	featureHeader := generateWhiteSpace(subMetaIndex) + feature.Type + whiteSpaceTrail + wrapLocation(location, qualifierIndex) + "\n"
 	returnString := featureHeader
 
 	qualifierKeys := make([]string, 0, len(feature.Attributes))
 	for key := range feature.Attributes {
 		qualifierKeys = append(qualifierKeys, key)
 	}
+	sort.Strings(qualifierKeys)
 
 	for _, qualifier := range qualifierKeys {
-		returnString += generateWhiteSpace(qualifierIndex) + "/" + qualifier + "=\"" + feature.Attributes[qualifier] + "\"\n"
+		// repeated qualifiers like /db_xref get one line per value.
+		for _, value := range feature.Attributes[qualifier] {
+			returnString += buildQualifierString(qualifier, value)
+		}
+	}
+	return returnString
+}
+
+// unquotedQualifiers are the qualifiers genbank writes without quotes around their values.
+var unquotedQualifiers = map[string]bool{
+	"anticodon":        true,
+	"citation":         true,
+	"codon_start":      true,
+	"compare":          true,
+	"direction":        true,
+	"estimated_length": true,
+	"mod_base":         true,
+	"number":           true,
+	"rpt_type":         true,
+	"rpt_unit_range":   true,
+	"tag_peptide":      true,
+	"transl_except":    true,
+	"transl_table":     true,
+}
+
+// featureLineWidth is how many characters fit after the qualifier indent in a genbank feature table's 79 columns.
+const featureLineWidth = 79 - qualifierIndex
+
+// buildQualifierString builds the lines of a single qualifier. Qualifiers without a value like /pseudo are written bare.
+func buildQualifierString(key, value string) string {
+	qualifier := "/" + key
+	if value != "" {
+		if unquotedQualifiers[key] {
+			qualifier += "=" + value
+		} else {
+			qualifier += "=\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\""
+		}
+	}
 
+	var wrapped string
+	if key == "translation" {
+		// translations have no spaces to break on so they are cut every featureLineWidth characters.
+		for len(qualifier) > featureLineWidth {
+			wrapped += qualifier[:featureLineWidth] + "\n"
+			qualifier = qualifier[featureLineWidth:]
+		}
+		wrapped += qualifier
+	} else {
+		wrapped = wordwrap.WrapString(qualifier, featureLineWidth)
+	}
+
+	var returnString string
+	for _, line := range strings.Split(wrapped, "\n") {
+		returnString += generateWhiteSpace(qualifierIndex) + line + "\n"
 	}
 	return returnString
 }
 
+// wrapLocation breaks long location strings after commas so lines indented by indent fit in 79 columns.
+func wrapLocation(location string, indent int) string {
+	width := 79 - indent
+	var wrapped string
+	for len(location) > width {
+		cut := strings.LastIndex(location[:width], ",")
+		if cut == -1 {
+			break
+		}
+		wrapped += location[:cut+1] + "\n" + generateWhiteSpace(indent)
+		location = location[cut+1:]
+	}
+	return wrapped + location
+}
+
+// buildLocusString builds the LOCUS line of a genbank file.
+func buildLocusString(locus poly.Locus) string {
+	var shape string
+	if locus.Circular {
+		shape = "circular"
+	} else if locus.Linear {
+		shape = "linear"
+	}
+
+	fivespace := generateWhiteSpace(subMetaIndex)
+	locusData := locus.Name + fivespace + locus.SequenceLength + " bp" + fivespace + locus.MoleculeType + fivespace + shape + fivespace + locus.GenbankDivision + fivespace + locus.ModificationDate
+	return "LOCUS       " + locusData + "\n"
+}
+
+// buildReferenceString builds a REFERENCE section. referenceNumber is the reference's 1-based position in the file.
+func buildReferenceString(referenceNumber int, reference poly.Reference) string {
+	referenceData := strconv.Itoa(referenceNumber) + "  " + reference.Range
+	referenceString := buildMetaString("REFERENCE", referenceData)
+
+	// TODO: could use reflection to get keys and make more general.
+	if reference.Authors != "" {
+		referenceString += buildMetaString("  AUTHORS", reference.Authors)
+	}
+	if reference.Title != "" {
+		referenceString += buildMetaString("  TITLE", reference.Title)
+	}
+	if reference.Journal != "" {
+		referenceString += buildMetaString("  JOURNAL", reference.Journal)
+	}
+	if reference.PubMed != "" {
+		referenceString += buildMetaString("  PUBMED", reference.PubMed)
+	}
+	if reference.Remark != "" {
+		referenceString += buildMetaString("  REMARK", reference.Remark)
+	}
+	return referenceString
+}
+
+// buildSequenceString builds the lines of sequence following ORIGIN. Each line holds 60 bases in groups of 10
+// after the 1-based position of its first base.
+func buildSequenceString(sequence string) string {
+	var sequenceString strings.Builder
+	for lineStart := 0; lineStart < len(sequence); lineStart += 60 {
+		lineNumberString := strconv.Itoa(lineStart + 1)      // genbank indexes at 1 for some reason
+		leadingWhiteSpaceLength := 9 - len(lineNumberString) // <- I wish I was kidding
+		sequenceString.WriteString(generateWhiteSpace(leadingWhiteSpaceLength) + lineNumberString)
+		for groupStart := lineStart; groupStart < lineStart+60 && groupStart < len(sequence); groupStart += 10 {
+			groupEnd := groupStart + 10
+			if groupEnd > len(sequence) {
+				groupEnd = len(sequence)
+			}
+			sequenceString.WriteString(" " + sequence[groupStart:groupEnd])
+		}
+		sequenceString.WriteString("\n")
+	}
+	return sequenceString.String()
+}
+
 func generateWhiteSpace(length int) string {
 	var spaceBuilder strings.Builder
 
@@ -759,67 +1257,112 @@ Genbank Flat specific IO related things begin here.
 
 ******************************************************************************/
 
-// ParseMulti parses multiple Genbank files in a byte array to multiple sequences
-func ParseMulti(file []byte) []poly.Sequence {
-
-	gbk := string(file)
-	genbankFiles := strings.SplitAfter(gbk, "//\n")
+// Reader reads genbank records one at a time from a stream holding any number of them, so a multi-gigabyte
+// release file never has to be in memory all at once. Only the record being parsed is buffered.
+type Reader struct {
+	records *flatfile.Reader
+}
 
-	//Remove last genbankFile in list. The real file terminates with //, which
-	//will be interpreted as an empty genbankFile.
-	genbankFiles = genbankFiles[:len(genbankFiles)-1]
+// NewReader returns a Reader over r. Gzip compressed input is detected and decompressed on the fly.
+func NewReader(r io.Reader) (*Reader, error) {
+	records, err := flatfile.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{records: records}, nil
+}
 
-	//Iterate through each genbankFile in genbankFiles list and Parse it
-	//using the Parse function. Return output.
-	var sequences []poly.Sequence
-	for _, f := range genbankFiles {
-		sequences = append(sequences, Parse([]byte(f)))
+// NewFlatReader returns a Reader over a genbank flat file like the ones from the NCBI FTP server,
+// skipping the release header that comes before the first LOCUS line.
+func NewFlatReader(r io.Reader) (*Reader, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
 	}
+	reader.records.SkipUntil("LOCUS")
+	return reader, nil
+}
 
-	return sequences
+// Next reads and parses the next record. It returns io.EOF once there are no records left.
+//
+// Records are parsed with ParseLenient so a bad record still comes back with whatever could be read from it,
+// along with its first problem as a *ParseError whose line number counts from the start of the stream.
+// A *ParseError only concerns that record and Next can be called again to carry on with the rest of the
+// stream. Any other error comes from the underlying reader and is returned again by every later call.
+func (reader *Reader) Next() (poly.Sequence, error) {
+	lines, firstLineNumber, err := reader.records.Next()
+	if err != nil {
+		return poly.Sequence{}, err
+	}
 
+	sequence, parseErrors := ParseLenient([]byte(strings.Join(lines, "\n") + "\n"))
+	if len(parseErrors) > 0 {
+		for _, parseError := range parseErrors {
+			parseError.Line += firstLineNumber - 1
+		}
+		return sequence, parseErrors[0]
+	}
+	return sequence, nil
 }
 
-// ParseFlat specifically takes the output of a Genbank Flat file that from
-// the genbank ftp dumps. These files have 10 line headers, which are entirely
-// removed
-func ParseFlat(file []byte) []poly.Sequence {
+// Close releases the gzip decompressor if the stream was compressed. It does not close the underlying reader.
+func (reader *Reader) Close() error {
+	return reader.records.Close()
+}
 
-	gbk := string(file)
+// readAll reads every record left in reader. Records with problems are kept so one bad record doesn't lose the rest.
+func readAll(reader *Reader) []poly.Sequence {
+	defer reader.Close()
+	return flatfile.ReadAll(reader.Next)
+}
 
-	// This code removes the header, which is 10 lines long. This is inefficient
-	// and gets rid of the data in the header, which may be useful for some
-	// application. Header data is not needed to parse the Genbank files, though
-	gbkWithoutHeader := []byte(strings.Join(strings.Split(gbk, "\n")[10:], "\n"))
+// ParseMulti parses multiple Genbank files in a byte array to multiple sequences
+func ParseMulti(file []byte) []poly.Sequence {
+	reader, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		return nil
+	}
+	return readAll(reader)
+}
 
-	// Pass gbkWithoutHeader to ParseMulti, which should handle
-	// the rest of the parsing just fine
-	sequences := ParseMulti(gbkWithoutHeader)
-	return sequences
+// ParseFlat specifically takes the output of a Genbank Flat file that from
+// the genbank ftp dumps. These files have headers before the first record,
+// which are entirely removed
+func ParseFlat(file []byte) []poly.Sequence {
+	reader, err := NewFlatReader(bytes.NewReader(file))
+	if err != nil {
+		return nil
+	}
+	return readAll(reader)
 }
 
 // ReadMulti reads multiple genbank files from a single file
 func ReadMulti(path string) []poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	sequences := ParseMulti(file)
-	return sequences
+	return readFile(path, NewReader)
 }
 
 // ReadFlat reads flat genbank files, like the ones provided by the NCBI FTP server (after decompression)
 func ReadFlat(path string) []poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	sequences := ParseFlat(file)
-	return sequences
+	return readFile(path, NewFlatReader)
 }
 
 // ReadFlatGz reads flat gzip'd genbank files, like the ones provided by the NCBI FTP server
 func ReadFlatGz(path string) []poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	rdata := bytes.NewReader(file)
-	r, _ := gzip.NewReader(rdata)
-	s, _ := ioutil.ReadAll(r)
-	sequences := ParseFlat(s)
-	return sequences
+	return readFile(path, NewFlatReader)
+}
+
+// readFile streams every record out of the file at path with a Reader made by newReader.
+func readFile(path string, newReader func(io.Reader) (*Reader, error)) []poly.Sequence {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+	reader, err := newReader(file)
+	if err != nil {
+		return nil
+	}
+	return readAll(reader)
 }
 
 /******************************************************************************
@@ -1,14 +1,20 @@
 package genbank
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/gff"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -20,23 +26,23 @@ Gbk/gb/genbank related benchmarks begin here.
 ******************************************************************************/
 
 func ExampleRead() {
-	sequence := Read("../../data/puc19.gbk")
+	sequence, _ := Read("../../data/puc19.gbk")
 	fmt.Println(sequence.Meta.Locus.ModificationDate)
 	// Output: 22-OCT-2019
 }
 
 func ExampleParse() {
 	file, _ := ioutil.ReadFile("../../data/puc19.gbk")
-	sequence := Parse(file)
+	sequence, _ := Parse(file)
 
 	fmt.Println(sequence.Meta.Locus.ModificationDate)
 	// Output: 22-OCT-2019
 }
 
 func ExampleBuild() {
-	sequence := Read("../../data/puc19.gbk")
+	sequence, _ := Read("../../data/puc19.gbk")
 	gbkBytes := Build(sequence)
-	testSequence := Parse(gbkBytes)
+	testSequence, _ := Parse(gbkBytes)
 
 	fmt.Println(testSequence.Meta.Locus.ModificationDate)
 	// Output: 22-OCT-2019
@@ -49,12 +55,12 @@ func ExampleWrite() {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	sequence := Read("../../data/puc19.gbk")
+	sequence, _ := Read("../../data/puc19.gbk")
 
 	tmpGbkFilePath := filepath.Join(tmpDataDir, "puc19.gbk")
 	Write(sequence, tmpGbkFilePath)
 
-	testSequence := Read(tmpGbkFilePath)
+	testSequence, _ := Read(tmpGbkFilePath)
 
 	fmt.Println(testSequence.Meta.Locus.ModificationDate)
 	// Output: 22-OCT-2019
@@ -67,18 +73,18 @@ func TestGbkIO(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	gbk := Read("../../data/puc19.gbk")
+	gbk, _ := Read("../../data/puc19.gbk")
 
 	tmpGbkFilePath := filepath.Join(tmpDataDir, "puc19.gbk")
 	Write(gbk, tmpGbkFilePath)
 
-	writeTestGbk := Read(tmpGbkFilePath)
+	writeTestGbk, _ := Read(tmpGbkFilePath)
 	if diff := cmp.Diff(gbk, writeTestGbk, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
 		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file read with Read(). Got this diff:\n%s", diff)
 	}
 
 	// Test multiline Genbank features
-	pichia := Read("../../data/pichia_chr1_head.gb")
+	pichia, _, _ := ReadLenient("../../data/pichia_chr1_head.gb")
 	var multilineOutput string
 	for _, feature := range pichia.Features {
 		multilineOutput = feature.GbkLocationString
@@ -95,7 +101,7 @@ func TestGbkLocationStringBuilder(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	scrubbedGbk := Read("../../data/sample.gbk")
+	scrubbedGbk, _ := Read("../../data/sample.gbk")
 
 	// removing gbkLocationString from features to allow testing for gbkLocationBuilder
 	for featureIndex := range scrubbedGbk.Features {
@@ -105,10 +111,18 @@ func TestGbkLocationStringBuilder(t *testing.T) {
 	tmpGbkFilePath := filepath.Join(tmpDataDir, "sample.gbk")
 	Write(scrubbedGbk, tmpGbkFilePath)
 
-	testInputGbk := Read("../../data/sample.gbk")
-	testOutputGbk := Read(tmpGbkFilePath)
+	testInputGbk, _ := Read("../../data/sample.gbk")
+	testOutputGbk, _ := Read(tmpGbkFilePath)
 
-	if diff := cmp.Diff(testInputGbk, testOutputGbk, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
+	// sample.gbk marks a partial end the old 687..3158> way, which is built back out the INSDC way.
+	for featureIndex, feature := range testInputGbk.Features {
+		if feature.GbkLocationString == "687..3158>" {
+			testInputGbk.Features[featureIndex].GbkLocationString = "687..>3158"
+		}
+	}
+
+	// features without a location string are written out fresh so their layout changes.
+	if diff := cmp.Diff(testInputGbk, testOutputGbk, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence", "GbkFeatureString")); diff != "" {
 		t.Errorf("Issue with partial location building. Parsing the output of Build() does not produce the same output as parsing the original file read with Read(). Got this diff:\n%s", diff)
 	}
 }
@@ -120,7 +134,7 @@ func TestGbLocationStringBuilder(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	scrubbedGb := Read("../../data/t4_intron.gb")
+	scrubbedGb, _ := Read("../../data/t4_intron.gb")
 
 	// removing gbkLocationString from features to allow testing for gbkLocationBuilder
 	for featureIndex := range scrubbedGb.Features {
@@ -130,16 +144,17 @@ func TestGbLocationStringBuilder(t *testing.T) {
 	tmpGbFilePath := filepath.Join(tmpDataDir, "t4_intron_test.gb")
 	Write(scrubbedGb, tmpGbFilePath)
 
-	testInputGb := Read("../../data/t4_intron.gb")
-	testOutputGb := Read(tmpGbFilePath)
+	testInputGb, _ := Read("../../data/t4_intron.gb")
+	testOutputGb, _ := Read(tmpGbFilePath)
 
-	if diff := cmp.Diff(testInputGb, testOutputGb, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
+	// features without a location string are written out fresh so their layout changes.
+	if diff := cmp.Diff(testInputGb, testOutputGb, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence", "GbkFeatureString")); diff != "" {
 		t.Errorf("Issue with either Join or complement location building. Parsing the output of Build() does not produce the same output as parsing the original file read with Read(). Got this diff:\n%s", diff)
 	}
 }
 
 func TestPartialLocationParseRegression(t *testing.T) {
-	gbk := Read("../../data/sample.gbk")
+	gbk, _ := Read("../../data/sample.gbk")
 
 	for _, feature := range gbk.Features {
 		if feature.GbkLocationString == "687..3158>" && (feature.SequenceLocation.Start != 686 || feature.SequenceLocation.End != 3158) {
@@ -150,17 +165,67 @@ func TestPartialLocationParseRegression(t *testing.T) {
 	}
 }
 
+func TestThreePrimePartialLocation(t *testing.T) {
+	for _, locationString := range []string{"3..>15", "3..15>"} {
+		sequence, err := Parse([]byte(strings.Replace(brokenGbk, "3..15", locationString, 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		feature := sequence.Features[0]
+		expected := poly.Location{Start: 2, End: 15, ThreePrimePartial: true}
+		if diff := cmp.Diff(expected, feature.SequenceLocation); diff != "" {
+			t.Errorf("%s was not parsed (-want +got):\n%s", locationString, diff)
+		}
+		if feature.GbkLocationString != locationString {
+			t.Errorf("Expected the location string as it was read. Got %q", feature.GbkLocationString)
+		}
+		if built := BuildLocationString(feature.SequenceLocation); built != "3..>15" {
+			t.Errorf("%s should be built as 3..>15. Got %q", locationString, built)
+		}
+	}
+}
+
 func TestSnapgeneGenbankRegression(t *testing.T) {
-	snapgene := Read("../../data/puc19_snapgene.gb")
+	snapgene, _ := Read("../../data/puc19_snapgene.gb")
 
 	if snapgene.Sequence == "" {
 		t.Errorf("Parsing snapgene returned an empty string")
 	}
 }
 
+func TestQualifierIndentRegression(t *testing.T) {
+	// qualifiers in this file start at column 29 rather than 21, straight after a location.
+	sequence, err := Read("../../data/pfu-sso7d.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequence.Features) != 17 {
+		t.Fatalf("Expected 17 features. Got %d", len(sequence.Features))
+	}
+
+	tests := []struct {
+		featureIndex int
+		location     poly.Location
+		label        string
+	}{
+		{featureIndex: 0, location: poly.Location{Start: 0, End: 3411}, label: "Translation 1-3411"},
+		{featureIndex: 4, location: poly.Location{Start: 2343, End: 2532}, label: "Sso7d DNA binding domain"},
+		{featureIndex: 16, location: poly.Location{Start: 3351, End: 3408}, label: "Translation 3352-3408"},
+	}
+	for _, test := range tests {
+		feature := sequence.Features[test.featureIndex]
+		if diff := cmp.Diff(test.location, feature.SequenceLocation); diff != "" {
+			t.Errorf("Feature %d has the wrong location (-want +got):\n%s", test.featureIndex, diff)
+		}
+		if label := feature.Attributes.Get("label"); label != test.label {
+			t.Errorf("Feature %d: expected label %q. Got %q", test.featureIndex, test.label, label)
+		}
+	}
+}
+
 func TestGetSequenceMethod(t *testing.T) {
 
-	gbk := Read("../../data/t4_intron.gb")
+	gbk, _ := Read("../../data/t4_intron.gb")
 
 	// Check to see if GetSequence method works on Features struct
 	feature := gbk.Features[1].GetSequence()
@@ -172,7 +237,7 @@ func TestGetSequenceMethod(t *testing.T) {
 }
 
 func TestLocationParser(t *testing.T) {
-	gbk := Read("../../data/t4_intron.gb")
+	gbk, _ := Read("../../data/t4_intron.gb")
 
 	// Read 1..243
 	feature := gbk.Features[1].GetSequence()
@@ -214,12 +279,41 @@ func TestLocationParser(t *testing.T) {
 	}
 }
 
+func TestOriginSpanningFeatures(t *testing.T) {
+	phix, _ := Read("../../data/phix174.gb")
+
+	// CDS join(3981..5386,1..136) crosses the origin of the circular phiX174 genome.
+	cds := phix.Features[2]
+	if cds.SequenceLocation.Start != 3980 || cds.SequenceLocation.End != 136 || !cds.SequenceLocation.SpansOrigin() {
+		t.Errorf("Origin spanning join was not parsed into a single range. Got: %+v", cds.SequenceLocation)
+	}
+
+	expectedSequence := phix.Sequence[3980:] + phix.Sequence[:136]
+	if cdsSequence := cds.GetSequence(); cdsSequence != expectedSequence || !strings.HasPrefix(cdsSequence, "atg") {
+		t.Errorf("Origin spanning CDS sequence is wrong. Got:\n%s", cdsSequence)
+	}
+
+	// removing gbkLocationString from features to make sure Build writes origin spanning features as joins.
+	for featureIndex := range phix.Features {
+		phix.Features[featureIndex].GbkLocationString = ""
+	}
+	built := Build(phix)
+	if !strings.Contains(string(built), "CDS             join(3981..5386,1..136)") {
+		t.Errorf("Build did not write origin spanning feature as a join.")
+	}
+
+	reparsed, _ := Parse(built)
+	if diff := cmp.Diff(phix.Features[2].SequenceLocation, reparsed.Features[2].SequenceLocation); diff != "" {
+		t.Errorf("Origin spanning feature does not round trip. Got this diff:\n%s", diff)
+	}
+}
+
 func TestGenbankNewlineParsingRegression(t *testing.T) {
-	gbk := Read("../../data/puc19.gbk")
+	gbk, _ := Read("../../data/puc19.gbk")
 
 	for _, feature := range gbk.Features {
 		if feature.SequenceLocation.Start == 410 && feature.SequenceLocation.End == 1750 && feature.Type == "CDS" {
-			if feature.Attributes["product"] != "chromosomal replication initiator informational ATPase" {
+			if feature.Attributes.Get("product") != "chromosomal replication initiator informational ATPase" {
 				t.Errorf("Newline parsing has failed.")
 			}
 			break
@@ -227,6 +321,135 @@ func TestGenbankNewlineParsingRegression(t *testing.T) {
 	}
 }
 
+func TestRepeatedQualifiers(t *testing.T) {
+	pichia, _, _ := ReadLenient("../../data/pichia_chr1_head.gb")
+
+	var cds poly.Feature
+	for _, feature := range pichia.Features {
+		if feature.Type == "CDS" {
+			cds = feature
+			break
+		}
+	}
+
+	dbXrefs := []string{"EnsemblGenomes-Gn:PP7435_Chr1-0001", "EnsemblGenomes-Tr:CCA36173", "UniProtKB/TrEMBL:F2QL95"}
+	if diff := cmp.Diff(dbXrefs, cds.Attributes["db_xref"]); diff != "" {
+		t.Errorf("Repeated /db_xref qualifiers were not all kept in order. Got this diff:\n%s", diff)
+	}
+
+	reparsed, _ := ParseLenient(Build(pichia))
+	for _, feature := range reparsed.Features {
+		if feature.Type == "CDS" {
+			if diff := cmp.Diff(dbXrefs, feature.Attributes["db_xref"]); diff != "" {
+				t.Errorf("Repeated qualifiers do not round trip. Got this diff:\n%s", diff)
+			}
+			break
+		}
+	}
+}
+
+// roundTripNormalizations are the only differences TestRoundTripCorpus accepts between a file and Build's output.
+// Build always writes unix line endings and ends every record with "//\n", and Reader skips anything before the
+// first LOCUS like the release header of NCBI flat files.
+var roundTripNormalizations = []struct {
+	name      string
+	normalize func(file string) string
+}{
+	{"dropped release header", func(file string) string {
+		if locusIndex := strings.Index(file, "\nLOCUS"); !strings.HasPrefix(file, "LOCUS") && locusIndex != -1 {
+			return file[locusIndex+1:]
+		}
+		return file
+	}},
+	{"unix line endings", func(file string) string {
+		return strings.ReplaceAll(file, "\r\n", "\n")
+	}},
+	{"trimmed trailing whitespace from sequence lines and //", func(file string) string {
+		return regexp.MustCompile(`(?m)^( +\d+(?: [a-zA-Z*-]+)+|//)[ \t]+$`).ReplaceAllString(file, "$1")
+	}},
+	{"single newline at the end", func(file string) string {
+		return strings.TrimRight(file, "\n") + "\n"
+	}},
+}
+
+func TestRoundTripCorpus(t *testing.T) {
+	paths, _ := filepath.Glob("../../data/*")
+	for _, path := range paths {
+		if extension := filepath.Ext(path); extension != ".gb" && extension != ".gbk" && extension != ".seq" {
+			continue
+		}
+
+		file, _ := ioutil.ReadFile(path)
+		reader, _ := NewFlatReader(bytes.NewReader(file))
+		var built bytes.Buffer
+		for {
+			sequence, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			built.Write(Build(sequence))
+		}
+
+		expected := string(file)
+		var normalizations []string
+		for _, normalization := range roundTripNormalizations {
+			if normalized := normalization.normalize(expected); normalized != expected {
+				expected = normalized
+				normalizations = append(normalizations, normalization.name)
+			}
+		}
+
+		if diff := cmp.Diff(strings.Split(expected, "\n"), strings.Split(built.String(), "\n")); diff != "" {
+			t.Errorf("%s does not round trip through Parse and Build (-want +got):\n%s", path, diff)
+		}
+		if len(normalizations) > 0 {
+			t.Logf("%s round trips with normalizations: %s", path, strings.Join(normalizations, ", "))
+		}
+	}
+}
+
+func TestBuildKeepsLayout(t *testing.T) {
+	original, _ := ioutil.ReadFile("../../data/phix174.gb")
+	phix, _ := Parse(original)
+
+	phix.Meta.Definition = "Escherichia phage phiX174, edited."
+	phix.Features[2].Attributes["note"] = []string{"an edited note"}
+	built := string(Build(phix))
+
+	// edited sections are written fresh.
+	reparsed, _ := Parse([]byte(built))
+	if reparsed.Meta.Definition != phix.Meta.Definition {
+		t.Errorf("Edited DEFINITION was not written. Got: %q", reparsed.Meta.Definition)
+	}
+	if diff := cmp.Diff(phix.Features[2].Attributes, reparsed.Features[2].Attributes); diff != "" {
+		t.Errorf("Edited feature was not written. Got this diff:\n%s", diff)
+	}
+
+	// everything else is written exactly as it was read.
+	for _, section := range phix.Meta.GbkSections {
+		if sectionKey(section) != "DEFINITION" && !strings.Contains(built, section+"\n") {
+			t.Errorf("Untouched section was not kept as it was read:\n%s", section)
+		}
+	}
+	for featureIndex, feature := range phix.Features {
+		if featureIndex != 2 && !strings.Contains(built, feature.GbkFeatureString+"\n") {
+			t.Errorf("Untouched feature %d was not kept as it was read:\n%s", featureIndex, feature.GbkFeatureString)
+		}
+	}
+
+	// BuildNormalized ignores the original layout, here the COMMENT's line breaks.
+	normalized := string(BuildNormalized(phix))
+	for _, section := range phix.Meta.GbkSections {
+		if sectionKey(section) == "COMMENT" && strings.Contains(normalized, section) {
+			t.Errorf("BuildNormalized kept the original layout of the COMMENT section.")
+		}
+	}
+	renormalized, _ := Parse([]byte(normalized))
+	if diff := cmp.Diff(reparsed.Meta.Other, renormalized.Meta.Other); diff != "" {
+		t.Errorf("BuildNormalized changed the meta data. Got this diff:\n%s", diff)
+	}
+}
+
 func BenchmarkRead(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		Read("../../data/bsub.gbk")
@@ -308,8 +531,282 @@ func ExampleParseFlat() {
 	// Output: AB000100, AB000106
 }
 
+func ExampleReader() {
+	file, _ := os.Open("../../data/flatGbk_test.seq.gz")
+	defer file.Close()
+
+	reader, _ := NewFlatReader(file)
+	defer reader.Close()
+
+	for {
+		sequence, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println(sequence.Meta.Locus.Name)
+	}
+	// Output:
+	// AB000100
+	// AB000106
+}
+
+func TestReaderRecordErrors(t *testing.T) {
+	broken := strings.Replace(brokenGbk, "3..15", "3..15)", 1)
+	stream := brokenGbk + "\n" + broken + brokenGbk
+
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	_, _ = gzipWriter.Write([]byte(stream))
+	gzipWriter.Close()
+
+	for name, input := range map[string][]byte{"plain": []byte(stream), "gzip": compressed.Bytes()} {
+		reader, err := NewReader(bytes.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+
+		var names []string
+		var recordErrors []error
+		for {
+			sequence, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			names = append(names, sequence.Meta.Locus.Name)
+			recordErrors = append(recordErrors, err)
+		}
+
+		if diff := cmp.Diff([]string{"broken", "broken", "broken"}, names); diff != "" {
+			t.Errorf("%s: unexpected records (-want +got):\n%s", name, diff)
+		}
+		if len(recordErrors) != 3 || recordErrors[0] != nil || recordErrors[2] != nil {
+			t.Fatalf("%s: only the middle record should have an error. Got: %v", name, recordErrors)
+		}
+		var parseError *ParseError
+		if !errors.As(recordErrors[1], &parseError) || !errors.Is(parseError, ErrInvalidLocation) {
+			t.Fatalf("%s: expected an invalid location error. Got: %v", name, recordErrors[1])
+		}
+		// the broken location is on line 4 of the second record, which starts after the first record and a blank line.
+		if parseError.Line != 13 {
+			t.Errorf("%s: error should be on line 13 of the stream. Got line %d", name, parseError.Line)
+		}
+		if _, err := reader.Next(); err != io.EOF {
+			t.Errorf("%s: reading past the end should keep returning io.EOF. Got: %v", name, err)
+		}
+	}
+}
+
+func TestReaderTruncatedStream(t *testing.T) {
+	stream := brokenGbk + strings.TrimSuffix(brokenGbk, "//\n")
+	reader, _ := NewReader(strings.NewReader(stream))
+
+	if _, err := reader.Next(); err != nil {
+		t.Fatalf("First record should parse. Got: %s", err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Record without // at the end of the stream should be truncated. Got: %v", err)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last record. Got: %v", err)
+	}
+}
+
 /******************************************************************************
 
 GbkMulti/GbkFlat related tests end here.
 
 ******************************************************************************/
+
+func TestBuildGroupsGeneModels(t *testing.T) {
+	gffString := `##gff-version 3
+##sequence-region chr1 1 42
+chr1	test	CDS	21	30	.	-	0	ID=cds1;Parent=mRNA1
+chr1	test	gene	1	40	.	-	.	ID=gene1;Name=abcA
+chr1	test	mRNA	1	40	.	-	.	ID=mRNA1;Parent=gene1
+chr1	test	exon	1	10	.	-	.	Parent=mRNA1
+chr1	test	exon	21	40	.	-	.	Parent=mRNA1
+chr1	test	CDS	5	10	.	-	0	ID=cds1;Parent=mRNA1
+chr1	test	misc_feature	11	12	.	+	.	Parent=nothing;note=orphan
+##FASTA
+>chr1
+ATGAAACCCGGGTTTAAATAGATGAAACCCGGGTTTAAATAG
+`
+	sequence, _ := gff.Parse([]byte(gffString))
+	genbank, _ := Parse(Build(sequence))
+
+	var types, locations, genes []string
+	for _, feature := range genbank.Features {
+		types = append(types, feature.Type)
+		locations = append(locations, feature.GbkLocationString)
+		genes = append(genes, feature.Attributes.Get("gene"))
+		if _, ok := feature.Attributes["ID"]; ok {
+			t.Errorf("ID should not be written to genbank. Got: %v", feature.Attributes)
+		}
+	}
+
+	if diff := cmp.Diff([]string{"gene", "mRNA", "CDS", "exon", "exon", "misc_feature"}, types); diff != "" {
+		t.Errorf("Build did not group gene model. Got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"complement(1..40)", "complement(join(1..10,21..40))", "complement(join(5..10,21..30))", "complement(1..10)", "complement(21..40)", "11..12"}, locations); diff != "" {
+		t.Errorf("Build did not join gene model locations. Got this diff:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"abcA", "abcA", "abcA", "abcA", "abcA", ""}, genes); diff != "" {
+		t.Errorf("Build did not pass /gene down the gene model. Got this diff:\n%s", diff)
+	}
+	if sequence.Features[0].Attributes.Get("ID") != "cds1" {
+		t.Errorf("Build should not modify the features it was given.")
+	}
+}
+
+func TestBuildKeepsGenbankIDs(t *testing.T) {
+	gbkString := `LOCUS       ids                       20 bp    DNA     linear   SYN 01-JAN-2021
+FEATURES             Location/Qualifiers
+     misc_feature    11..15
+                     /ID="part2"
+                     /Parent="part1"
+     misc_feature    3..15
+                     /ID="part1"
+     misc_feature    1..2
+                     /ID="part1"
+ORIGIN
+        1 atgaaaccca tgaaacccaa
+//
+`
+	sequence, err := Parse([]byte(gbkString))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(gbkString, string(Build(sequence))); diff != "" {
+		t.Errorf("Build should leave the /ID and /Parent of a genbank record alone. Got this diff:\n%s", diff)
+	}
+	normalized, _ := Parse(BuildNormalized(sequence))
+	for featureIndex, feature := range normalized.Features {
+		if diff := cmp.Diff(sequence.Features[featureIndex].Attributes, feature.Attributes); diff != "" {
+			t.Errorf("BuildNormalized should leave the /ID and /Parent of a genbank record alone. Got this diff:\n%s", diff)
+		}
+	}
+}
+
+// brokenGbk is a tiny genbank file for breaking in different ways.
+const brokenGbk = `LOCUS       broken                    20 bp    DNA     linear   SYN 01-JAN-2021
+DEFINITION  A file for breaking.
+FEATURES             Location/Qualifiers
+     gene            3..15
+                     /gene="brk"
+ORIGIN
+        1 atgaaaccca tgaaacccaa
+//
+`
+
+func TestBuildBaseCount(t *testing.T) {
+	counted := strings.Replace(brokenGbk, "ORIGIN\n", "BASE COUNT       10 a      6 c      2 g      2 t\nORIGIN\n", 1)
+	sequence, err := Parse([]byte(counted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built := string(Build(sequence)); built != counted {
+		t.Errorf("BASE COUNT that still adds up should be written as it was read. Got:\n%s", built)
+	}
+
+	if err := sequence.Insert(0, "GGGGG"); err != nil {
+		t.Fatal(err)
+	}
+	for _, built := range []string{string(Build(sequence)), string(BuildNormalized(sequence))} {
+		if !strings.Contains(built, "\nBASE COUNT       10 a      6 c      7 g      2 t\n") {
+			t.Errorf("BASE COUNT was not counted again after an edit. Got:\n%s", built)
+		}
+	}
+}
+
+func TestReadStrict(t *testing.T) {
+	if _, err := ReadStrict("../../data/puc19.gbk"); err != nil {
+		t.Errorf("Valid genbank failed strict parsing: %s", err)
+	}
+
+	pastEnd := strings.Replace(brokenGbk, "3..15", "3..25", 1)
+	_, err := ParseStrict([]byte(pastEnd))
+	validationError, ok := err.(*poly.ValidationError)
+	if !ok {
+		t.Fatalf("Feature past the end of the sequence should fail strict parsing. Got: %v", err)
+	}
+	if validationError.Issues[0].FeatureIndex != 0 {
+		t.Errorf("ValidationError should point at the broken feature. Got: %v", validationError.Issues[0])
+	}
+
+	// parse errors come back before validation gets a chance to run.
+	if _, err := ReadStrict("../../data/pichia_chr1_head.gb"); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Truncated genbank should fail strict parsing with a parse error. Got: %v", err)
+	}
+
+	if _, err := ReadStrict("../../data/does_not_exist.gbk"); err == nil {
+		t.Errorf("ReadStrict should return an error for missing files.")
+	}
+}
+
+func ExampleParseLenient() {
+	truncated := []byte(`LOCUS       broken                    20 bp    DNA     linear   SYN 01-JAN-2021
+FEATURES             Location/Qualifiers
+     gene            3..15
+ORIGIN
+        1 atgaaaccca tgaaa
+`)
+	sequence, warnings := ParseLenient(truncated)
+
+	fmt.Println(sequence.Sequence)
+	for _, warning := range warnings {
+		fmt.Println(warning)
+	}
+	// Output:
+	// atgaaacccatgaaa
+	// line 5: truncated file: missing //: "        1 atgaaaccca tgaaa"
+	// line 5: truncated file: LOCUS declares 20 bp but ORIGIN has 15: "        1 atgaaaccca tgaaa"
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		line int
+		err  error
+	}{
+		{"locus without length", strings.Replace(brokenGbk, "20 bp", "", 1), 1, ErrMalformedLocus},
+		{"missing locus", strings.Replace(brokenGbk, "LOCUS ", "LOCOS ", 1), 1, ErrMalformedLocus},
+		{"bad location", strings.Replace(brokenGbk, "3..15", "3..15)", 1), 4, ErrInvalidLocation},
+		{"bad location on wrapped line", strings.Replace(brokenGbk, "3..15\n", "join(3..5,\n                     9..x15)\n", 1), 4, ErrInvalidLocation},
+		{"garbage in origin", strings.Replace(brokenGbk, "tgaaacccaa", "tgaaac%ccaa", 1), 7, ErrInvalidSequence},
+		{"short origin", strings.Replace(brokenGbk, "tgaaacccaa", "tgaaa", 1), 8, ErrTruncated},
+		{"missing end", strings.Replace(brokenGbk, "//\n", "", 1), 7, ErrTruncated},
+	}
+
+	if _, err := Parse([]byte(brokenGbk)); err != nil {
+		t.Fatalf("Unbroken file failed to parse: %s", err)
+	}
+	for _, test := range tests {
+		sequence, err := Parse([]byte(test.file))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Errorf("%s: expected a *ParseError. Got: %v", test.name, err)
+			continue
+		}
+		if !errors.Is(err, test.err) || parseError.Line != test.line {
+			t.Errorf("%s: expected %q on line %d. Got: %s", test.name, test.err, test.line, err)
+		}
+		if sequence.Sequence != "" {
+			t.Errorf("%s: Parse should not return a sequence along with an error.", test.name)
+		}
+	}
+
+	// lenient parsing keeps going and reports everything.
+	broken := strings.Replace(strings.Replace(brokenGbk, "3..15", "3..15)", 1), "//\n", "", 1)
+	sequence, warnings := ParseLenient([]byte(broken))
+	if len(warnings) != 2 || !errors.Is(warnings[0], ErrInvalidLocation) || !errors.Is(warnings[1], ErrTruncated) {
+		t.Errorf("ParseLenient should collect every problem in line order. Got: %v", warnings)
+	}
+	if sequence.Sequence != "atgaaacccatgaaacccaa" || sequence.Features[0].Attributes.Get("gene") != "brk" {
+		t.Errorf("ParseLenient should still parse what it can.")
+	}
+}
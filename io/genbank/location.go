@@ -0,0 +1,290 @@
+package genbank
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+)
+
+/******************************************************************************
+
+Location parsing and building begins here.
+
+Locations are written in the INSDC location grammar which is shared by
+GenBank, EMBL and DDBJ. The full spec lives here:
+
+http://www.insdc.org/files/feature_table.html#3.4
+
+The forms we understand are:
+
+	467                      a single base
+	340..565                 a range of bases
+	<345..500, 1..>888       ranges with partial ends
+	102.110                  a single unknown base within a range
+	123^124                  a site between two bases
+	J00194.1:100..202        a range in another entry
+	complement(...)          the reverse complement of a location
+	join(...), order(...)    a list of locations that are joined or merely ordered
+	bond(...)                a list of bonds between residues in proteins
+
+Operators can be nested as deep as anyone cares to nest them.
+
+******************************************************************************/
+
+// locationParser is a small recursive descent parser for INSDC location strings.
+type locationParser struct {
+	input    string
+	position int
+}
+
+// ParseLocation parses an INSDC location string like complement(join(1..10,20..30)) into a poly.Location.
+func ParseLocation(locationString string) (poly.Location, error) {
+	// location strings can be wrapped over multiple lines so whitespace means nothing.
+	parser := locationParser{input: strings.Join(strings.Fields(locationString), "")}
+	if parser.input == "" {
+		return poly.Location{}, fmt.Errorf("empty location string")
+	}
+
+	location, err := parser.parseLocation()
+	if err != nil {
+		return poly.Location{}, err
+	}
+	if parser.position != len(parser.input) {
+		return poly.Location{}, parser.errorf("unexpected %q", parser.input[parser.position:])
+	}
+	return location, nil
+}
+
+func (parser *locationParser) errorf(format string, arguments ...interface{}) error {
+	return fmt.Errorf("invalid location %q at character %d: %s", parser.input, parser.position+1, fmt.Sprintf(format, arguments...))
+}
+
+func (parser *locationParser) parseLocation() (poly.Location, error) {
+	for _, operator := range []string{"complement", "join", "order", "bond"} {
+		if strings.HasPrefix(parser.input[parser.position:], operator+"(") {
+			parser.position += len(operator) + 1
+			return parser.parseOperator(operator)
+		}
+	}
+	return parser.parseRange()
+}
+
+// parseOperator parses the arguments of an operator up to and including its closing parenthesis.
+func (parser *locationParser) parseOperator(operator string) (poly.Location, error) {
+	var subLocations []poly.Location
+	for {
+		subLocation, err := parser.parseLocation()
+		if err != nil {
+			return poly.Location{}, err
+		}
+		subLocations = append(subLocations, subLocation)
+
+		if parser.position >= len(parser.input) {
+			return poly.Location{}, parser.errorf("missing closing parenthesis for %s", operator)
+		}
+		character := parser.input[parser.position]
+		parser.position++
+		if character == ')' {
+			break
+		}
+		if character != ',' {
+			return poly.Location{}, parser.errorf("expected ',' or ')' but got %q", character)
+		}
+	}
+
+	if operator == "complement" {
+		if len(subLocations) != 1 {
+			return poly.Location{}, parser.errorf("complement takes exactly one location but got %d", len(subLocations))
+		}
+		location := subLocations[0]
+		location.Complement = !location.Complement
+		return location, nil
+	}
+
+	location := poly.Location{SubLocations: subLocations}
+	switch operator {
+	case "join":
+		location.Join = true
+	case "order":
+		location.Order = true
+	case "bond":
+		location.Bond = true
+	}
+
+	// compound locations are partial if any of their parts are.
+	for _, subLocation := range subLocations {
+		location.FivePrimePartial = location.FivePrimePartial || subLocation.FivePrimePartial
+		location.ThreePrimePartial = location.ThreePrimePartial || subLocation.ThreePrimePartial
+	}
+	return location, nil
+}
+
+// parseRange parses a single base, range, site or remote location.
+func (parser *locationParser) parseRange() (poly.Location, error) {
+	var location poly.Location
+
+	// remote locations start with an accession like J00194.1: which always starts with a letter.
+	if colon := strings.IndexByte(parser.input[parser.position:], ':'); colon > 0 && isLetter(parser.input[parser.position]) {
+		accession := parser.input[parser.position : parser.position+colon]
+		if strings.ContainsAny(accession, "(),") {
+			return poly.Location{}, parser.errorf("invalid accession %q", accession)
+		}
+		location.Accession = accession
+		parser.position += colon + 1
+	}
+
+	start, startFivePrimePartial, startThreePrimePartial, err := parser.parseBase()
+	if err != nil {
+		return poly.Location{}, err
+	}
+	location.FivePrimePartial = startFivePrimePartial
+
+	rest := parser.input[parser.position:]
+	switch {
+	case strings.HasPrefix(rest, ".."):
+		parser.position += 2
+		end, endFivePrimePartial, endThreePrimePartial, err := parser.parseBase()
+		if err != nil {
+			return poly.Location{}, err
+		}
+		location.Start, location.End = start-1, end
+		location.FivePrimePartial = location.FivePrimePartial || endFivePrimePartial
+		location.ThreePrimePartial = startThreePrimePartial || endThreePrimePartial
+	case strings.HasPrefix(rest, "."):
+		parser.position++
+		end, _, _, err := parser.parseBase()
+		if err != nil {
+			return poly.Location{}, err
+		}
+		location.Start, location.End = start-1, end
+		location.SingleBase = true
+	case strings.HasPrefix(rest, "^"):
+		parser.position++
+		end, _, _, err := parser.parseBase()
+		if err != nil {
+			return poly.Location{}, err
+		}
+		// a site between two bases has no length. Start is the 0-based position of the gap.
+		location.Start, location.End = start, end-1
+		location.Between = true
+	default:
+		location.Start, location.End = start-1, start
+		location.ThreePrimePartial = startThreePrimePartial
+	}
+
+	return location, nil
+}
+
+// parseBase parses a 1-based position with optional leading or trailing partial markers.
+func (parser *locationParser) parseBase() (int, bool, bool, error) {
+	var fivePrimePartial, threePrimePartial bool
+	if parser.position < len(parser.input) {
+		switch parser.input[parser.position] {
+		case '<':
+			fivePrimePartial = true
+			parser.position++
+		case '>':
+			threePrimePartial = true
+			parser.position++
+		}
+	}
+
+	digitsStart := parser.position
+	for parser.position < len(parser.input) && parser.input[parser.position] >= '0' && parser.input[parser.position] <= '9' {
+		parser.position++
+	}
+	if digitsStart == parser.position {
+		return 0, false, false, parser.errorf("expected a position")
+	}
+	base, err := strconv.Atoi(parser.input[digitsStart:parser.position])
+	if err != nil {
+		return 0, false, false, parser.errorf("%s", err)
+	}
+	if base < 1 {
+		return 0, false, false, parser.errorf("positions start at 1 but got %d", base)
+	}
+
+	// some software writes partial ends after the position like 687..3158>
+	if parser.position < len(parser.input) {
+		switch parser.input[parser.position] {
+		case '<':
+			fivePrimePartial = true
+			parser.position++
+		case '>':
+			threePrimePartial = true
+			parser.position++
+		}
+	}
+	return base, fivePrimePartial, threePrimePartial, nil
+}
+
+func isLetter(character byte) bool {
+	return (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z')
+}
+
+// BuildLocationString is a recursive function that takes a location object and creates a gbk location string for Build()
+func BuildLocationString(location poly.Location) string {
+	if location.Complement {
+		location.Complement = false
+		return "complement(" + BuildLocationString(location) + ")"
+	}
+
+	var operator string
+	switch {
+	case location.Join:
+		operator = "join"
+	case location.Order:
+		operator = "order"
+	case location.Bond:
+		operator = "bond"
+	}
+	if operator != "" || len(location.SubLocations) > 0 {
+		// a bare list of sub locations reads as a join.
+		if operator == "" {
+			operator = "join"
+		}
+		subLocationStrings := make([]string, len(location.SubLocations))
+		for subLocationIndex, subLocation := range location.SubLocations {
+			subLocationStrings[subLocationIndex] = BuildLocationString(subLocation)
+		}
+		return operator + "(" + strings.Join(subLocationStrings, ",") + ")"
+	}
+
+	var locationString string
+	if location.Accession != "" {
+		locationString = location.Accession + ":"
+	}
+
+	start := strconv.Itoa(location.Start + 1)
+	end := strconv.Itoa(location.End)
+	if location.FivePrimePartial {
+		start = "<" + start
+	}
+	if location.ThreePrimePartial {
+		end = ">" + end
+	}
+
+	switch {
+	case location.Between:
+		locationString += strconv.Itoa(location.Start) + "^" + strconv.Itoa(location.End+1)
+	case location.SingleBase:
+		locationString += start + "." + end
+	case location.End == location.Start+1 && !(location.FivePrimePartial && location.ThreePrimePartial):
+		if location.ThreePrimePartial {
+			locationString += end
+		} else {
+			locationString += start
+		}
+	default:
+		locationString += start + ".." + end
+	}
+	return locationString
+}
+
+/******************************************************************************
+
+Location parsing and building ends here.
+
+******************************************************************************/
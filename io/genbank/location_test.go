@@ -0,0 +1,88 @@
+package genbank
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleParseLocation() {
+	location, _ := ParseLocation("complement(join(<1..10,20..30))")
+
+	fmt.Println(location.Complement, location.Join, location.SubLocations[0].FivePrimePartial, location.SubLocations[1].Start)
+	// Output: true true true 19
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		locationString string
+		location       poly.Location
+	}{
+		{"467", poly.Location{Start: 466, End: 467}},
+		{"340..565", poly.Location{Start: 339, End: 565}},
+		{"<345..500", poly.Location{Start: 344, End: 500, FivePrimePartial: true}},
+		{"<1..>888", poly.Location{Start: 0, End: 888, FivePrimePartial: true, ThreePrimePartial: true}},
+		{"102.110", poly.Location{Start: 101, End: 110, SingleBase: true}},
+		{"123^124", poly.Location{Start: 123, End: 123, Between: true}},
+		{"5386^1", poly.Location{Start: 5386, End: 0, Between: true}},
+		{"J00194.1:100..202", poly.Location{Start: 99, End: 202, Accession: "J00194.1"}},
+		{"complement(34..126)", poly.Location{Start: 33, End: 126, Complement: true}},
+		{"complement(J00194.1:100..202)", poly.Location{Start: 99, End: 202, Accession: "J00194.1", Complement: true}},
+		{"join(12..78,134..202)", poly.Location{Join: true, SubLocations: []poly.Location{{Start: 11, End: 78}, {Start: 133, End: 202}}}},
+		{"order(1..10,20..30)", poly.Location{Order: true, SubLocations: []poly.Location{{Start: 0, End: 10}, {Start: 19, End: 30}}}},
+		{"bond(12,345)", poly.Location{Bond: true, SubLocations: []poly.Location{{Start: 11, End: 12}, {Start: 344, End: 345}}}},
+		{"complement(join(2691..4571,4918..5163,5301..5420))", poly.Location{Join: true, Complement: true, SubLocations: []poly.Location{{Start: 2690, End: 4571}, {Start: 4917, End: 5163}, {Start: 5300, End: 5420}}}},
+		{"join(complement(4918..5163),complement(2691..4571))", poly.Location{Join: true, SubLocations: []poly.Location{{Start: 4917, End: 5163, Complement: true}, {Start: 2690, End: 4571, Complement: true}}}},
+		{"join(1..10,J00194.1:100..202,complement(order(30..40,50..60)))", poly.Location{Join: true, SubLocations: []poly.Location{
+			{Start: 0, End: 10},
+			{Start: 99, End: 202, Accession: "J00194.1"},
+			{Order: true, Complement: true, SubLocations: []poly.Location{{Start: 29, End: 40}, {Start: 49, End: 60}}},
+		}}},
+		{"join(<459260..459456,459556..>460126)", poly.Location{Join: true, FivePrimePartial: true, ThreePrimePartial: true, SubLocations: []poly.Location{{Start: 459259, End: 459456, FivePrimePartial: true}, {Start: 459555, End: 460126, ThreePrimePartial: true}}}},
+	}
+
+	for _, test := range tests {
+		location, err := ParseLocation(test.locationString)
+		if err != nil {
+			t.Errorf("ParseLocation(%q) returned an error: %s", test.locationString, err)
+			continue
+		}
+		if diff := cmp.Diff(test.location, location); diff != "" {
+			t.Errorf("ParseLocation(%q) got this diff:\n%s", test.locationString, diff)
+		}
+		if builtString := BuildLocationString(location); builtString != test.locationString {
+			t.Errorf("BuildLocationString does not round trip %q. Got: %q", test.locationString, builtString)
+		}
+	}
+}
+
+func TestParseLocationLegacyPartial(t *testing.T) {
+	// some software writes partial ends after the position.
+	location, err := ParseLocation("687..3158>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Start != 686 || location.End != 3158 || !location.ThreePrimePartial {
+		t.Errorf("Trailing partial marker was not parsed. Got: %+v", location)
+	}
+}
+
+func TestParseLocationErrors(t *testing.T) {
+	badLocations := []string{
+		"",
+		"join(1..10,20..30",
+		"join(1..10;20..30)",
+		"complement(1..10,20..30)",
+		"1..",
+		"0..10",
+		"joint(1..10)",
+		"1..10)",
+	}
+	for _, badLocation := range badLocations {
+		if location, err := ParseLocation(badLocation); err == nil {
+			t.Errorf("ParseLocation(%q) should have returned an error. Got: %+v", badLocation, location)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package gff
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"regexp"
@@ -10,197 +12,579 @@ import (
 	"strings"
 
 	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/flatfile"
 )
 
+/******************************************************************************
+
+GFF3 specific IO related things begin here.
+
+A GFF3 file is a list of tab separated feature lines that can describe any
+number of sequences, each named by the seqid in the first column:
+
+	##gff-version 3
+	##sequence-region chr1 1 1000
+	##sequence-region chr2 1 500
+	chr1	poly	gene	10	90	.	+	.	ID=gene1;Name=abcA
+	chr2	poly	gene	20	60	.	-	.	ID=gene2;Note=a%3B b
+	###
+	##FASTA
+	>chr1
+	ATG...
+	>chr2
+	ATG...
+
+ParseMulti returns one poly.Sequence per seqid in the order they first show
+up in the file. Parse is for the common case of files with a single seqid.
+
+The ninth column holds attributes like ID=gene1;Dbxref=a,b where commas
+separate values. Characters that mean something in a column are percent
+encoded so Note=a%3B b holds "a; b". Attributes are decoded when parsing and
+encoded again when building.
+
+Features that are split over several lines, like a CDS broken up by introns,
+share an ID. Each line stays its own poly.Feature and poly.Hierarchy ties
+them back together. Build writes joined locations from other formats the
+same way, making up an ID for features that don't have one.
+
+The full spec lives here:
+
+https://github.com/The-Sequence-Ontology/Specifications/blob/master/gff3.md
+
+******************************************************************************/
+
+// Problems found while parsing a gff file. Each is wrapped in a ParseError and can be matched with errors.Is.
+var (
+	ErrMalformedDirective  = errors.New("malformed directive")
+	ErrInvalidColumns      = errors.New("invalid feature columns")
+	ErrInvalidCoordinates  = errors.New("invalid feature coordinates")
+	ErrInvalidAttribute    = errors.New("invalid attribute")
+	ErrInvalidEscape       = errors.New("invalid percent encoding")
+	ErrInvalidSequence     = errors.New("invalid FASTA section")
+	ErrInconsistentFeature = errors.New("discontinuous feature parts disagree")
+)
+
+// ParseError is a problem with a specific line of a gff file. Directives, feature lines and the ##FASTA
+// section all count towards its line number.
+type ParseError = flatfile.ParseError
+
 // Parse Takes in a string representing a gffv3 file and parses it into an Sequence object.
-func Parse(file []byte) poly.Sequence {
+// Only the first seqid in the file is returned so use ParseMulti for files that describe several sequences.
+// The first problem found in the file is returned as a *ParseError.
+func Parse(file []byte) (poly.Sequence, error) {
+	sequences, err := ParseMulti(file)
+	if err != nil || len(sequences) == 0 {
+		return poly.Sequence{}, err
+	}
+	return sequences[0], nil
+}
 
-	gff := string(file)
-	sequence := poly.Sequence{}
+// ParseMulti parses a gffv3 file into one Sequence per seqid in the order they first appear in the file.
+// The first problem found in the file is returned as a *ParseError.
+func ParseMulti(file []byte) ([]poly.Sequence, error) {
+	sequences, parseErrors := ParseLenient(file)
+	if len(parseErrors) > 0 {
+		return nil, parseErrors[0]
+	}
+	return sequences, nil
+}
 
-	lines := strings.Split(gff, "\n")
-	metaString := lines[0:2]
-	versionString := metaString[0]
-	regionStringArray := strings.Split(metaString[1], " ")
+// ParseLenient parses a gffv3 file like ParseMulti but carries on past problems, returning them
+// in the order they appear in the file along with whatever could be parsed.
+func ParseLenient(file []byte) ([]poly.Sequence, []*ParseError) {
+	var parseErrors []*ParseError
+	addError := func(lineNumber int, text string, err error) {
+		parseErrors = append(parseErrors, &ParseError{Line: lineNumber, Text: text, Err: err})
+	}
 
-	meta := poly.Meta{}
-	meta.GffVersion = strings.Split(versionString, " ")[1]
-	meta.Name = regionStringArray[1] // Formally region name, but changed to name here for generality/interoperability.
-	meta.RegionStart, _ = strconv.Atoi(regionStringArray[2])
-	meta.RegionEnd, _ = strconv.Atoi(regionStringArray[3])
-	meta.Size = meta.RegionEnd - meta.RegionStart
+	var version string
+	var sequences []*poly.Sequence
+	sequencesBySeqid := make(map[string]*poly.Sequence)
+	// getSequence returns the sequence for a seqid, adding it the first time the seqid shows up.
+	getSequence := func(seqid string) *poly.Sequence {
+		sequence, ok := sequencesBySeqid[seqid]
+		if !ok {
+			sequence = &poly.Sequence{Meta: poly.Meta{Name: seqid}}
+			sequencesBySeqid[seqid] = sequence
+			sequences = append(sequences, sequence)
+		}
+		return sequence
+	}
 
-	var sequenceBuffer bytes.Buffer
+	type featurePart struct {
+		seqid, featureType string
+		lineNumber         int
+	}
+	featureParts := make(map[string]featurePart)
+
+	var fastaSequence *poly.Sequence
+	fastaBuffers := make(map[*poly.Sequence]*bytes.Buffer)
 	fastaFlag := false
-	for _, line := range lines {
-		if line == "##FASTA" {
-			fastaFlag = true
-		} else if len(line) == 0 {
+	versionFound := false
+
+	lines := strings.Split(string(file), "\n")
+	for lineIndex, line := range lines {
+		lineNumber := lineIndex + 1
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
 			continue
-		} else if line[0:2] == "##" {
+		}
+
+		if !versionFound && !strings.HasPrefix(line, "##gff-version") {
+			addError(lineNumber, line, fmt.Errorf("%w: file does not start with ##gff-version", ErrMalformedDirective))
+		}
+		versionFound = true
+
+		switch {
+		case line == "##FASTA":
+			fastaFlag = true
+		case fastaFlag && strings.HasPrefix(line, ">"):
+			seqid, err := unescape(strings.Fields(line[1:] + " ")[0])
+			if err != nil {
+				addError(lineNumber, line, err)
+			}
+			fastaSequence = getSequence(seqid)
+			if _, ok := fastaBuffers[fastaSequence]; ok {
+				addError(lineNumber, line, fmt.Errorf("%w: %s has more than one FASTA entry", ErrInvalidSequence, seqid))
+			}
+			fastaBuffers[fastaSequence] = &bytes.Buffer{}
+			fastaSequence.Description = line
+		case fastaFlag:
+			if fastaSequence == nil {
+				addError(lineNumber, line, fmt.Errorf("%w: sequence before the first > header", ErrInvalidSequence))
+				continue
+			}
+			fastaBuffers[fastaSequence].WriteString(strings.TrimSpace(line))
+		case strings.HasPrefix(line, "##gff-version"):
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				addError(lineNumber, line, ErrMalformedDirective)
+				continue
+			}
+			version = fields[1]
+		case strings.HasPrefix(line, "##sequence-region"):
+			fields := strings.Fields(line)
+			if len(fields) != 4 {
+				addError(lineNumber, line, fmt.Errorf("%w: expected a seqid, start and end", ErrMalformedDirective))
+				continue
+			}
+			seqid, err := unescape(fields[1])
+			if err != nil {
+				addError(lineNumber, line, err)
+			}
+			regionStart, startErr := strconv.Atoi(fields[2])
+			regionEnd, endErr := strconv.Atoi(fields[3])
+			if startErr != nil || endErr != nil || regionStart > regionEnd {
+				addError(lineNumber, line, fmt.Errorf("%w: invalid region coordinates", ErrMalformedDirective))
+				continue
+			}
+			sequence := getSequence(seqid)
+			sequence.Meta.RegionStart = regionStart
+			sequence.Meta.RegionEnd = regionEnd
+			sequence.Meta.Size = regionEnd - regionStart
+		case strings.HasPrefix(line, "#"):
+			// ### only says every forward reference so far has been resolved and other directives and comments carry nothing we keep.
 			continue
-		} else if fastaFlag && line[0:1] != ">" {
-			// sequence.Sequence = sequence.Sequence + line
-			sequenceBuffer.WriteString(line)
-		} else if fastaFlag && line[0:1] == ">" {
-			sequence.Description = line
-		} else {
-			record := poly.Feature{}
-			fields := strings.Split(line, "\t")
-			record.Name = fields[0]
-			record.Source = fields[1]
-			record.Type = fields[2]
-
-			// Indexing starts at 1 for gff so we need to shift down for Sequence 0 index.
-			record.SequenceLocation.Start, _ = strconv.Atoi(fields[3])
-			record.SequenceLocation.Start--
-			record.SequenceLocation.End, _ = strconv.Atoi(fields[4])
-
-			record.Score = fields[5]
-			record.Strand = fields[6]
-			record.Phase = fields[7]
-			record.Attributes = make(map[string]string)
-			attributes := fields[8]
-			// var eqIndex int
-			attributeSlice := strings.Split(attributes, ";")
-
-			for _, attribute := range attributeSlice {
-				attributeSplit := strings.Split(attribute, "=")
-				key := attributeSplit[0]
-				value := attributeSplit[1]
-				record.Attributes[key] = value
+		default:
+			record, recordErrors := parseFeature(line)
+			for _, err := range recordErrors {
+				addError(lineNumber, line, err)
+			}
+			if record.Type == "" {
+				continue
+			}
+
+			// lines of a discontinuous feature share an ID and have to describe the same kind of feature on the same sequence.
+			if id := record.Attributes.Get("ID"); id != "" {
+				part, ok := featureParts[id]
+				if ok && (part.seqid != record.Name || part.featureType != record.Type) {
+					addError(lineNumber, line, fmt.Errorf("%w: ID %s is a %s on %s on line %d", ErrInconsistentFeature, id, part.featureType, part.seqid, part.lineNumber))
+				} else if !ok {
+					featureParts[id] = featurePart{seqid: record.Name, featureType: record.Type, lineNumber: lineNumber}
+				}
+			}
+
+			sequence := getSequence(record.Name)
+			// GFF3 flags circular sequences with an Is_circular attribute on their region feature.
+			if record.Attributes.Get("Is_circular") == "true" {
+				sequence.Meta.Locus.Circular = true
 			}
 			sequence.AddFeature(&record)
 		}
 	}
-	sequence.Sequence = sequenceBuffer.String()
-	sequence.Meta = meta
 
-	return sequence
-}
+	parsedSequences := make([]poly.Sequence, len(sequences))
+	for sequenceIndex, sequence := range sequences {
+		sequence.Meta.GffVersion = version
+		if buffer, ok := fastaBuffers[sequence]; ok {
+			sequence.Sequence = buffer.String()
+		}
 
-// Build takes an Annotated sequence and returns a byte array representing a gff to be written out.
-func Build(sequence poly.Sequence) []byte {
-	var gffBuffer bytes.Buffer
+		// GFF3 writes features spanning the origin with an end past the length of the sequence.
+		// poly models those as a location with an end before its start.
+		if sequence.Meta.Locus.Circular {
+			sequenceLength := len(sequence.Sequence)
+			if sequenceLength == 0 && sequence.Meta.RegionEnd != 0 {
+				sequenceLength = sequence.Meta.RegionEnd - sequence.Meta.RegionStart + 1
+			}
+			// without a ##FASTA section or a ##sequence-region there's no telling which features run past the end.
+			for featureIndex := range sequence.Features {
+				location := &sequence.Features[featureIndex].SequenceLocation
+				if sequenceLength != 0 && location.End > sequenceLength {
+					location.End -= sequenceLength
+				}
+			}
+		}
 
-	var versionString string
-	if sequence.Meta.GffVersion != "" {
-		versionString = "##gff-version " + sequence.Meta.GffVersion + "\n"
-	} else {
-		versionString = "##gff-version 3 \n"
+		// features point back at the sequence they belong to so they have to be added again to the copy returned.
+		features := sequence.Features
+		parsedSequences[sequenceIndex] = *sequence
+		parsedSequences[sequenceIndex].Features = nil
+		for _, feature := range features {
+			parsedSequences[sequenceIndex].AddFeature(&feature)
+		}
 	}
-	gffBuffer.WriteString(versionString)
+	return parsedSequences, parseErrors
+}
 
-	var regionString string
-	var name string
-	var start string
-	var end string
-
-	if sequence.Meta.Name != "" {
-		name = sequence.Meta.Name
-	} else if sequence.Meta.Locus.Name != "" {
-		name = sequence.Meta.Locus.Name
-	} else if sequence.Meta.Accession != "" {
-		name = sequence.Meta.Accession
-	} else {
-		name = "unknown"
-	}
+// parseFeature parses a single feature line. A feature without a Type is returned if the line can't be used at all.
+func parseFeature(line string) (poly.Feature, []error) {
+	record := poly.Feature{}
+	var errs []error
 
-	if sequence.Meta.RegionStart != 0 {
-		start = strconv.Itoa(sequence.Meta.RegionStart)
-	} else {
-		start = "1"
+	fields := strings.Split(line, "\t")
+	if len(fields) != 9 {
+		return record, []error{fmt.Errorf("%w: expected 9 tab separated columns but got %d", ErrInvalidColumns, len(fields))}
 	}
 
-	if sequence.Meta.RegionEnd != 0 {
-		end = strconv.Itoa(sequence.Meta.RegionEnd)
-	} else if sequence.Meta.Locus.SequenceLength != "" {
-		reg, err := regexp.Compile("[^0-9]+")
+	for fieldIndex := 0; fieldIndex < 8; fieldIndex++ {
+		field, err := unescape(fields[fieldIndex])
 		if err != nil {
-			log.Fatal(err)
+			errs = append(errs, err)
 		}
-		end = reg.ReplaceAllString(sequence.Meta.Locus.SequenceLength, "")
-	} else {
-		end = "1"
+		fields[fieldIndex] = field
 	}
+	record.Name = fields[0]
+	record.Source = fields[1]
+	record.Type = fields[2]
 
-	regionString = "##sequence-region " + name + " " + start + " " + end + "\n"
-	gffBuffer.WriteString(regionString)
+	// Indexing starts at 1 for gff so we need to shift down for Sequence 0 index.
+	start, startErr := strconv.Atoi(fields[3])
+	end, endErr := strconv.Atoi(fields[4])
+	if startErr != nil || endErr != nil || start < 1 || start > end {
+		errs = append(errs, fmt.Errorf("%w: start %s and end %s", ErrInvalidCoordinates, fields[3], fields[4]))
+	}
+	record.SequenceLocation.Start = start - 1
+	record.SequenceLocation.End = end
 
-	for _, feature := range sequence.Features {
-		var featureString string
+	record.Score = fields[5]
+	record.Strand = fields[6]
+	record.Phase = fields[7]
+	if !strings.Contains("+-.?", record.Strand) || len(record.Strand) != 1 {
+		errs = append(errs, fmt.Errorf("%w: strand must be one of + - . or ? but got %q", ErrInvalidColumns, record.Strand))
+	}
+	if !strings.Contains("012.", record.Phase) || len(record.Phase) != 1 {
+		errs = append(errs, fmt.Errorf("%w: phase must be 0, 1, 2 or . but got %q", ErrInvalidColumns, record.Phase))
+	}
 
-		var featureName string
-		if feature.Name != "" {
-			featureName = feature.Name
-		} else {
-			featureName = sequence.Meta.Locus.Name
+	record.Attributes = make(poly.Attributes)
+	if fields[8] == "." {
+		return record, errs
+	}
+	for _, attribute := range strings.Split(fields[8], ";") {
+		if strings.TrimSpace(attribute) == "" {
+			continue
+		}
+		attributeSplit := strings.SplitN(attribute, "=", 2)
+		key, err := unescape(attributeSplit[0])
+		if err != nil {
+			errs = append(errs, err)
 		}
+		if len(attributeSplit) != 2 || key == "" {
+			errs = append(errs, fmt.Errorf("%w: %q is not a tag=value pair", ErrInvalidAttribute, attribute))
+			if key != "" {
+				record.Attributes.Add(key, "")
+			}
+			continue
+		}
+		// gff attributes can have multiple values separated by commas.
+		for _, value := range strings.Split(attributeSplit[1], ",") {
+			value, err := unescape(value)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			record.Attributes.Add(key, value)
+		}
+	}
+	return record, errs
+}
 
-		var featureSource string
-		if feature.Source != "" {
-			featureSource = feature.Source
-		} else {
-			featureSource = "feature"
+// unescape decodes the percent encoded characters in a column. Broken escapes are left as they are.
+func unescape(text string) (string, error) {
+	if !strings.Contains(text, "%") {
+		return text, nil
+	}
+	var err error
+	var unescaped strings.Builder
+	for index := 0; index < len(text); index++ {
+		if text[index] == '%' {
+			if index+2 < len(text) {
+				if decoded, parseErr := strconv.ParseUint(text[index+1:index+3], 16, 8); parseErr == nil {
+					unescaped.WriteByte(byte(decoded))
+					index += 2
+					continue
+				}
+			}
+			err = fmt.Errorf("%w: %q", ErrInvalidEscape, text)
+		}
+		unescaped.WriteByte(text[index])
+	}
+	return unescaped.String(), err
+}
+
+// escape percent encodes every character of text that is in reserved along with % and control characters like tabs and newlines.
+func escape(text, reserved string) string {
+	var escaped strings.Builder
+	for index := 0; index < len(text); index++ {
+		character := text[index]
+		if character == '%' || character < 0x20 || character == 0x7f || strings.IndexByte(reserved, character) != -1 {
+			escaped.WriteString(fmt.Sprintf("%%%02X", character))
+			continue
 		}
+		escaped.WriteByte(character)
+	}
+	return escaped.String()
+}
+
+// seqidRegex matches the characters a seqid can hold without being escaped.
+var seqidRegex = regexp.MustCompile(`^[a-zA-Z0-9.:^*$@!+_?|-]*$`)
 
-		var featureType string
-		if feature.Type != "" {
-			featureType = feature.Type
+// escapeSeqid escapes every character of a seqid that the spec doesn't allow as is.
+func escapeSeqid(seqid string) string {
+	if seqidRegex.MatchString(seqid) {
+		return seqid
+	}
+	var escaped strings.Builder
+	for index := 0; index < len(seqid); index++ {
+		if character := seqid[index : index+1]; seqidRegex.MatchString(character) {
+			escaped.WriteString(character)
 		} else {
-			featureType = "unknown"
+			escaped.WriteString(fmt.Sprintf("%%%02X", seqid[index]))
 		}
+	}
+	return escaped.String()
+}
 
-		// Indexing starts at 1 for gff so we need to shift up from Sequence 0 index.
-		featureStart := strconv.Itoa(feature.SequenceLocation.Start + 1)
-		featureEnd := strconv.Itoa(feature.SequenceLocation.End)
+// attributeReserved holds the characters that have to be escaped in attribute tags and values. Parentheses don't
+// have to be but are anyway so files from tools that escape them, like the ones in data, round trip unchanged.
+const attributeReserved = ";=&,()"
+
+// Build takes an Annotated sequence and returns a byte array representing a gff to be written out.
+func Build(sequence poly.Sequence) []byte {
+	return BuildMulti([]poly.Sequence{sequence})
+}
 
-		featureScore := feature.Score
-		featureStrand := string(feature.Strand)
-		featurePhase := feature.Phase
-		var featureAttributes string
+// BuildMulti builds a single gff out of several sequences. Each sequence gets its own ##sequence-region and FASTA entry.
+func BuildMulti(sequences []poly.Sequence) []byte {
+	var gffBuffer bytes.Buffer
 
-		keys := make([]string, 0, len(feature.Attributes))
-		for key := range feature.Attributes {
-			keys = append(keys, key)
+	var versionString string
+	if len(sequences) > 0 && sequences[0].Meta.GffVersion != "" {
+		versionString = "##gff-version " + sequences[0].Meta.GffVersion + "\n"
+	} else {
+		versionString = "##gff-version 3\n"
+	}
+	gffBuffer.WriteString(versionString)
+
+	for _, sequence := range sequences {
+		var start string
+		var end string
+
+		if sequence.Meta.RegionStart != 0 {
+			start = strconv.Itoa(sequence.Meta.RegionStart)
+		} else {
+			start = "1"
 		}
-		sort.Strings(keys)
 
-		for _, key := range keys {
-			attributeString := key + "=" + feature.Attributes[key] + ";"
-			featureAttributes += attributeString
+		if sequence.Meta.RegionEnd != 0 {
+			end = strconv.Itoa(sequence.Meta.RegionEnd)
+		} else if sequence.Meta.Locus.SequenceLength != "" {
+			reg, err := regexp.Compile("[^0-9]+")
+			if err != nil {
+				log.Fatal(err)
+			}
+			end = reg.ReplaceAllString(sequence.Meta.Locus.SequenceLength, "")
+		} else {
+			end = "1"
 		}
 
-		if len(featureAttributes) > 0 {
-			featureAttributes = featureAttributes[0 : len(featureAttributes)-1]
+		gffBuffer.WriteString("##sequence-region " + escapeSeqid(sequence.DisplayName()) + " " + start + " " + end + "\n")
+	}
+
+	// the lines of a joined feature are tied together by a shared ID so features without one get one made up.
+	usedIDs := make(map[string]bool)
+	for _, sequence := range sequences {
+		for _, feature := range sequence.Features {
+			if id := feature.Attributes.Get("ID"); id != "" {
+				usedIDs[id] = true
+			}
+		}
+	}
+	for _, sequence := range sequences {
+		for _, feature := range sequence.Features {
+			if len(feature.SequenceLocation.Ranges()) > 1 && feature.Attributes.Get("ID") == "" {
+				prefix := feature.Type
+				if prefix == "" {
+					prefix = "feature"
+				}
+				id := prefix
+				for number := 1; usedIDs[id]; number++ {
+					id = prefix + "-" + strconv.Itoa(number)
+				}
+				usedIDs[id] = true
+				attributes := poly.Attributes{"ID": {id}}
+				for key, values := range feature.Attributes {
+					attributes[key] = values
+				}
+				feature.Attributes = attributes
+			}
+			gffBuffer.WriteString(buildFeatureString(sequence, feature))
 		}
-		TAB := "\t"
-		featureString = featureName + TAB + featureSource + TAB + featureType + TAB + featureStart + TAB + featureEnd + TAB + featureScore + TAB + featureStrand + TAB + featurePhase + TAB + featureAttributes + "\n"
-		gffBuffer.WriteString(featureString)
 	}
 
 	gffBuffer.WriteString("###\n")
 	gffBuffer.WriteString("##FASTA\n")
-	gffBuffer.WriteString(">" + sequence.Meta.Name + "\n")
-
-	for letterIndex, letter := range sequence.Sequence {
-		letterIndex++
-		if letterIndex%70 == 0 && letterIndex != 0 && letterIndex != sequence.Meta.RegionEnd {
-			gffBuffer.WriteRune(letter)
+	for _, sequence := range sequences {
+		gffBuffer.WriteString(">" + escapeSeqid(sequence.DisplayName()) + "\n")
+		for lineStart := 0; lineStart < len(sequence.Sequence); lineStart += 70 {
+			lineEnd := lineStart + 70
+			if lineEnd > len(sequence.Sequence) {
+				lineEnd = len(sequence.Sequence)
+			}
+			gffBuffer.WriteString(sequence.Sequence[lineStart:lineEnd] + "\n")
+		}
+		if sequence.Sequence == "" {
 			gffBuffer.WriteString("\n")
-		} else {
-			gffBuffer.WriteRune(letter)
 		}
 	}
-	gffBuffer.WriteString("\n")
 	return gffBuffer.Bytes()
 }
 
+// buildFeatureString builds the lines of a single feature.
+func buildFeatureString(sequence poly.Sequence, feature poly.Feature) string {
+	var featureName string
+	if feature.Name != "" {
+		featureName = feature.Name
+	} else {
+		featureName = sequence.DisplayName()
+	}
+
+	var featureSource string
+	if feature.Source != "" {
+		featureSource = feature.Source
+	} else {
+		featureSource = "feature"
+	}
+
+	var featureType string
+	if feature.Type != "" {
+		featureType = feature.Type
+	} else {
+		featureType = "unknown"
+	}
+
+	// features from other formats don't have these columns filled in.
+	featureScore := feature.Score
+	if featureScore == "" {
+		featureScore = "."
+	}
+	featureStrand := feature.Strand
+	if featureStrand == "" {
+		featureStrand = feature.SequenceLocation.Strand()
+	}
+	// every part of a CDS needs a phase, which for features from genbank comes from their /codon_start.
+	parts := feature.SequenceLocation.Ranges()
+	phases := make([]string, len(parts))
+	for partIndex := range parts {
+		phases[partIndex] = feature.Phase
+		if phases[partIndex] == "" {
+			phases[partIndex] = "."
+		}
+	}
+	if feature.Phase == "" && featureType == "CDS" {
+		phases = feature.SequenceLocation.Phases(feature.Attributes.Get("codon_start"))
+	}
+
+	keys := make([]string, 0, len(feature.Attributes))
+	for key := range feature.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := make([]string, len(feature.Attributes[key]))
+		for valueIndex, value := range feature.Attributes[key] {
+			values[valueIndex] = escape(value, attributeReserved)
+		}
+		attributes = append(attributes, escape(key, attributeReserved)+"="+strings.Join(values, ","))
+	}
+	featureAttributes := strings.Join(attributes, ";")
+	if featureAttributes == "" {
+		featureAttributes = "."
+	}
+
+	// joins like genbank's spliced features get a line per part, all sharing the feature's ID.
+	var featureString strings.Builder
+	for partIndex, part := range parts {
+		// Indexing starts at 1 for gff so we need to shift up from Sequence 0 index.
+		featureStart := strconv.Itoa(part.Start + 1)
+		featureEnd := strconv.Itoa(part.End)
+		if part.SpansOrigin() {
+			featureEnd = strconv.Itoa(part.End + len(sequence.Sequence))
+		}
+		TAB := "\t"
+		featureString.WriteString(escapeSeqid(featureName) + TAB + escape(featureSource, "") + TAB + escape(featureType, "") + TAB + featureStart + TAB + featureEnd + TAB + featureScore + TAB + featureStrand + TAB + phases[partIndex] + TAB + featureAttributes + "\n")
+	}
+	return featureString.String()
+}
+
 // Read takes in a filepath for a .gffv3 file and parses it into an Annotated poly.Sequence struct.
-func Read(path string) poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	sequence := Parse(file)
-	return sequence
+// Only the first seqid in the file is returned so use ReadMulti for files that describe several sequences.
+func Read(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return Parse(file)
+}
+
+// ReadMulti reads a .gffv3 file from path into one Sequence per seqid.
+func ReadMulti(path string) ([]poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMulti(file)
+}
+
+// ParseStrict parses a gffv3 file and validates the result. Feature coordinates past the end of the
+// FASTA section, broken Parent links and the like come back as a *poly.ValidationError.
+func ParseStrict(file []byte) (poly.Sequence, error) {
+	sequence, err := Parse(file)
+	if err != nil {
+		return sequence, err
+	}
+	return sequence, sequence.ValidateStrict()
+}
+
+// ReadStrict reads a .gffv3 file from path with ParseStrict.
+func ReadStrict(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return ParseStrict(file)
 }
 
 // Write takes an poly.Sequence struct and a path string and writes out a gff to that path.
@@ -208,3 +592,14 @@ func Write(sequence poly.Sequence, path string) {
 	gff := Build(sequence)
 	_ = ioutil.WriteFile(path, gff, 0644)
 }
+
+// WriteMulti writes several sequences out to a single gff at path.
+func WriteMulti(sequences []poly.Sequence, path string) {
+	_ = ioutil.WriteFile(path, BuildMulti(sequences), 0644)
+}
+
+/******************************************************************************
+
+GFF3 specific IO related things end here.
+
+******************************************************************************/
@@ -1,13 +1,17 @@
 package gff
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/genbank"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/pmezard/go-difflib/difflib"
@@ -21,14 +25,14 @@ Gff related tests and benchmarks begin here.
 
 func ExampleRead() {
 
-	sequence := Read("../../data/ecoli-mg1655-short.gff")
+	sequence, _ := Read("../../data/ecoli-mg1655-short.gff")
 	fmt.Println(sequence.Meta.Name)
 	// Output: U00096.3
 }
 
 func ExampleParse() {
 	file, _ := ioutil.ReadFile("../../data/ecoli-mg1655-short.gff")
-	sequence := Parse(file)
+	sequence, _ := Parse(file)
 
 	fmt.Println(sequence.Meta.Name)
 	// Output: U00096.3
@@ -36,9 +40,9 @@ func ExampleParse() {
 
 func ExampleBuild() {
 
-	sequence := Read("../../data/ecoli-mg1655-short.gff")
+	sequence, _ := Read("../../data/ecoli-mg1655-short.gff")
 	gffBytes := Build(sequence)
-	reparsedSequence := Parse(gffBytes)
+	reparsedSequence, _ := Parse(gffBytes)
 
 	fmt.Println(reparsedSequence.Meta.Name)
 	// Output: U00096.3
@@ -52,12 +56,12 @@ func ExampleWrite() {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	sequence := Read("../../data/ecoli-mg1655-short.gff")
+	sequence, _ := Read("../../data/ecoli-mg1655-short.gff")
 
 	tmpGffFilePath := filepath.Join(tmpDataDir, "ecoli-mg1655-short.gff")
 	Write(sequence, tmpGffFilePath)
 
-	testSequence := Read(tmpGffFilePath)
+	testSequence, _ := Read(tmpGffFilePath)
 
 	fmt.Println(testSequence.Meta.Name)
 	// Output: U00096.3
@@ -75,10 +79,16 @@ func TestGffIO(t *testing.T) {
 	testInputPath := "../../data/ecoli-mg1655-short.gff"
 	tmpGffFilePath := filepath.Join(tmpDataDir, "ecoli-mg1655-short.gff")
 
-	testSequence := Read(testInputPath)
+	testSequence, err := Read(testInputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
 	Write(testSequence, tmpGffFilePath)
 
-	readTestSequence := Read(tmpGffFilePath)
+	readTestSequence, err := Read(tmpGffFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if diff := cmp.Diff(testSequence, readTestSequence, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
 		t.Errorf("Parsing the output of Build() does not produce the same output as parsing the original file read with ReadGff(). Got this diff:\n%s", diff)
@@ -102,9 +112,87 @@ func TestGffIO(t *testing.T) {
 
 }
 
+func TestGenbankRoundTrip(t *testing.T) {
+	// genbank features have no score, strand or phase columns of their own.
+	for _, path := range []string{"puc19.gbk", "sample.gbk", "t4_intron.gb", "phix174.gb", "puc19_snapgene.gb", "matches.gbk"} {
+		sequence, err := genbank.Read("../../data/" + path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		gff := Build(sequence)
+		parsed, err := Parse(gff)
+		if err != nil {
+			t.Fatalf("%s: the gff built from it doesn't parse: %s", path, err)
+		}
+		if rebuilt := Build(parsed); string(rebuilt) != string(gff) {
+			t.Errorf("%s: building the parsed gff again does not produce the same file", path)
+		}
+	}
+
+	// joins are written a line per part and the parts share an ID.
+	sequence, _ := genbank.Read("../../data/t4_intron.gb")
+	parsed, _ := Parse(Build(sequence))
+	type part struct {
+		Start, End    int
+		Strand, Phase string
+	}
+	var expected []part
+	for _, feature := range sequence.Features {
+		phases := feature.SequenceLocation.Phases(feature.Attributes.Get("codon_start"))
+		for partIndex, location := range feature.SequenceLocation.Ranges() {
+			phase := "."
+			if feature.Type == "CDS" {
+				phase = phases[partIndex]
+			}
+			expected = append(expected, part{Start: location.Start, End: location.End, Strand: feature.SequenceLocation.Strand(), Phase: phase})
+		}
+	}
+	var got []part
+	hierarchy := parsed.Hierarchy()
+	featureCount := 0
+	for featureIndex, feature := range parsed.Features {
+		got = append(got, part{Start: feature.SequenceLocation.Start, End: feature.SequenceLocation.End, Strand: feature.Strand, Phase: feature.Phase})
+		if feature.Score != "." {
+			t.Errorf("Expected an empty score to be written as a dot. Got %q", feature.Score)
+		}
+		if parts := hierarchy.Lookup(feature.Attributes.Get("ID")); len(parts) == 0 || parts[0] == featureIndex {
+			featureCount++
+		}
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("Locations, strands and phases did not survive the round trip (-want +got):\n%s", diff)
+	}
+	if featureCount != len(sequence.Features) {
+		t.Errorf("Expected %d features after the round trip. Got %d", len(sequence.Features), featureCount)
+	}
+
+	// and back to genbank again.
+	regbk, err := genbank.Parse(genbank.Build(parsed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regbk.Features) != len(sequence.Features) {
+		t.Fatalf("Expected %d features back in genbank. Got %d", len(sequence.Features), len(regbk.Features))
+	}
+	// gff doesn't say what order the parts of a feature are read in so only compare what they cover.
+	sortedParts := func(location poly.Location) []part {
+		var parts []part
+		for _, location := range location.Ranges() {
+			parts = append(parts, part{Start: location.Start, End: location.End, Strand: location.Strand()})
+		}
+		sort.Slice(parts, func(i, j int) bool { return parts[i].Start < parts[j].Start })
+		return parts
+	}
+	for featureIndex, feature := range sequence.Features {
+		if diff := cmp.Diff(sortedParts(feature.SequenceLocation), sortedParts(regbk.Features[featureIndex].SequenceLocation)); diff != "" {
+			t.Errorf("Feature %d changed location going through gff (-want +got):\n%s", featureIndex, diff)
+		}
+	}
+}
+
 func BenchmarkReadGff(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		Read("../../data/ecoli-mg1655-short.gff")
+		_, _ = Read("../../data/ecoli-mg1655-short.gff")
 	}
 }
 
@@ -119,3 +207,229 @@ func BenchmarkReadGff10000(b *testing.B) { BenchmarkReadGff(b) }
 Gff related tests and benchmarks end here.
 
 ******************************************************************************/
+
+func TestOriginSpanningFeatures(t *testing.T) {
+	circularGff := "##gff-version 3\n" +
+		"##sequence-region plasmid 1 10\n" +
+		"plasmid\tfeature\tregion\t1\t10\t.\t+\t.\tID=plasmid;Is_circular=true\n" +
+		"plasmid\tfeature\tCDS\t9\t13\t.\t+\t.\tID=spanning\n" +
+		"###\n" +
+		"##FASTA\n" +
+		">plasmid\n" +
+		"GGGAAAAACC\n"
+
+	sequence, err := Parse([]byte(circularGff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sequence.Meta.Locus.Circular {
+		t.Errorf("Is_circular region attribute was not parsed.")
+	}
+
+	location := sequence.Features[1].SequenceLocation
+	if location.Start != 8 || location.End != 3 || !location.SpansOrigin() {
+		t.Errorf("Origin spanning feature was not parsed into a single range. Got: %+v", location)
+	}
+	if featureSequence := sequence.Features[1].GetSequence(); featureSequence != "CCGGG" {
+		t.Errorf("Origin spanning feature sequence is wrong. Got: %s", featureSequence)
+	}
+
+	reparsed, _ := Parse(Build(sequence))
+	if diff := cmp.Diff(sequence.Features[1].SequenceLocation, reparsed.Features[1].SequenceLocation); diff != "" {
+		t.Errorf("Origin spanning feature does not round trip. Got this diff:\n%s", diff)
+	}
+}
+
+func TestCircularWithoutLength(t *testing.T) {
+	// nothing says how long plasmid is so no feature can be taken to run across the origin.
+	circularGff := "##gff-version 3\n" +
+		"plasmid\tfeature\tregion\t1\t100\t.\t+\t.\tID=plasmid;Is_circular=true\n" +
+		"plasmid\tfeature\tgene\t10\t20\t.\t+\t.\tID=gene1\n"
+
+	sequence, err := Parse([]byte(circularGff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sequence.Meta.Locus.Circular {
+		t.Errorf("Is_circular region attribute was not parsed.")
+	}
+	expected := []poly.Location{{Start: 0, End: 100}, {Start: 9, End: 20}}
+	for featureIndex, feature := range sequence.Features {
+		if diff := cmp.Diff(expected[featureIndex], feature.SequenceLocation); diff != "" {
+			t.Errorf("Feature %d should keep its end (-want +got):\n%s", featureIndex, diff)
+		}
+	}
+}
+
+func TestMultipleAttributeValues(t *testing.T) {
+	sequence, _ := Read("../../data/ecoli-mg1655-short.gff")
+
+	// the first CDS has four comma separated db_xref values.
+	cds := sequence.Features[1]
+	dbXrefs := []string{"GI:1786182", "ASAP:ABE-0000006", "UniProtKB/Swiss-Prot:P0AD86", "EcoGene:EG11277"}
+	if diff := cmp.Diff(dbXrefs, cds.Attributes["db_xref"]); diff != "" {
+		t.Errorf("Comma separated attribute values were not split. Got this diff:\n%s", diff)
+	}
+	if cds.Attributes.Get("gene") != "thrL" {
+		t.Errorf("Single attribute value was not parsed. Got: %s", cds.Attributes.Get("gene"))
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	if _, err := ReadStrict("../../data/ecoli-mg1655-short.gff"); err != nil {
+		t.Errorf("Valid gff failed strict parsing: %s", err)
+	}
+
+	badGff := "##gff-version 3\n" +
+		"##sequence-region chr1 1 10\n" +
+		"chr1\tfeature\tgene\t1\t20\t.\t+\t.\tID=gene1\n" +
+		"###\n" +
+		"##FASTA\n" +
+		">chr1\n" +
+		"GGGAAAAACC\n"
+	sequence, err := ParseStrict([]byte(badGff))
+	validationError, ok := err.(*poly.ValidationError)
+	if !ok {
+		t.Fatalf("Feature past the end of the sequence should fail strict parsing. Got: %v", err)
+	}
+	if len(validationError.Issues) != 1 || validationError.Issues[0].FeatureIndex != 0 {
+		t.Errorf("ValidationError should point at the broken feature. Got: %v", validationError.Issues)
+	}
+	if len(sequence.Features) != 1 {
+		t.Errorf("ParseStrict should still return the parsed sequence.")
+	}
+}
+
+const multiGff = "##gff-version 3\n" +
+	"##sequence-region chr1 1 20\n" +
+	"##sequence-region chr%202 1 10\n" +
+	"# a comment line\n" +
+	"chr1\tpoly\tgene\t1\t20\t.\t+\t.\tID=gene1;Name=abcA;Note=first%3B of two,second%2C with a comma\n" +
+	"chr1\tpoly\tCDS\t1\t6\t.\t+\t0\tID=cds1;Parent=gene1\n" +
+	"chr1\tpoly\tCDS\t13\t18\t.\t+\t0\tID=cds1;Parent=gene1\n" +
+	"###\n" +
+	"chr%202\tpoly\tmisc_feature\t2\t5\t.\t-\t.\tNote=tab%09and 100%25 done;Dbxref=a:1,b:2\n" +
+	"###\n" +
+	"##FASTA\n" +
+	">chr1\n" +
+	"ATGAAACCCGGGTTTAAATA\n" +
+	">chr%202 second sequence\n" +
+	"GGGGAAAACC\n"
+
+func ExampleParseMulti() {
+	sequences, _ := ParseMulti([]byte(multiGff))
+	for _, sequence := range sequences {
+		fmt.Println(sequence.Meta.Name, len(sequence.Features), sequence.Sequence)
+	}
+	// Output:
+	// chr1 3 ATGAAACCCGGGTTTAAATA
+	// chr 2 1 GGGGAAAACC
+}
+
+func TestParseMulti(t *testing.T) {
+	sequences, err := ParseMulti([]byte(multiGff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != 2 {
+		t.Fatalf("Expected a sequence per seqid. Got %d", len(sequences))
+	}
+	if sequences[1].Meta.RegionEnd != 10 || sequences[1].Features[0].Name != "chr 2" {
+		t.Errorf("Escaped seqid was not matched to its ##sequence-region. Got: %+v", sequences[1].Meta)
+	}
+	if sequences[1].Features[0].ParentSequence.Sequence != "GGGGAAAACC" {
+		t.Errorf("Features should point at the sequence they belong to.")
+	}
+
+	reparsed, err := ParseMulti(BuildMulti(sequences))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sequences, reparsed, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence"), cmpopts.IgnoreFields(poly.Sequence{}, "Description")); diff != "" {
+		t.Errorf("BuildMulti does not round trip (-want +got):\n%s", diff)
+	}
+
+	// Parse keeps working for callers that expect a single sequence.
+	sequence, err := Parse([]byte(multiGff))
+	if err != nil || sequence.Meta.Name != "chr1" {
+		t.Errorf("Parse should return the first seqid. Got %q and %v", sequence.Meta.Name, err)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	sequences, _ := ParseMulti([]byte(multiGff))
+
+	note := sequences[0].Features[0].Attributes["Note"]
+	if diff := cmp.Diff([]string{"first; of two", "second, with a comma"}, note); diff != "" {
+		t.Errorf("Escaped attribute values were not decoded (-want +got):\n%s", diff)
+	}
+	if got := sequences[1].Features[0].Attributes.Get("Note"); got != "tab\tand 100% done" {
+		t.Errorf("Escaped control characters were not decoded. Got: %q", got)
+	}
+
+	built := string(BuildMulti(sequences))
+	for _, expected := range []string{"Note=first%3B of two,second%2C with a comma", "Note=tab%09and 100%25 done", "\nchr%202\tpoly\tmisc_feature"} {
+		if !strings.Contains(built, expected) {
+			t.Errorf("Build did not escape %q. Got:\n%s", expected, built)
+		}
+	}
+
+	sequences[0].Features[0].Attributes["Name"] = []string{"a=b;c&d"}
+	reparsed, _ := ParseMulti(BuildMulti(sequences))
+	if got := reparsed[0].Features[0].Attributes.Get("Name"); got != "a=b;c&d" {
+		t.Errorf("Reserved characters do not round trip. Got: %q", got)
+	}
+}
+
+func TestDiscontinuousFeatures(t *testing.T) {
+	sequence, _ := Parse([]byte(multiGff))
+
+	hierarchy := sequence.Hierarchy()
+	parts := hierarchy.Lookup("cds1")
+	if diff := cmp.Diff([]int{1, 2}, parts); diff != "" {
+		t.Errorf("Lines sharing an ID should be parts of one feature (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1, 2}, hierarchy.Children(0)); diff != "" {
+		t.Errorf("Every part should be a child of the gene (-want +got):\n%s", diff)
+	}
+
+	inconsistent := strings.Replace(multiGff, "poly\tCDS\t13", "poly\texon\t13", 1)
+	_, err := ParseMulti([]byte(inconsistent))
+	var parseError *ParseError
+	if !errors.As(err, &parseError) || !errors.Is(err, ErrInconsistentFeature) || parseError.Line != 7 {
+		t.Errorf("Parts of a discontinuous feature with different types should be an error on line 7. Got: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		err  error
+	}{
+		{"attribute without =", "chr1\tpoly\tgene\t1\t20\t.\t+\t.\tID=gene1;flag", ErrInvalidAttribute},
+		{"missing column", "chr1\tpoly\tgene\t1\t20\t.\t+\tID=gene1", ErrInvalidColumns},
+		{"start after end", "chr1\tpoly\tgene\t20\t1\t.\t+\t.\tID=gene1", ErrInvalidCoordinates},
+		{"bad strand", "chr1\tpoly\tgene\t1\t20\t.\tplus\t.\tID=gene1", ErrInvalidColumns},
+		{"bad escape", "chr1\tpoly\tgene\t1\t20\t.\t+\t.\tNote=100%", ErrInvalidEscape},
+		{"malformed directive", "##sequence-region chr1 1", ErrMalformedDirective},
+	}
+
+	for _, test := range tests {
+		file := "##gff-version 3\n##sequence-region chr1 1 20\n" + test.line + "\n"
+		_, err := Parse([]byte(file))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("%s: expected a *ParseError. Got: %v", test.name, err)
+		}
+		if parseError.Line != 3 || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q on line 3. Got: %s", test.name, test.err, err)
+		}
+	}
+
+	// lenient parsing keeps what it can instead of panicking on bad lines.
+	sequences, parseErrors := ParseLenient([]byte("chr1\tpoly\tgene\t1\t20\t.\t+\t.\tID=gene1;flag\n"))
+	if len(parseErrors) != 2 || len(sequences) != 1 || sequences[0].Features[0].Attributes.Get("ID") != "gene1" {
+		t.Errorf("ParseLenient should report the missing ##gff-version and the bad attribute and keep the feature. Got %v", parseErrors)
+	}
+}
@@ -0,0 +1,466 @@
+package gtf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/flatfile"
+)
+
+/******************************************************************************
+
+GTF specific IO related things begin here.
+
+GTF, also known as GFF2, is what most RNA-seq tools read and write. It has
+the same nine tab separated columns as GFF3 but its attributes look like
+
+	gene_id "ENSG01"; transcript_id "ENST01"; exon_number "1";
+
+and genes, transcripts and exons are tied together by their gene_id and
+transcript_id instead of GFF3's ID and Parent:
+
+	chr1	poly	gene	1	900	.	+	.	gene_id "g1";
+	chr1	poly	transcript	1	900	.	+	.	gene_id "g1"; transcript_id "t1";
+	chr1	poly	exon	1	300	.	+	.	gene_id "g1"; transcript_id "t1";
+	chr1	poly	exon	601	900	.	+	.	gene_id "g1"; transcript_id "t1";
+
+Parse keeps every attribute as it is and adds the ID and Parent attributes
+GFF3 would use so the rest of poly, like poly.Hierarchy and the genbank
+builder, understands the gene model. Build drops them again so a GTF comes
+back out the way it went in.
+
+Quotes and backslashes inside attribute values are escaped with a backslash.
+
+Build can also write sequences that came from GFF3 or genbank. gene_id and
+transcript_id are then worked out from the ID and Parent hierarchy or from
+/locus_tag and /gene, joined locations are written as one line per part and
+the frame of each CDS line is worked out from /codon_start.
+
+GTF lines go through GFF3 unchanged. GenBank has no place for the source and
+score columns so those come back as feature and . after a trip through it.
+
+The spec lives here:
+
+http://mblab.wustl.edu/GTF22.html
+
+******************************************************************************/
+
+// Ways a gtf line can be malformed. ParseError wraps them so errors.Is tells you which one happened.
+var (
+	ErrInvalidColumns     = errors.New("invalid feature columns")
+	ErrInvalidCoordinates = errors.New("invalid feature coordinates")
+	ErrInvalidAttribute   = errors.New("invalid attribute")
+)
+
+// ParseError is a problem with a specific line of a gtf file. Comment lines count towards its line number.
+type ParseError = flatfile.ParseError
+
+// Parse parses a gtf file into a poly.Sequence. Only the first seqname in the file is returned so use ParseMulti
+// for files that describe several sequences. The first problem found in the file is returned as a *ParseError.
+func Parse(file []byte) (poly.Sequence, error) {
+	sequences, err := ParseMulti(file)
+	if err != nil || len(sequences) == 0 {
+		return poly.Sequence{}, err
+	}
+	return sequences[0], nil
+}
+
+// ParseMulti parses a gtf file into one Sequence per seqname in the order they first appear in the file.
+// The first problem found in the file is returned as a *ParseError.
+func ParseMulti(file []byte) ([]poly.Sequence, error) {
+	sequences, parseErrors := ParseLenient(file)
+	if len(parseErrors) > 0 {
+		return nil, parseErrors[0]
+	}
+	return sequences, nil
+}
+
+// ParseLenient parses a gtf file like ParseMulti but carries on past problems, returning them
+// in the order they appear in the file along with whatever could be parsed.
+func ParseLenient(file []byte) ([]poly.Sequence, []*ParseError) {
+	var parseErrors []*ParseError
+	var sequences []*poly.Sequence
+	sequencesBySeqname := make(map[string]*poly.Sequence)
+
+	for lineIndex, line := range strings.Split(string(file), "\n") {
+		line = strings.TrimRight(line, "\r")
+		// lines starting with # are comments or headers like #!genome-build.
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		record, recordErrors := parseFeature(line)
+		for _, err := range recordErrors {
+			parseErrors = append(parseErrors, &ParseError{Line: lineIndex + 1, Text: line, Err: err})
+		}
+		if record.Type == "" {
+			continue
+		}
+
+		sequence, ok := sequencesBySeqname[record.Name]
+		if !ok {
+			sequence = &poly.Sequence{Meta: poly.Meta{Name: record.Name}}
+			sequencesBySeqname[record.Name] = sequence
+			sequences = append(sequences, sequence)
+		}
+		sequence.Features = append(sequence.Features, record)
+	}
+
+	parsedSequences := make([]poly.Sequence, len(sequences))
+	for sequenceIndex, sequence := range sequences {
+		addHierarchy(sequence.Features)
+		parsedSequences[sequenceIndex].Meta = sequence.Meta
+		for _, feature := range sequence.Features {
+			parsedSequences[sequenceIndex].AddFeature(&feature)
+		}
+	}
+	return parsedSequences, parseErrors
+}
+
+// parseFeature parses a single feature line. A feature without a Type is returned if the line can't be used at all.
+func parseFeature(line string) (poly.Feature, []error) {
+	record := poly.Feature{}
+	var errs []error
+
+	fields := strings.Split(line, "\t")
+	if len(fields) != 9 {
+		return record, []error{fmt.Errorf("%w: expected 9 tab separated columns but got %d", ErrInvalidColumns, len(fields))}
+	}
+	record.Name = fields[0]
+	record.Source = fields[1]
+	record.Type = fields[2]
+
+	// Indexing starts at 1 for gtf so we need to shift down for Sequence 0 index.
+	start, startErr := strconv.Atoi(fields[3])
+	end, endErr := strconv.Atoi(fields[4])
+	if startErr != nil || endErr != nil || start < 1 || start > end {
+		errs = append(errs, fmt.Errorf("%w: start %s and end %s", ErrInvalidCoordinates, fields[3], fields[4]))
+	}
+	record.SequenceLocation.Start = start - 1
+	record.SequenceLocation.End = end
+
+	record.Score = fields[5]
+	record.Strand = fields[6]
+	record.Phase = fields[7]
+	if record.Strand != "+" && record.Strand != "-" && record.Strand != "." {
+		errs = append(errs, fmt.Errorf("%w: strand must be one of + - or . but got %q", ErrInvalidColumns, record.Strand))
+	}
+	if record.Phase != "0" && record.Phase != "1" && record.Phase != "2" && record.Phase != "." {
+		errs = append(errs, fmt.Errorf("%w: frame must be 0, 1, 2 or . but got %q", ErrInvalidColumns, record.Phase))
+	}
+	record.SequenceLocation.Complement = record.Strand == "-"
+
+	attributes, err := parseAttributes(fields[8])
+	if err != nil {
+		errs = append(errs, err)
+	}
+	record.Attributes = attributes
+	return record, errs
+}
+
+// parseAttributes parses the attribute column. Attributes are a key followed by a value, quoted for text and bare for
+// numbers, and end with a semicolon. Keys can be repeated to hold several values and anything after a # is a comment.
+func parseAttributes(column string) (poly.Attributes, error) {
+	attributes := make(poly.Attributes)
+	var err error
+	var key, value strings.Builder
+	inKey, inQuotes, quoted := true, false, false
+
+	finishAttribute := func() {
+		if key.Len() == 0 && value.Len() == 0 && !quoted {
+			return
+		}
+		if key.Len() == 0 || (value.Len() == 0 && !quoted) {
+			err = fmt.Errorf("%w: %q is not a key and a value", ErrInvalidAttribute, strings.TrimSpace(key.String()+" "+value.String()))
+		}
+		if key.Len() != 0 {
+			attributes.Add(key.String(), value.String())
+		}
+		key.Reset()
+		value.Reset()
+		inKey, quoted = true, false
+	}
+
+	for index := 0; index < len(column); index++ {
+		character := column[index]
+		switch {
+		case inQuotes && character == '\\' && index+1 < len(column):
+			index++
+			value.WriteByte(column[index])
+		case inQuotes && character == '"':
+			inQuotes = false
+		case inQuotes:
+			value.WriteByte(character)
+		case character == ';':
+			finishAttribute()
+		case character == '#':
+			index = len(column)
+		case character == ' ' || character == '\t':
+			if key.Len() > 0 {
+				inKey = false
+			}
+		case character == '"':
+			if inKey || quoted || value.Len() > 0 {
+				err = fmt.Errorf("%w: unexpected quote in %q", ErrInvalidAttribute, column)
+			}
+			inKey, inQuotes, quoted = false, true, true
+		case inKey:
+			key.WriteByte(character)
+		default:
+			value.WriteByte(character)
+		}
+	}
+	if inQuotes {
+		err = fmt.Errorf("%w: unterminated quote in %q", ErrInvalidAttribute, column)
+	}
+	finishAttribute()
+	return attributes, err
+}
+
+// addHierarchy gives features the ID and Parent attributes GFF3 would have used for their gene_id and transcript_id.
+// Features only get a Parent if a gene or transcript with that ID is in the file.
+func addHierarchy(features []poly.Feature) {
+	genes := make(map[string]bool)
+	transcripts := make(map[string]bool)
+	for _, feature := range features {
+		switch {
+		case feature.Type == "gene":
+			genes[feature.Attributes.Get("gene_id")] = true
+		case isTranscript(feature.Type):
+			transcripts[feature.Attributes.Get("transcript_id")] = true
+		}
+	}
+
+	for featureIndex := range features {
+		feature := &features[featureIndex]
+		if feature.Attributes.Get("ID") != "" || len(feature.Attributes["Parent"]) > 0 {
+			continue
+		}
+		geneID := feature.Attributes.Get("gene_id")
+		transcriptID := feature.Attributes.Get("transcript_id")
+		switch {
+		case feature.Type == "gene" && geneID != "":
+			feature.Attributes["ID"] = []string{geneID}
+		case isTranscript(feature.Type) && transcriptID != "":
+			feature.Attributes["ID"] = []string{transcriptID}
+			if genes[geneID] {
+				feature.Attributes["Parent"] = []string{geneID}
+			}
+		case transcripts[transcriptID] && transcriptID != "":
+			feature.Attributes["Parent"] = []string{transcriptID}
+		case genes[geneID] && geneID != "":
+			feature.Attributes["Parent"] = []string{geneID}
+		}
+	}
+}
+
+func isTranscript(featureType string) bool {
+	switch featureType {
+	case "transcript", "mRNA", "primary_transcript", "ncRNA", "lnc_RNA", "rRNA", "tRNA", "misc_RNA", "precursor_RNA":
+		return true
+	}
+	return false
+}
+
+// Build builds a gtf out of a poly.Sequence.
+func Build(sequence poly.Sequence) []byte {
+	return BuildMulti([]poly.Sequence{sequence})
+}
+
+// BuildMulti builds a single gtf out of the features of several sequences.
+func BuildMulti(sequences []poly.Sequence) []byte {
+	var gtfBuffer bytes.Buffer
+	for _, sequence := range sequences {
+		hierarchy := sequence.Hierarchy()
+		for featureIndex, feature := range sequence.Features {
+			gtfBuffer.WriteString(buildFeatureString(sequence, hierarchy, featureIndex, feature))
+		}
+	}
+	return gtfBuffer.Bytes()
+}
+
+// buildFeatureString builds the lines of a single feature. Features with joined locations like genbank's spliced
+// CDSs are written as one line per part with the same attributes.
+func buildFeatureString(sequence poly.Sequence, hierarchy poly.Hierarchy, featureIndex int, feature poly.Feature) string {
+	featureName := feature.Name
+	if featureName == "" {
+		featureName = sequence.DisplayName()
+	}
+	featureSource := feature.Source
+	if featureSource == "" {
+		featureSource = "feature"
+	}
+	featureType := feature.Type
+	if featureType == "" {
+		featureType = "unknown"
+	}
+	featureScore := feature.Score
+	if featureScore == "" {
+		featureScore = "."
+	}
+
+	location := feature.SequenceLocation
+	if location.SpansOrigin() {
+		location = location.SplitAtOrigin(len(sequence.Sequence))
+	}
+	featureStrand := feature.Strand
+	switch featureStrand {
+	case "":
+		featureStrand = location.Strand()
+	case "?":
+		// GFF3's unknown strand has no GTF equivalent.
+		featureStrand = "."
+	}
+
+	// CDS phases follow the order the parts are translated in, which Ranges gives, so work them out before sorting.
+	parts := location.Ranges()
+	phases := make([]string, len(parts))
+	attributes := buildAttributes(sequence, hierarchy, featureIndex, feature)
+	for partIndex := range parts {
+		phases[partIndex] = feature.Phase
+		if phases[partIndex] == "" {
+			phases[partIndex] = "."
+		}
+	}
+	if feature.Phase == "" && (feature.Type == "CDS" || feature.Type == "start_codon" || feature.Type == "stop_codon") {
+		phases = location.Phases(feature.Attributes.Get("codon_start"))
+		delete(attributes, "codon_start")
+	}
+	order := make([]int, len(parts))
+	for partIndex := range order {
+		order[partIndex] = partIndex
+	}
+	sort.SliceStable(order, func(i, j int) bool { return parts[order[i]].Start < parts[order[j]].Start })
+	sortedParts := make([]poly.Location, len(parts))
+	sortedPhases := make([]string, len(parts))
+	for sortedIndex, partIndex := range order {
+		sortedParts[sortedIndex] = parts[partIndex]
+		sortedPhases[sortedIndex] = phases[partIndex]
+	}
+	parts, phases = sortedParts, sortedPhases
+
+	// genes and transcripts are a single line spanning all their parts.
+	if feature.Type == "gene" || isTranscript(feature.Type) {
+		span := parts[0]
+		for _, part := range parts {
+			if part.End > span.End {
+				span.End = part.End
+			}
+		}
+		parts, phases = []poly.Location{span}, phases[:1]
+	}
+	attributeString := buildAttributeString(attributes)
+
+	var featureString strings.Builder
+	for partIndex, part := range parts {
+		TAB := "\t"
+		// Indexing starts at 1 for gtf so we need to shift up from Sequence 0 index.
+		featureString.WriteString(featureName + TAB + featureSource + TAB + featureType + TAB + strconv.Itoa(part.Start+1) + TAB + strconv.Itoa(part.End) + TAB + featureScore + TAB + featureStrand + TAB + phases[partIndex] + TAB + attributeString + "\n")
+	}
+	return featureString.String()
+}
+
+// buildAttributes returns the attributes to write for a feature. Features that didn't come from a gtf get a gene_id and
+// transcript_id from their ID and Parent hierarchy or their /locus_tag or /gene. ID and Parent are always dropped since
+// gene_id and transcript_id carry the hierarchy in gtf.
+func buildAttributes(sequence poly.Sequence, hierarchy poly.Hierarchy, featureIndex int, feature poly.Feature) poly.Attributes {
+	attributes := make(poly.Attributes, len(feature.Attributes))
+	for key, values := range feature.Attributes {
+		if key != "ID" && key != "Parent" {
+			attributes[key] = append([]string{}, values...)
+		}
+	}
+	if attributes.Get("gene_id") != "" {
+		return attributes
+	}
+
+	// walk up the first parent of every feature to the gene at the top. The feature just below the gene is the transcript.
+	chain := []int{featureIndex}
+	for len(chain) <= len(sequence.Features) {
+		parents := hierarchy.Parents(chain[len(chain)-1])
+		if len(parents) == 0 {
+			break
+		}
+		chain = append(chain, parents[0])
+	}
+	if root := sequence.Features[chain[len(chain)-1]]; root.Attributes.Get("ID") != "" {
+		attributes["gene_id"] = []string{root.Attributes.Get("ID")}
+		if len(chain) >= 2 {
+			attributes["transcript_id"] = []string{sequence.Features[chain[len(chain)-2]].Attributes.Get("ID")}
+		}
+		return attributes
+	}
+
+	for _, qualifier := range []string{"locus_tag", "gene"} {
+		if value := feature.Attributes.Get(qualifier); value != "" {
+			attributes["gene_id"] = []string{value}
+			break
+		}
+	}
+	return attributes
+}
+
+// attributeEscaper escapes the characters that would end a quoted attribute value early.
+var attributeEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// buildAttributeString writes gene_id and transcript_id first like every gtf does and then the rest in alphabetical
+// order. Every value is quoted and repeated keys are written once per value.
+func buildAttributeString(attributes poly.Attributes) string {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		if key != "gene_id" && key != "transcript_id" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"gene_id", "transcript_id"}, keys...)
+
+	var attributeStrings []string
+	for _, key := range keys {
+		for _, value := range attributes[key] {
+			attributeStrings = append(attributeStrings, key+" \""+attributeEscaper.Replace(value)+"\";")
+		}
+	}
+	return strings.Join(attributeStrings, " ")
+}
+
+// Read reads a gtf from path into a poly.Sequence. Only the first seqname in the file is returned so use ReadMulti for
+// files that describe several sequences.
+func Read(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return Parse(file)
+}
+
+// ReadMulti reads a gtf from path into one Sequence per seqname.
+func ReadMulti(path string) ([]poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMulti(file)
+}
+
+// Write writes a poly.Sequence out to path as a gtf.
+func Write(sequence poly.Sequence, path string) {
+	_ = ioutil.WriteFile(path, Build(sequence), 0644)
+}
+
+// WriteMulti writes several sequences out to a single gtf at path.
+func WriteMulti(sequences []poly.Sequence, path string) {
+	_ = ioutil.WriteFile(path, BuildMulti(sequences), 0644)
+}
+
+/******************************************************************************
+
+GTF specific IO related things end here.
+
+******************************************************************************/
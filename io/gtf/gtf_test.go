@@ -0,0 +1,194 @@
+package gtf
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/embl"
+	"github.com/Open-Science-Global/poly/io/genbank"
+	"github.com/Open-Science-Global/poly/io/gff"
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleRead() {
+	sequence, _ := Read("../../data/sample.gtf")
+	transcript := sequence.Features[1]
+	fmt.Println(sequence.Meta.Name, transcript.Type, transcript.Attributes.Get("transcript_id"), transcript.Attributes["tag"])
+	// Output: chr1 transcript t1 [basic CCDS]
+}
+
+func ExampleBuild() {
+	sequences, _ := ReadMulti("../../data/sample.gtf")
+	fmt.Print(strings.SplitAfter(string(Build(sequences[1])), "\n")[0])
+	// Output: chr2	ensembl	gene	5	300	.	-	.	gene_id "g2"; gene_name "xyzB";
+}
+
+// sampleGtf returns data/sample.gtf without its header comment, which is all Build leaves out.
+func sampleGtf(t *testing.T) string {
+	file, err := ioutil.ReadFile("../../data/sample.gtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.SplitN(string(file), "\n", 2)[1]
+}
+
+func TestParse(t *testing.T) {
+	sequences, err := ReadMulti("../../data/sample.gtf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sequences) != 2 || sequences[0].Meta.Name != "chr1" || sequences[1].Meta.Name != "chr2" {
+		t.Fatalf("Expected a sequence per seqname. Got %d", len(sequences))
+	}
+
+	// the gene model is tied together with ID and Parent like gff3 does.
+	hierarchy := sequences[0].Hierarchy()
+	if diff := cmp.Diff([]int{2, 3, 4, 5, 6}, hierarchy.Children(1)); diff != "" {
+		t.Errorf("Exons and CDSs should be children of their transcript (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{1}, hierarchy.Children(0)); diff != "" {
+		t.Errorf("Transcripts should be children of their gene (-want +got):\n%s", diff)
+	}
+
+	cds := sequences[1].Features[3]
+	if !cds.SequenceLocation.Complement || cds.Strand != "-" || cds.Phase != "0" {
+		t.Errorf("Minus strand CDS was not parsed. Got: %+v", cds)
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attributes, err := parseAttributes(`gene_id "g1"; exon_number 2; note "a; b"; tag "x";tag "y" # a comment; ignored "yes";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"gene_id": {"g1"}, "exon_number": {"2"}, "note": {"a; b"}, "tag": {"x", "y"}}
+	if diff := cmp.Diff(expected, map[string][]string(attributes)); diff != "" {
+		t.Errorf("Attributes were not parsed (-want +got):\n%s", diff)
+	}
+
+	// quotes and backslashes inside values are escaped with a backslash.
+	attributes, err = parseAttributes(`gene_id "g1"; note "a \"quoted\" word \\ and a slash";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note := attributes.Get("note"); note != `a "quoted" word \ and a slash` {
+		t.Errorf("Escaped quotes were not read. Got %q", note)
+	}
+
+	for _, column := range []string{`gene_id;`, `gene_id "g1`, `"g1";`} {
+		if _, err := parseAttributes(column); !errors.Is(err, ErrInvalidAttribute) {
+			t.Errorf("%q should be an invalid attribute. Got: %v", column, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		err  error
+	}{
+		{"missing column", "chr1\tpoly\texon\t1\t20\t.\t+\tgene_id \"g1\";", ErrInvalidColumns},
+		{"start after end", "chr1\tpoly\texon\t20\t1\t.\t+\t.\tgene_id \"g1\";", ErrInvalidCoordinates},
+		{"bad frame", "chr1\tpoly\tCDS\t1\t20\t.\t+\t3\tgene_id \"g1\";", ErrInvalidColumns},
+		{"bad attribute", "chr1\tpoly\texon\t1\t20\t.\t+\t.\tgene_id \"g1", ErrInvalidAttribute},
+	}
+
+	for _, test := range tests {
+		file := "# header\nchr1\tpoly\tgene\t1\t20\t.\t+\t.\tgene_id \"g1\";\n" + test.line + "\n"
+		_, err := Parse([]byte(file))
+		var parseError *ParseError
+		if !errors.As(err, &parseError) {
+			t.Fatalf("%s: expected a *ParseError. Got: %v", test.name, err)
+		}
+		if parseError.Line != 3 || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q on line 3. Got: %s", test.name, test.err, err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	sequences, _ := ReadMulti("../../data/sample.gtf")
+	if diff := cmp.Diff(sampleGtf(t), string(BuildMulti(sequences))); diff != "" {
+		t.Errorf("gtf does not round trip (-want +got):\n%s", diff)
+	}
+}
+
+func TestGffRoundTrip(t *testing.T) {
+	sequences, _ := ReadMulti("../../data/sample.gtf")
+
+	gff3 := gff.BuildMulti(sequences)
+	if !strings.Contains(string(gff3), "chr1\thavana\texon\t11\t100\t.\t+\t.\tParent=t1;") {
+		t.Errorf("gff3 should hold the gene model as ID and Parent. Got:\n%s", gff3)
+	}
+
+	gffSequences, err := gff.ParseMulti(gff3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(sampleGtf(t), string(BuildMulti(gffSequences))); diff != "" {
+		t.Errorf("gtf does not survive a trip through gff3 (-want +got):\n%s", diff)
+	}
+}
+
+func TestEmblRoundTrip(t *testing.T) {
+	sequence, err := embl.Read("../../data/sample.embl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// GFF3's unknown strand isn't allowed in a gtf.
+	sequence.Features[0].Strand = "?"
+
+	gtf := Build(sequence)
+	parsed, err := Parse(gtf)
+	if err != nil {
+		t.Fatalf("gtf built from an embl file doesn't parse: %s", err)
+	}
+	if diff := cmp.Diff(string(gtf), string(Build(parsed))); diff != "" {
+		t.Errorf("gtf built from an embl file does not round trip (-want +got):\n%s", diff)
+	}
+
+	var notes []string
+	for _, feature := range parsed.Features {
+		notes = append(notes, feature.Attributes["note"]...)
+	}
+	if !strings.Contains(strings.Join(notes, "\n"), `contains a "quoted" word`) {
+		t.Errorf("Quotes inside values did not survive. Got notes %q", notes)
+	}
+	if parsed.Features[0].Strand != "." {
+		t.Errorf("Expected an unknown strand to be written as a dot. Got %q", parsed.Features[0].Strand)
+	}
+}
+
+func TestGenbankRoundTrip(t *testing.T) {
+	sequences, _ := ReadMulti("../../data/sample.gtf")
+
+	var genbankSequences []poly.Sequence
+	for _, sequence := range sequences {
+		genbankSequence, err := genbank.Parse(genbank.Build(sequence))
+		if err != nil {
+			t.Fatal(err)
+		}
+		genbankSequences = append(genbankSequences, genbankSequence)
+	}
+
+	// genbank has nowhere to keep the source column.
+	expected := strings.NewReplacer("\thavana\t", "\tfeature\t", "\tensembl\t", "\tfeature\t").Replace(sampleGtf(t))
+	if diff := cmp.Diff(expected, string(BuildMulti(genbankSequences))); diff != "" {
+		t.Errorf("gtf does not survive a trip through genbank (-want +got):\n%s", diff)
+	}
+
+	// genbank's spliced CDSs are split back into a line per exon with the frame worked out from /codon_start.
+	cds := genbankSequences[1].Features[3]
+	cds.SequenceLocation = poly.Location{Complement: true, Join: true, SubLocations: []poly.Location{{Start: 19, End: 60}, {Start: 150, End: 280}}}
+	cds.Attributes = poly.Attributes{"codon_start": {"1"}, "gene": {"xyzB"}}
+	expectedCds := "chr2\tfeature\tCDS\t20\t60\t.\t-\t2\tgene_id \"xyzB\"; gene \"xyzB\";\n" +
+		"chr2\tfeature\tCDS\t151\t280\t.\t-\t0\tgene_id \"xyzB\"; gene \"xyzB\";\n"
+	if got := buildFeatureString(genbankSequences[1], genbankSequences[1].Hierarchy(), 3, cds); got != expectedCds {
+		t.Errorf("Joined CDS was not split. Got:\n%s", got)
+	}
+}
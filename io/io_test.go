@@ -11,8 +11,12 @@ import (
 )
 
 func TestLocusParseRegression(t *testing.T) {
-	gbk := genbank.Read("../../data/puc19.gbk").Meta.Locus
-	json := polyjson.Read("../../data/puc19static.json").Meta.Locus
+	gbkSequence, err := genbank.Read("../data/puc19.gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gbk := gbkSequence.Meta.Locus
+	json := polyjson.Read("../data/puc19static.json").Meta.Locus
 
 	if diff := cmp.Diff(gbk, json, cmpopts.IgnoreFields(poly.Feature{}, "ParentSequence")); diff != "" {
 		t.Errorf("The meta parser has changed behaviour. Got this diff:\n%s", diff)
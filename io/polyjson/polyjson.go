@@ -15,22 +15,48 @@ JSON specific IO related things begin here.
 
 // Parse parses a poly.Sequence JSON file and adds appropriate pointers to struct.
 func Parse(file []byte) poly.Sequence {
+	sequence, _ := parse(file)
+	return sequence
+}
+
+// Read reads a poly.Sequence JSON file.
+func Read(path string) poly.Sequence {
+	file, _ := ioutil.ReadFile(path)
+	sequence := Parse(file)
+	return sequence
+}
+
+// ParseStrict is Parse for pipelines that would rather stop than carry on with a bad record. Malformed JSON
+// returns its decoding error and a sequence with validation errors comes back with a *poly.ValidationError.
+func ParseStrict(file []byte) (poly.Sequence, error) {
+	sequence, err := parse(file)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return sequence, sequence.ValidateStrict()
+}
+
+// parse decodes a poly.Sequence and points its features back at it. Whatever could be decoded is returned
+// along with any decoding error.
+func parse(file []byte) (poly.Sequence, error) {
 	var sequence poly.Sequence
-	_ = json.Unmarshal([]byte(file), &sequence)
+	err := json.Unmarshal(file, &sequence)
 	legacyFeatures := sequence.Features
 	sequence.Features = []poly.Feature{}
 
 	for _, feature := range legacyFeatures {
 		sequence.AddFeature(&feature)
 	}
-	return sequence
+	return sequence, err
 }
 
-// Read reads a poly.Sequence JSON file.
-func Read(path string) poly.Sequence {
-	file, _ := ioutil.ReadFile(path)
-	sequence := Parse(file)
-	return sequence
+// ReadStrict reads a poly.Sequence JSON file from path with ParseStrict.
+func ReadStrict(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	return ParseStrict(file)
 }
 
 // Write writes a poly.Sequence struct out to json.
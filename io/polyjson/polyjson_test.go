@@ -61,7 +61,7 @@ func TestGbkToJSON(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	testSequence := genbank.Read("../../data/puc19.gbk")
+	testSequence, _ := genbank.Read("../../data/puc19.gbk")
 
 	tmpJSONFilePath := filepath.Join(tmpDataDir, "puc19.json")
 	Write(testSequence, tmpJSONFilePath)
@@ -80,7 +80,7 @@ func TestGffToJSON(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDataDir)
 
-	gffTestSequence := gff.Read("../../data/ecoli-mg1655-short.gff")
+	gffTestSequence, _ := gff.Read("../../data/ecoli-mg1655-short.gff")
 
 	tmpJSONFilePath := filepath.Join(tmpDataDir, "ecoli-mg1655-short.json")
 	Write(gffTestSequence, tmpJSONFilePath)
@@ -93,6 +93,31 @@ func TestGffToJSON(t *testing.T) {
 
 }
 
+func TestLegacyAttributes(t *testing.T) {
+	// puc19static.json was written before qualifiers could have more than one value.
+	sequence := Read("../../data/puc19static.json")
+
+	if diff := cmp.Diff([]string{"synthetic DNA construct"}, sequence.Features[0].Attributes["label"]); diff != "" {
+		t.Errorf("Legacy single value attributes were not read. Got this diff:\n%s", diff)
+	}
+}
+
+func TestReadStrict(t *testing.T) {
+	if _, err := ReadStrict("../../data/puc19static.json"); err != nil {
+		t.Errorf("Valid JSON failed strict parsing: %s", err)
+	}
+
+	// sample.json is from an older layout that kept the sequence in an object of its own.
+	if _, err := ReadStrict("../../data/sample.json"); err == nil {
+		t.Errorf("JSON that doesn't decode should fail strict parsing.")
+	}
+
+	// features without a sequence for them to sit on.
+	if _, err := ParseStrict([]byte(`{"features": [{"type": "gene", "sequence_location": {"start": 0, "end": 10}}]}`)); err == nil {
+		t.Errorf("Features without a sequence should fail strict parsing.")
+	}
+}
+
 /******************************************************************************
 
 JSON related tests end here.
@@ -0,0 +1,437 @@
+package snapgene
+
+import (
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Open-Science-Global/poly"
+)
+
+/******************************************************************************
+
+SnapGene specific IO related things begin here.
+
+SnapGene saves plasmid maps as binary .dna files.
+
+A .dna file is a list of packets, each one a type byte, a big endian 32 bit
+length and that many bytes of data. The ones we care about are:
+
+	0x09 cookie     "SnapGene" and what kind of sequence the file holds. Always first.
+	0x00 DNA        a flags byte (bit 0 is set for circular sequences) and the bases.
+	0x06 notes      XML with the description, dates and references.
+	0x0A features   XML with every feature, its segments, colors and qualifiers.
+	0x05 primers    XML with every primer and where it binds.
+
+Everything else (enzymes, history, alignments, ...) is skipped.
+
+Features are made of one or more segments with 1-based inclusive ranges, and a
+range whose start is after its end runs across the origin. Segments of type
+"gap" are just there to draw a gap in the map so they're left out. Qualifier
+values are often little bits of HTML which are stripped down to plain text.
+
+Primers become primer_bind features at each of their binding sites like they
+do when SnapGene exports genbank. Binding site locations are 0-based inclusive
+for some reason, unlike feature ranges.
+
+There is no official spec. This follows the description SnapGene sent to
+Biopython and what its parser does:
+
+https://github.com/biopython/biopython/blob/master/Bio/SeqIO/SnapGeneIO.py
+
+******************************************************************************/
+
+// Errors for SnapGene files. Check for them with errors.Is.
+var (
+	ErrNotSnapGene         = errors.New("not a SnapGene file")
+	ErrUnsupportedType     = errors.New("unsupported SnapGene sequence type")
+	ErrTruncated           = errors.New("truncated SnapGene file")
+	ErrMalformedPacket     = errors.New("malformed packet")
+	ErrMissingSequence     = errors.New("no DNA packet")
+	ErrInvalidFeatureRange = errors.New("invalid feature range")
+)
+
+const (
+	dnaPacket      = 0x00
+	primersPacket  = 0x05
+	notesPacket    = 0x06
+	cookiePacket   = 0x09
+	featuresPacket = 0x0A
+)
+
+// dnaSequenceType is what the cookie says .dna files hold. Protein and RNA files aren't supported.
+const dnaSequenceType = 1
+
+type notesXML struct {
+	Type            string         `xml:"Type"`
+	LastModified    string         `xml:"LastModified"`
+	AccessionNumber string         `xml:"AccessionNumber"`
+	Description     string         `xml:"Description"`
+	Comments        string         `xml:"Comments"`
+	References      []referenceXML `xml:"References>Reference"`
+}
+
+type referenceXML struct {
+	Title    string `xml:"title,attr"`
+	PubMedID string `xml:"pubMedID,attr"`
+	Journal  string `xml:"journal,attr"`
+	Authors  string `xml:"authors,attr"`
+}
+
+type featuresXML struct {
+	Features []featureXML `xml:"Feature"`
+}
+
+type featureXML struct {
+	Name           string       `xml:"name,attr"`
+	Type           string       `xml:"type,attr"`
+	Directionality string       `xml:"directionality,attr"`
+	Segments       []segmentXML `xml:"Segment"`
+	Qualifiers     []struct {
+		Name   string `xml:"name,attr"`
+		Values []struct {
+			Text   *string `xml:"text,attr"`
+			Predef *string `xml:"predef,attr"`
+			Int    *string `xml:"int,attr"`
+		} `xml:"V"`
+	} `xml:"Q"`
+}
+
+type segmentXML struct {
+	Range string `xml:"range,attr"`
+	Color string `xml:"color,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type primersXML struct {
+	Primers []struct {
+		Name         string `xml:"name,attr"`
+		Description  string `xml:"description,attr"`
+		Sequence     string `xml:"sequence,attr"`
+		BindingSites []struct {
+			Location    string `xml:"location,attr"`
+			BoundStrand string `xml:"boundStrand,attr"`
+		} `xml:"BindingSite"`
+	} `xml:"Primer"`
+}
+
+/******************************************************************************
+
+Start of SnapGene Parse functions
+
+******************************************************************************/
+
+// Parse parses a SnapGene .dna file into a poly.Sequence.
+func Parse(file []byte) (poly.Sequence, error) {
+	var sequence poly.Sequence
+	var features []poly.Feature
+	var primers *primersXML
+	sequenceFound := false
+
+	if len(file) == 0 || file[0] != cookiePacket {
+		return sequence, ErrNotSnapGene
+	}
+	for offset := 0; offset < len(file); {
+		if len(file)-offset < 5 {
+			return sequence, fmt.Errorf("%w: packet header at byte %d is cut short", ErrTruncated, offset)
+		}
+		packetType := file[offset]
+		length := int(binary.BigEndian.Uint32(file[offset+1:]))
+		dataStart := offset + 5
+		if length > len(file)-dataStart {
+			return sequence, fmt.Errorf("%w: packet %#02x at byte %d says it is %d bytes long but only %d are left", ErrTruncated, packetType, offset, length, len(file)-dataStart)
+		}
+		data := file[dataStart : dataStart+length]
+
+		var err error
+		switch packetType {
+		case cookiePacket:
+			err = parseCookie(data)
+		case dnaPacket:
+			if sequenceFound {
+				err = fmt.Errorf("%w: more than one DNA packet", ErrMalformedPacket)
+				break
+			}
+			parseDNA(data, &sequence)
+			sequenceFound = true
+		case notesPacket:
+			err = parseNotes(data, &sequence)
+		case featuresPacket:
+			features, err = parseFeatures(data)
+		case primersPacket:
+			primers = &primersXML{}
+			if xmlErr := xml.Unmarshal(data, primers); xmlErr != nil {
+				err = fmt.Errorf("%w: %s", ErrMalformedPacket, xmlErr)
+			}
+		}
+		if err != nil {
+			return sequence, fmt.Errorf("packet %#02x at byte %d: %w", packetType, offset, err)
+		}
+		offset = dataStart + length
+	}
+	if !sequenceFound {
+		return sequence, ErrMissingSequence
+	}
+
+	// locations can only be checked once we know how long the sequence is, which could come after the features.
+	circular := sequence.Meta.Locus.Circular
+	for featureIndex := range features {
+		location, err := buildLocation(features[featureIndex].SequenceLocation, len(sequence.Sequence), circular)
+		if err != nil {
+			return sequence, fmt.Errorf("feature %q: %w", features[featureIndex].Attributes.Get("label"), err)
+		}
+		features[featureIndex].SequenceLocation = location
+	}
+	if primers != nil {
+		primerFeatures, err := parsePrimers(*primers, len(sequence.Sequence), circular)
+		if err != nil {
+			return sequence, err
+		}
+		features = append(features, primerFeatures...)
+	}
+
+	for _, feature := range features {
+		sequence.AddFeature(&feature)
+	}
+	return sequence, nil
+}
+
+// parseCookie checks the cookie packet that starts every SnapGene file.
+func parseCookie(data []byte) error {
+	if len(data) < 10 || string(data[:8]) != "SnapGene" {
+		return ErrNotSnapGene
+	}
+	if sequenceType := binary.BigEndian.Uint16(data[8:]); sequenceType != dnaSequenceType {
+		return fmt.Errorf("%w: %d", ErrUnsupportedType, sequenceType)
+	}
+	return nil
+}
+
+// parseDNA reads the topology flags and the bases.
+func parseDNA(data []byte, sequence *poly.Sequence) {
+	if len(data) == 0 {
+		return
+	}
+	flags := data[0]
+	sequence.Sequence = string(data[1:])
+	sequence.Meta.Locus.SequenceLength = strconv.Itoa(len(sequence.Sequence))
+	sequence.Meta.Locus.SequenceCoding = "bp"
+	sequence.Meta.Locus.MoleculeType = "DNA"
+	sequence.Meta.Locus.Circular = flags&0x01 != 0
+	sequence.Meta.Locus.Linear = !sequence.Meta.Locus.Circular
+}
+
+// parseNotes fills in meta from the notes packet the way SnapGene fills in a genbank file when it exports one.
+func parseNotes(data []byte, sequence *poly.Sequence) error {
+	var notes notesXML
+	if err := xml.Unmarshal(data, &notes); err != nil {
+		return fmt.Errorf("%w: %s", ErrMalformedPacket, err)
+	}
+
+	if notes.Type == "Synthetic" {
+		sequence.Meta.Locus.GenbankDivision = "SYN"
+	} else {
+		sequence.Meta.Locus.GenbankDivision = "UNC"
+	}
+	if modified, err := time.Parse("2006.1.2", notes.LastModified); err == nil {
+		sequence.Meta.Locus.ModificationDate = strings.ToUpper(modified.Format("02-Jan-2006"))
+	}
+	sequence.Meta.Accession = notes.AccessionNumber
+	sequence.Meta.Definition = decodeText(notes.Description)
+	if comments := decodeText(notes.Comments); comments != "" {
+		if sequence.Meta.Other == nil {
+			sequence.Meta.Other = map[string]string{}
+		}
+		sequence.Meta.Other["COMMENT"] = comments
+	}
+	for referenceIndex, reference := range notes.References {
+		sequence.Meta.References = append(sequence.Meta.References, poly.Reference{
+			Index:   strconv.Itoa(referenceIndex + 1),
+			Authors: reference.Authors,
+			Title:   decodeText(reference.Title),
+			Journal: decodeText(reference.Journal),
+			PubMed:  reference.PubMedID,
+		})
+	}
+	return nil
+}
+
+// parseFeatures reads the features packet. Locations are left 1-based until Parse knows the sequence length.
+func parseFeatures(data []byte) ([]poly.Feature, error) {
+	var packet featuresXML
+	if err := xml.Unmarshal(data, &packet); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedPacket, err)
+	}
+
+	var features []poly.Feature
+	for _, featureXML := range packet.Features {
+		feature := poly.Feature{Type: featureXML.Type, Attributes: poly.Attributes{}}
+		if feature.Type == "" {
+			feature.Type = "misc_feature"
+		}
+
+		for _, qualifier := range featureXML.Qualifiers {
+			for _, value := range qualifier.Values {
+				switch {
+				case value.Text != nil:
+					feature.Attributes.Add(qualifier.Name, decodeText(*value.Text))
+				case value.Predef != nil:
+					feature.Attributes.Add(qualifier.Name, *value.Predef)
+				case value.Int != nil:
+					feature.Attributes.Add(qualifier.Name, *value.Int)
+				}
+			}
+		}
+		// SnapGene exports the feature's name as its label.
+		if name := featureXML.Name; name != "" {
+			if _, ok := feature.Attributes["label"]; !ok {
+				feature.Attributes.Add("label", name)
+			} else if feature.Attributes.Get("label") != name {
+				feature.Attributes.Add("name", name)
+			}
+		}
+
+		// segment ranges are kept as they are in SubLocations until buildLocation can check them.
+		reverse := featureXML.Directionality == "2"
+		for _, segment := range featureXML.Segments {
+			if segment.Type == "gap" {
+				continue
+			}
+			start, end, err := parseRange(segment.Range)
+			if err != nil {
+				return nil, fmt.Errorf("feature %q: %w", featureXML.Name, err)
+			}
+			feature.SequenceLocation.SubLocations = append(feature.SequenceLocation.SubLocations, poly.Location{Start: start - 1, End: end})
+			if segment.Color != "" && feature.Attributes.Get("color") == "" {
+				feature.Attributes.Add("color", segment.Color)
+			}
+		}
+		if len(feature.SequenceLocation.SubLocations) == 0 {
+			return nil, fmt.Errorf("feature %q: %w: no segments", featureXML.Name, ErrInvalidFeatureRange)
+		}
+		feature.SequenceLocation.Complement = reverse
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+// parsePrimers makes a primer_bind feature for every distinct binding site of every primer.
+func parsePrimers(packet primersXML, sequenceLength int, circular bool) ([]poly.Feature, error) {
+	var features []poly.Feature
+	for _, primer := range packet.Primers {
+		seen := map[string]bool{}
+		for _, site := range primer.BindingSites {
+			// the same site is often listed twice, once of them "simplified".
+			key := site.Location + "/" + site.BoundStrand
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			start, end, err := parseRange(site.Location)
+			if err != nil {
+				return nil, fmt.Errorf("primer %q: %w", primer.Name, err)
+			}
+			location := poly.Location{Complement: site.BoundStrand == "1", SubLocations: []poly.Location{{Start: start, End: end + 1}}}
+			if location, err = buildLocation(location, sequenceLength, circular); err != nil {
+				return nil, fmt.Errorf("primer %q: %w", primer.Name, err)
+			}
+
+			feature := poly.Feature{Type: "primer_bind", SequenceLocation: location, Attributes: poly.Attributes{}}
+			if primer.Name != "" {
+				feature.Attributes.Add("label", primer.Name)
+			}
+			if note := decodeText(primer.Description); note != "" {
+				feature.Attributes.Add("note", note)
+			}
+			features = append(features, feature)
+		}
+	}
+	return features, nil
+}
+
+// parseRange splits a range like 10-20 into its two numbers.
+func parseRange(rangeString string) (int, int, error) {
+	parts := strings.Split(rangeString, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidFeatureRange, rangeString)
+	}
+	start, startErr := strconv.Atoi(strings.TrimSpace(parts[0]))
+	end, endErr := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if startErr != nil || endErr != nil {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidFeatureRange, rangeString)
+	}
+	return start, end, nil
+}
+
+// buildLocation checks 0-based segment ranges against the sequence and turns them into a poly.Location.
+// A single segment becomes a plain range and several become a join. Ranges whose start is after their
+// end run across the origin, which poly models the same way.
+func buildLocation(segments poly.Location, sequenceLength int, circular bool) (poly.Location, error) {
+	for _, segment := range segments.SubLocations {
+		if segment.Start < 0 || segment.End > sequenceLength || segment.Start >= sequenceLength || segment.End < 1 {
+			return poly.Location{}, fmt.Errorf("%w: %d-%d is outside of a %d bp sequence", ErrInvalidFeatureRange, segment.Start+1, segment.End, sequenceLength)
+		}
+		if segment.End <= segment.Start && !circular {
+			return poly.Location{}, fmt.Errorf("%w: %d-%d runs across the origin of a linear sequence", ErrInvalidFeatureRange, segment.Start+1, segment.End)
+		}
+	}
+
+	if len(segments.SubLocations) == 1 {
+		location := segments.SubLocations[0]
+		location.Complement = segments.Complement
+		return location, nil
+	}
+	location := poly.Location{Join: true, Complement: segments.Complement, SubLocations: segments.SubLocations}
+	location.Start = location.SubLocations[0].Start
+	location.End = location.SubLocations[len(location.SubLocations)-1].End
+	return location, nil
+}
+
+// htmlTag matches the tags SnapGene wraps text in.
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// decodeText turns SnapGene's bits of HTML into plain text.
+func decodeText(text string) string {
+	if strings.Contains(text, "<") {
+		text = strings.NewReplacer("<br>", " ", "<br/>", " ", "<br />", " ").Replace(text)
+		text = htmlTag.ReplaceAllString(text, "")
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+/******************************************************************************
+
+Start of SnapGene Read functions
+
+******************************************************************************/
+
+// Read reads a SnapGene .dna file into a poly.Sequence. The file name, without its extension, is used as the locus name.
+func Read(path string) (poly.Sequence, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	sequence, err := Parse(file)
+	if err != nil {
+		return sequence, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	sequence.Meta.Name = name
+	sequence.Meta.Locus.Name = name
+	return sequence, nil
+}
+
+/******************************************************************************
+
+SnapGene specific IO related things end here.
+
+******************************************************************************/
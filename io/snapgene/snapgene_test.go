@@ -0,0 +1,236 @@
+package snapgene
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/genbank"
+	"github.com/google/go-cmp/cmp"
+)
+
+// ExampleRead shows basic usage for Read.
+func ExampleRead() {
+	sequence, _ := Read("../../data/puc19_snapgene.dna")
+	fmt.Println(sequence.Meta.Locus.Name, sequence.Meta.Locus.SequenceLength, sequence.Meta.Locus.Circular)
+	fmt.Println(sequence.Features[3].Type, sequence.Features[3].Attributes.Get("label"), sequence.Features[3].Attributes.Get("color"))
+	// Output:
+	// puc19_snapgene 2686 true
+	// CDS lacZ-alpha #ffcc99
+}
+
+// ExampleParse shows how to parse a SnapGene file that is already in memory.
+func ExampleParse() {
+	file, _ := ioutil.ReadFile("../../data/puc19_snapgene.dna")
+	sequence, _ := Parse(file)
+
+	for _, feature := range sequence.Features {
+		if feature.Type == "primer_bind" && feature.SequenceLocation.Complement {
+			fmt.Println(feature.Attributes.Get("label"))
+		}
+	}
+	// Output:
+	// M13 Forward
+	// M13 fwd
+	// M13/pUC Forward
+	// pRS-marker
+	// pBRforEco
+	// Amp-R
+}
+
+// compareFeature is the part of a feature both SnapGene and its genbank export should agree on.
+type compareFeature struct {
+	Type     string
+	Label    string
+	Sequence string
+}
+
+func compareFeatures(sequence poly.Sequence) []compareFeature {
+	var features []compareFeature
+	for _, feature := range sequence.Features {
+		// the source feature is made up by the genbank export.
+		if feature.Type == "source" {
+			continue
+		}
+		features = append(features, compareFeature{Type: feature.Type, Label: feature.Attributes.Get("label"), Sequence: feature.GetSequence()})
+	}
+	// primers come after the features in a .dna file but are sorted in with them in the export.
+	sort.Slice(features, func(i, j int) bool {
+		if features[i].Label != features[j].Label {
+			return features[i].Label < features[j].Label
+		}
+		return features[i].Sequence < features[j].Sequence
+	})
+	return features
+}
+
+func TestRead(t *testing.T) {
+	sequence, err := Read("../../data/puc19_snapgene.dna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := genbank.Read("../../data/puc19_snapgene.gb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sequence.Sequence != exported.Sequence {
+		t.Errorf("Sequence doesn't match the genbank SnapGene exported")
+	}
+	if diff := cmp.Diff(compareFeatures(exported), compareFeatures(sequence)); diff != "" {
+		t.Errorf("Features don't match the genbank SnapGene exported (-want +got):\n%s", diff)
+	}
+	for _, feature := range sequence.Features {
+		if feature.ParentSequence == nil {
+			t.Errorf("Feature %q has no parent sequence", feature.Attributes.Get("label"))
+		}
+	}
+
+	expectedLocus := poly.Locus{
+		Name:             "puc19_snapgene",
+		SequenceLength:   "2686",
+		MoleculeType:     "DNA",
+		GenbankDivision:  "SYN",
+		ModificationDate: "22-OCT-2019",
+		SequenceCoding:   "bp",
+		Circular:         true,
+	}
+	if diff := cmp.Diff(expectedLocus, sequence.Meta.Locus); diff != "" {
+		t.Errorf("Locus was not filled in (-want +got):\n%s", diff)
+	}
+	if sequence.Meta.Definition != "pUC cloning vector." {
+		t.Errorf("Expected the description with its HTML stripped as the definition. Got %q", sequence.Meta.Definition)
+	}
+	expectedReferences := []poly.Reference{{
+		Index:   "1",
+		Authors: "Norrander J, Kempe T, Messing J",
+		Title:   "Construction of improved M13 vectors using oligodeoxynucleotide-directed mutagenesis.",
+		Journal: "Gene. 1983 Dec;26(1):101-6.",
+		PubMed:  "6323249",
+	}}
+	if diff := cmp.Diff(expectedReferences, sequence.Meta.References); diff != "" {
+		t.Errorf("References were not read (-want +got):\n%s", diff)
+	}
+}
+
+func TestFeatureQualifiers(t *testing.T) {
+	sequence, _ := Read("../../data/puc19_snapgene.dna")
+
+	byLabel := map[string]poly.Feature{}
+	for _, feature := range sequence.Features {
+		byLabel[feature.Attributes.Get("label")] = feature
+	}
+
+	lacZ := byLabel["lacZ-alpha"]
+	if lacZ.Attributes.Get("codon_start") != "1" || lacZ.Attributes.Get("gene") != "lacZ fragment" {
+		t.Errorf("lacZ-alpha qualifiers were not read: %v", lacZ.Attributes)
+	}
+	cap := byLabel["CAP binding site"]
+	if note := cap.Attributes.Get("note"); note != "CAP binding activates transcription in the presence of cAMP." {
+		t.Errorf("Expected the note with its HTML stripped. Got %q", note)
+	}
+	ori := byLabel["ori"]
+	if ori.Attributes.Get("direction") != "RIGHT" {
+		t.Errorf("Expected predefined values to be read. Got %v", ori.Attributes)
+	}
+	if ori.SequenceLocation.Start != 2314 || ori.SequenceLocation.End != 217 || !ori.SequenceLocation.SpansOrigin() {
+		t.Errorf("Expected ori to run across the origin. Got %+v", ori.SequenceLocation)
+	}
+	m13 := byLabel["M13 fwd"]
+	expectedLocation := poly.Location{Start: 688, End: 705, Complement: true}
+	if diff := cmp.Diff(expectedLocation, m13.SequenceLocation); diff != "" {
+		t.Errorf("Primer binding site was not read (-want +got):\n%s", diff)
+	}
+}
+
+// packet builds a single SnapGene packet.
+func packet(packetType byte, data string) []byte {
+	header := make([]byte, 5)
+	header[0] = packetType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+	return append(header, data...)
+}
+
+// cookie is the cookie packet of a DNA file.
+var cookie = packet(cookiePacket, "SnapGene\x00\x01\x00\x0f\x00\x13")
+
+func buildFile(packets ...[]byte) []byte {
+	return bytes.Join(append([][]byte{cookie}, packets...), nil)
+}
+
+func TestParseSegments(t *testing.T) {
+	features := `<Features>
+<Feature name="split" type="CDS" directionality="2">
+	<Segment range="2-4" color="#993366" type="standard"/>
+	<Segment range="5-6" type="gap"/>
+	<Segment range="7-10" color="#ff0000" type="standard"/>
+	<Q name="note"><V text="&lt;html&gt;&lt;body&gt;one&lt;br&gt;two &amp;amp; three&lt;/body&gt;&lt;/html&gt;"/></Q>
+</Feature>
+<Feature name="untyped"><Segment range="1-1"/></Feature>
+</Features>`
+	file := buildFile(packet(dnaPacket, "\x00ACGTACGTACGT"), packet(featuresPacket, features))
+	sequence, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sequence.Meta.Locus.Linear || sequence.Meta.Locus.Circular {
+		t.Errorf("Expected a linear sequence. Got %+v", sequence.Meta.Locus)
+	}
+
+	split := sequence.Features[0]
+	expectedLocation := poly.Location{Start: 1, End: 10, Complement: true, Join: true, SubLocations: []poly.Location{{Start: 1, End: 4}, {Start: 6, End: 10}}}
+	if diff := cmp.Diff(expectedLocation, split.SequenceLocation); diff != "" {
+		t.Errorf("Segments were not joined (-want +got):\n%s", diff)
+	}
+	expectedAttributes := poly.Attributes{"note": {"one two & three"}, "label": {"split"}, "color": {"#993366"}}
+	if diff := cmp.Diff(expectedAttributes, split.Attributes); diff != "" {
+		t.Errorf("Attributes were not read (-want +got):\n%s", diff)
+	}
+	if split.GetSequence() != "GTACACG" {
+		t.Errorf("Expected the reverse complement of both segments. Got %q", split.GetSequence())
+	}
+
+	untyped := sequence.Features[1]
+	if untyped.Type != "misc_feature" || !cmp.Equal(untyped.SequenceLocation, poly.Location{Start: 0, End: 1}) {
+		t.Errorf("Expected a one base misc_feature. Got %s %+v", untyped.Type, untyped.SequenceLocation)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	dna := packet(dnaPacket, "\x00ACGTACGTACGT")
+	tests := []struct {
+		name string
+		file []byte
+		err  error
+	}{
+		{name: "empty", file: nil, err: ErrNotSnapGene},
+		{name: "genbank", file: []byte("LOCUS       puc19"), err: ErrNotSnapGene},
+		{name: "bad cookie", file: packet(cookiePacket, "SnapGenf\x00\x01"), err: ErrNotSnapGene},
+		{name: "protein", file: packet(cookiePacket, "SnapGene\x00\x02\x00\x0f\x00\x13"), err: ErrUnsupportedType},
+		{name: "no DNA", file: buildFile(), err: ErrMissingSequence},
+		{name: "two DNA packets", file: buildFile(dna, dna), err: ErrMalformedPacket},
+		{name: "cut off packet", file: buildFile(dna)[:len(cookie)+8], err: ErrTruncated},
+		{name: "cut off header", file: append(buildFile(dna), featuresPacket, 0), err: ErrTruncated},
+		{name: "bad XML", file: buildFile(dna, packet(featuresPacket, "<Features><Feature>")), err: ErrMalformedPacket},
+		{name: "past the end", file: buildFile(dna, packet(featuresPacket, `<Features><Feature><Segment range="5-13"/></Feature></Features>`)), err: ErrInvalidFeatureRange},
+		{name: "no segments", file: buildFile(dna, packet(featuresPacket, `<Features><Feature name="empty"/></Features>`)), err: ErrInvalidFeatureRange},
+		{name: "bad range", file: buildFile(dna, packet(featuresPacket, `<Features><Feature><Segment range="five"/></Feature></Features>`)), err: ErrInvalidFeatureRange},
+		{name: "origin of a linear sequence", file: buildFile(dna, packet(featuresPacket, `<Features><Feature><Segment range="10-2"/></Feature></Features>`)), err: ErrInvalidFeatureRange},
+		{name: "primer past the end", file: buildFile(dna, packet(primersPacket, `<Primers><Primer name="p"><BindingSite location="5-12" boundStrand="0"/></Primer></Primers>`)), err: ErrInvalidFeatureRange},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.file); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %q. Got: %v", test.name, test.err, err)
+		}
+	}
+
+	if _, err := Read("../../data/missing.dna"); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file error. Got: %v", err)
+	}
+}
@@ -0,0 +1,549 @@
+package twobit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/fasta"
+)
+
+/******************************************************************************
+
+2bit specific IO related things begin here.
+
+2bit is the format UCSC distributes reference genomes in. It packs four bases
+into every byte (T=00, C=01, A=10, G=11) and keeps an index of where every
+sequence starts so any stretch of any chromosome can be read without touching
+the rest of the file. Since two bits can only hold four bases, runs of Ns are
+kept separately as "N blocks" and runs of lowercase (softmasked repeats) as
+"mask blocks".
+
+A file looks like this, every number being a 32 bit integer in whatever byte
+order the machine that wrote it used (the signature tells you which):
+
+	signature 0x1A412743, version, sequence count, reserved
+	index: for each sequence a name length byte, the name and its offset
+	for each sequence:
+		DNA size
+		N block count, N block starts, N block sizes
+		mask block count, mask block starts, mask block sizes
+		reserved
+		packed DNA
+
+Version 1 files are the same except that the offsets in the index are 64 bit
+so that files bigger than 4 GB can be written.
+
+Like UCSC's faToTwoBit, anything that isn't A, C, G or T is written as an N
+since there is no way to store it, and names are cut off at the first
+whitespace.
+
+https://genome.ucsc.edu/FAQ/FAQformat.html#format7
+
+******************************************************************************/
+
+// Errors for 2bit files. Check for them with errors.Is.
+var (
+	ErrNotTwoBit          = errors.New("not a 2bit file")
+	ErrUnsupportedVersion = errors.New("unsupported 2bit version")
+	ErrTruncated          = errors.New("truncated 2bit file")
+	ErrUnknownSequence    = errors.New("no sequence with that name")
+	ErrInvalidRange       = errors.New("invalid range")
+	ErrInvalidName        = errors.New("invalid sequence name")
+)
+
+const signature = 0x1A412743
+
+// packedBases maps two bits onto the base they stand for.
+const packedBases = "TCAG"
+
+// TwoBit reads sequences out of a 2bit file on demand.
+type TwoBit struct {
+	// Uppercase ignores softmasking so that every base comes back in upper case.
+	Uppercase bool
+
+	r         io.ReaderAt
+	closer    io.Closer
+	byteOrder binary.ByteOrder
+	names     []string
+	offsets   map[string]int64
+
+	mutex   sync.Mutex
+	headers map[string]*recordHeader
+}
+
+// recordHeader is everything about a sequence in a 2bit file except its bases.
+type recordHeader struct {
+	size        int
+	nBlocks     []block
+	maskBlocks  []block
+	packedStart int64
+}
+
+// block is a run of Ns or lowercase bases. Start and End are 0-based and half-open.
+type block struct {
+	Start int
+	End   int
+}
+
+/******************************************************************************
+
+Start of 2bit Read functions
+
+******************************************************************************/
+
+// Open opens a 2bit file and reads its index. Close it when you're done.
+func Open(path string) (*TwoBit, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	twoBit, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	twoBit.closer = file
+	return twoBit, nil
+}
+
+// NewReader reads the index of a 2bit file. Sequences are only read when they're asked for.
+func NewReader(r io.ReaderAt) (*TwoBit, error) {
+	twoBit := &TwoBit{r: r, offsets: map[string]int64{}, headers: map[string]*recordHeader{}}
+
+	header := make([]byte, 16)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotTwoBit, err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(header) == signature:
+		twoBit.byteOrder = binary.LittleEndian
+	case binary.BigEndian.Uint32(header) == signature:
+		twoBit.byteOrder = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("%w: bad signature %x", ErrNotTwoBit, header[:4])
+	}
+	version := twoBit.byteOrder.Uint32(header[4:])
+	if version > 1 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	sequenceCount := twoBit.byteOrder.Uint32(header[8:])
+
+	reader := io.NewSectionReader(r, 16, math.MaxInt64-16)
+	for sequenceIndex := uint32(0); sequenceIndex < sequenceCount; sequenceIndex++ {
+		var nameLength [1]byte
+		if _, err := io.ReadFull(reader, nameLength[:]); err != nil {
+			return nil, fmt.Errorf("%w: index ends after %d of %d sequences", ErrTruncated, sequenceIndex, sequenceCount)
+		}
+		name := make([]byte, nameLength[0])
+		if _, err := io.ReadFull(reader, name); err != nil {
+			return nil, fmt.Errorf("%w: index ends after %d of %d sequences", ErrTruncated, sequenceIndex, sequenceCount)
+		}
+		var offset int64
+		if version == 0 {
+			var offset32 uint32
+			if err := binary.Read(reader, twoBit.byteOrder, &offset32); err != nil {
+				return nil, fmt.Errorf("%w: index ends after %d of %d sequences", ErrTruncated, sequenceIndex, sequenceCount)
+			}
+			offset = int64(offset32)
+		} else {
+			var offset64 uint64
+			if err := binary.Read(reader, twoBit.byteOrder, &offset64); err != nil {
+				return nil, fmt.Errorf("%w: index ends after %d of %d sequences", ErrTruncated, sequenceIndex, sequenceCount)
+			}
+			offset = int64(offset64)
+		}
+		twoBit.names = append(twoBit.names, string(name))
+		twoBit.offsets[string(name)] = offset
+	}
+	return twoBit, nil
+}
+
+// Close closes the file if the TwoBit was made by Open.
+func (twoBit *TwoBit) Close() error {
+	if twoBit.closer == nil {
+		return nil
+	}
+	return twoBit.closer.Close()
+}
+
+// Names returns the names of every sequence in the order they are in the file.
+func (twoBit *TwoBit) Names() []string {
+	return append([]string{}, twoBit.names...)
+}
+
+// Length returns how many bases the sequence called name has.
+func (twoBit *TwoBit) Length(name string) (int, error) {
+	header, err := twoBit.header(name)
+	if err != nil {
+		return 0, err
+	}
+	return header.size, nil
+}
+
+// header reads, and remembers, the header of the sequence called name.
+func (twoBit *TwoBit) header(name string) (*recordHeader, error) {
+	twoBit.mutex.Lock()
+	defer twoBit.mutex.Unlock()
+	if header, ok := twoBit.headers[name]; ok {
+		return header, nil
+	}
+	offset, ok := twoBit.offsets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSequence, name)
+	}
+
+	reader := io.NewSectionReader(twoBit.r, offset, math.MaxInt64-offset)
+	truncated := fmt.Errorf("%w: header of %q is cut short", ErrTruncated, name)
+	var size uint32
+	if err := binary.Read(reader, twoBit.byteOrder, &size); err != nil {
+		return nil, truncated
+	}
+	nBlocks, err := readBlocks(reader, twoBit.byteOrder, size)
+	if err != nil {
+		return nil, truncated
+	}
+	maskBlocks, err := readBlocks(reader, twoBit.byteOrder, size)
+	if err != nil {
+		return nil, truncated
+	}
+	var reserved uint32
+	if err := binary.Read(reader, twoBit.byteOrder, &reserved); err != nil {
+		return nil, truncated
+	}
+	packedStart, _ := reader.Seek(0, io.SeekCurrent)
+
+	header := &recordHeader{size: int(size), nBlocks: nBlocks, maskBlocks: maskBlocks, packedStart: offset + packedStart}
+	twoBit.headers[name] = header
+	return header, nil
+}
+
+// readBlocks reads a block count followed by that many starts and then that many sizes.
+// There can't be more blocks than bases so a bigger count means the file is broken.
+func readBlocks(r io.Reader, byteOrder binary.ByteOrder, size uint32) ([]block, error) {
+	var count uint32
+	if err := binary.Read(r, byteOrder, &count); err != nil {
+		return nil, err
+	}
+	if count > size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	starts := make([]uint32, count)
+	sizes := make([]uint32, count)
+	if err := binary.Read(r, byteOrder, starts); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(r, byteOrder, sizes); err != nil {
+		return nil, err
+	}
+	blocks := make([]block, count)
+	for blockIndex := range blocks {
+		blocks[blockIndex] = block{Start: int(starts[blockIndex]), End: int(starts[blockIndex] + sizes[blockIndex])}
+	}
+	return blocks, nil
+}
+
+// Fetch returns the bases from start up to but not including end (0-based like the rest of poly) of the sequence called name.
+// N blocks come back as Ns and masked bases in lower case unless Uppercase is set.
+func (twoBit *TwoBit) Fetch(name string, start, end int) (string, error) {
+	header, err := twoBit.header(name)
+	if err != nil {
+		return "", err
+	}
+	if start < 0 || end > header.size || start > end {
+		return "", fmt.Errorf("%w: %d to %d of %q which is %d long", ErrInvalidRange, start, end, name, header.size)
+	}
+	if start == end {
+		return "", nil
+	}
+
+	packed := make([]byte, (end-1)/4-start/4+1)
+	if _, err := twoBit.r.ReadAt(packed, header.packedStart+int64(start/4)); err != nil {
+		return "", fmt.Errorf("%w: bases of %q are cut short", ErrTruncated, name)
+	}
+	sequence := make([]byte, end-start)
+	for position := start; position < end; position++ {
+		packedByte := packed[position/4-start/4]
+		shift := 6 - 2*uint(position%4)
+		sequence[position-start] = packedBases[packedByte>>shift&3]
+	}
+
+	for _, nBlock := range overlapping(header.nBlocks, start, end) {
+		for position := nBlock.Start; position < nBlock.End; position++ {
+			sequence[position-start] = 'N'
+		}
+	}
+	if !twoBit.Uppercase {
+		for _, maskBlock := range overlapping(header.maskBlocks, start, end) {
+			for position := maskBlock.Start; position < maskBlock.End; position++ {
+				sequence[position-start] += 'a' - 'A'
+			}
+		}
+	}
+	return string(sequence), nil
+}
+
+// overlapping returns the parts of sorted, non-overlapping blocks that fall between start and end.
+func overlapping(blocks []block, start, end int) []block {
+	first := sort.Search(len(blocks), func(blockIndex int) bool { return blocks[blockIndex].End > start })
+	var overlaps []block
+	for _, overlap := range blocks[first:] {
+		if overlap.Start >= end {
+			break
+		}
+		if overlap.Start < start {
+			overlap.Start = start
+		}
+		if overlap.End > end {
+			overlap.End = end
+		}
+		overlaps = append(overlaps, overlap)
+	}
+	return overlaps
+}
+
+// Fasta returns the whole sequence called name as a fasta.Fasta.
+func (twoBit *TwoBit) Fasta(name string) (fasta.Fasta, error) {
+	length, err := twoBit.Length(name)
+	if err != nil {
+		return fasta.Fasta{}, err
+	}
+	sequence, err := twoBit.Fetch(name, 0, length)
+	return fasta.Fasta{Name: name, Sequence: sequence}, err
+}
+
+// Sequence returns the whole sequence called name as a poly.Sequence. N blocks become Meta.Gaps.
+func (twoBit *TwoBit) Sequence(name string) (poly.Sequence, error) {
+	record, err := twoBit.Fasta(name)
+	if err != nil {
+		return poly.Sequence{}, err
+	}
+	header, _ := twoBit.header(name)
+
+	var sequence poly.Sequence
+	sequence.Meta.Name = name
+	sequence.Meta.Locus.Name = name
+	sequence.Meta.Locus.SequenceLength = strconv.Itoa(header.size)
+	sequence.Meta.Locus.SequenceCoding = "bp"
+	sequence.Meta.Locus.MoleculeType = "DNA"
+	for _, nBlock := range header.nBlocks {
+		sequence.Meta.Gaps = append(sequence.Meta.Gaps, poly.Gap{Start: nBlock.Start, End: nBlock.End})
+	}
+	sequence.Sequence = record.Sequence
+	return sequence, nil
+}
+
+// StreamFasta sends every sequence, in file order, down a channel and closes it.
+// It stops at the first error, which is returned.
+func (twoBit *TwoBit) StreamFasta(sequences chan<- fasta.Fasta) error {
+	defer close(sequences)
+	for _, name := range twoBit.names {
+		record, err := twoBit.Fasta(name)
+		if err != nil {
+			return err
+		}
+		sequences <- record
+	}
+	return nil
+}
+
+// StreamSequences sends every sequence, in file order, down a channel as poly.Sequences and closes it.
+// It stops at the first error, which is returned.
+func (twoBit *TwoBit) StreamSequences(sequences chan<- poly.Sequence) error {
+	defer close(sequences)
+	for _, name := range twoBit.names {
+		sequence, err := twoBit.Sequence(name)
+		if err != nil {
+			return err
+		}
+		sequences <- sequence
+	}
+	return nil
+}
+
+// ReadConcurrent reads every sequence in a 2bit file into a fasta.Fasta channel, closing it when it's done or at the
+// first error, which is returned. It blocks until the whole file has been read so run it in its own goroutine.
+func ReadConcurrent(path string, sequences chan<- fasta.Fasta) error {
+	twoBit, err := Open(path)
+	if err != nil {
+		close(sequences)
+		return err
+	}
+	defer twoBit.Close()
+	return twoBit.StreamFasta(sequences)
+}
+
+// Read reads every sequence in a 2bit file into an array of fasta.Fasta structs.
+func Read(path string) ([]fasta.Fasta, error) {
+	twoBit, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer twoBit.Close()
+
+	var fastas []fasta.Fasta
+	for _, name := range twoBit.names {
+		record, err := twoBit.Fasta(name)
+		if err != nil {
+			return nil, err
+		}
+		fastas = append(fastas, record)
+	}
+	return fastas, nil
+}
+
+/******************************************************************************
+
+Start of 2bit Write functions
+
+******************************************************************************/
+
+// Build packs fasta records into a 2bit file. Names are cut off at the first whitespace and have to be unique.
+// Files are written little endian as version 0 unless they're too big, in which case they're written as version 1.
+func Build(fastas []fasta.Fasta) ([]byte, error) {
+	names := make([]string, len(fastas))
+	seen := map[string]bool{}
+	for fastaIndex, record := range fastas {
+		fields := strings.Fields(record.Name)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("%w: record %d has no name", ErrInvalidName, fastaIndex+1)
+		}
+		name := fields[0]
+		if len(name) > math.MaxUint8 {
+			return nil, fmt.Errorf("%w: %q is longer than 255 characters", ErrInvalidName, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %q is used more than once", ErrInvalidName, name)
+		}
+		if uint64(len(record.Sequence)) > math.MaxUint32 {
+			return nil, fmt.Errorf("%w: %q is longer than 2bit can hold", ErrInvalidRange, name)
+		}
+		seen[name] = true
+		names[fastaIndex] = name
+	}
+
+	records := make([][]byte, len(fastas))
+	for fastaIndex, record := range fastas {
+		records[fastaIndex] = buildRecord(record.Sequence)
+	}
+
+	// offsets can only be worked out once we know how big the index is, which depends on the version.
+	version := uint32(0)
+	offsetSize := 4
+	indexSize := func() int64 {
+		size := int64(16)
+		for _, name := range names {
+			size += 1 + int64(len(name)) + int64(offsetSize)
+		}
+		return size
+	}
+	totalSize := indexSize()
+	for _, record := range records {
+		totalSize += int64(len(record))
+	}
+	lastOffset := totalSize
+	if len(records) > 0 {
+		lastOffset -= int64(len(records[len(records)-1]))
+	}
+	if lastOffset > math.MaxUint32 {
+		version = 1
+		offsetSize = 8
+	}
+
+	var twoBit bytes.Buffer
+	byteOrder := binary.LittleEndian
+	_ = binary.Write(&twoBit, byteOrder, []uint32{signature, version, uint32(len(fastas)), 0})
+	offset := indexSize()
+	for recordIndex, name := range names {
+		twoBit.WriteByte(byte(len(name)))
+		twoBit.WriteString(name)
+		if version == 0 {
+			_ = binary.Write(&twoBit, byteOrder, uint32(offset))
+		} else {
+			_ = binary.Write(&twoBit, byteOrder, uint64(offset))
+		}
+		offset += int64(len(records[recordIndex]))
+	}
+	for _, record := range records {
+		twoBit.Write(record)
+	}
+	return twoBit.Bytes(), nil
+}
+
+// buildRecord packs a single sequence with its N and mask blocks.
+func buildRecord(sequence string) []byte {
+	var nBlocks, maskBlocks []block
+	packed := make([]byte, (len(sequence)+3)/4)
+	for position := 0; position < len(sequence); position++ {
+		base := sequence[position]
+		lower := base >= 'a' && base <= 'z'
+		if lower {
+			base -= 'a' - 'A'
+		}
+
+		code := strings.IndexByte(packedBases, base)
+		if code == -1 {
+			// Ns are packed as Ts and put back by the N blocks.
+			code = 0
+			nBlocks = extendBlocks(nBlocks, position)
+		}
+		if lower {
+			maskBlocks = extendBlocks(maskBlocks, position)
+		}
+		packed[position/4] |= byte(code) << (6 - 2*uint(position%4))
+	}
+
+	var record bytes.Buffer
+	byteOrder := binary.LittleEndian
+	_ = binary.Write(&record, byteOrder, uint32(len(sequence)))
+	writeBlocks(&record, byteOrder, nBlocks)
+	writeBlocks(&record, byteOrder, maskBlocks)
+	_ = binary.Write(&record, byteOrder, uint32(0))
+	record.Write(packed)
+	return record.Bytes()
+}
+
+// extendBlocks adds position to the last block if it's right after it or starts a new block if it isn't.
+func extendBlocks(blocks []block, position int) []block {
+	if len(blocks) > 0 && blocks[len(blocks)-1].End == position {
+		blocks[len(blocks)-1].End++
+		return blocks
+	}
+	return append(blocks, block{Start: position, End: position + 1})
+}
+
+// writeBlocks writes a block count followed by every start and then every size.
+func writeBlocks(w io.Writer, byteOrder binary.ByteOrder, blocks []block) {
+	_ = binary.Write(w, byteOrder, uint32(len(blocks)))
+	for _, block := range blocks {
+		_ = binary.Write(w, byteOrder, uint32(block.Start))
+	}
+	for _, block := range blocks {
+		_ = binary.Write(w, byteOrder, uint32(block.End-block.Start))
+	}
+}
+
+// Write packs fasta records into a 2bit file.
+func Write(fastas []fasta.Fasta, path string) error {
+	twoBit, err := Build(fastas)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, twoBit, 0644)
+}
+
+/******************************************************************************
+
+2bit specific IO related things end here.
+
+******************************************************************************/
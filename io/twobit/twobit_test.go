@@ -0,0 +1,317 @@
+package twobit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Open-Science-Global/poly"
+	"github.com/Open-Science-Global/poly/io/fasta"
+	"github.com/google/go-cmp/cmp"
+)
+
+// ExampleOpen shows how to pull a region out of a 2bit file.
+func ExampleOpen() {
+	twoBit, _ := Open("data/sample.2bit")
+	defer twoBit.Close()
+
+	fmt.Println(twoBit.Names())
+	sequence, _ := twoBit.Fetch("chr1", 5, 15)
+	fmt.Println(sequence)
+	sequence, _ = twoBit.Fetch("chr1", 135, 145)
+	fmt.Println(sequence)
+
+	twoBit.Uppercase = true
+	sequence, _ = twoBit.Fetch("chr1", 135, 145)
+	fmt.Println(sequence)
+	// Output:
+	// [chr1 chrM scaffold_3]
+	// NNNNNTCCCC
+	// tacggACACA
+	// TACGGACACA
+}
+
+// ExampleRead shows basic usage for Read.
+func ExampleRead() {
+	fastas, _ := Read("data/sample.2bit")
+	fmt.Println(fastas[2].Name, fastas[2].Sequence)
+	// Output: scaffold_3 nagagatNTGCCCACCT
+}
+
+// ExampleReadConcurrent shows how to stream every sequence in a 2bit file.
+func ExampleReadConcurrent() {
+	fastas := make(chan fasta.Fasta, 10)
+	errs := make(chan error, 1)
+	go func() { errs <- ReadConcurrent("data/sample.2bit", fastas) }()
+	var bases int
+	for record := range fastas {
+		bases += len(record.Sequence)
+	}
+	if err := <-errs; err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(bases)
+	// Output: 1257
+}
+
+// ExampleWrite shows how to pack a fasta file into a 2bit file.
+func ExampleWrite() {
+	fastas, _ := fasta.Read("data/sample.fasta")
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	defer os.RemoveAll(tmpDataDir)
+
+	path := tmpDataDir + "/sample.2bit"
+	_ = Write(fastas, path)
+	twoBitFastas, _ := Read(path)
+	fmt.Println(twoBitFastas[0].Sequence == fastas[0].Sequence)
+	// Output: true
+}
+
+func TestRead(t *testing.T) {
+	fastas, err := fasta.Read("data/sample.fasta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fastaIndex := range fastas {
+		fastas[fastaIndex].Name = strings.Fields(fastas[fastaIndex].Name)[0]
+	}
+
+	// both byte orders UCSC tools can write.
+	for _, path := range []string{"data/sample.2bit", "data/sample_bigendian.2bit"} {
+		twoBitFastas, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(fastas, twoBitFastas); diff != "" {
+			t.Errorf("%s was not read (-want +got):\n%s", path, diff)
+		}
+	}
+}
+
+func TestReadVersion1(t *testing.T) {
+	// version 1 only differs in having 64 bit offsets in the index.
+	file, _ := ioutil.ReadFile("data/sample.2bit")
+	reader, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := reader.Names()
+	extraIndex := uint32(4 * len(names))
+
+	var version1 bytes.Buffer
+	_ = binary.Write(&version1, binary.LittleEndian, []uint32{signature, 1, uint32(len(names)), 0})
+	position := 16
+	for _, name := range names {
+		version1.Write(file[position : position+1+len(name)])
+		position += 1 + len(name)
+		_ = binary.Write(&version1, binary.LittleEndian, uint64(binary.LittleEndian.Uint32(file[position:])+extraIndex))
+		position += 4
+	}
+	version1.Write(file[position:])
+
+	expected, _ := Read("data/sample.2bit")
+	reader, err = NewReader(bytes.NewReader(version1.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for recordIndex, name := range reader.Names() {
+		record, err := reader.Fasta(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(expected[recordIndex], record); diff != "" {
+			t.Errorf("Version 1 file was not read (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	fastas, _ := fasta.Read("data/sample.fasta")
+	twoBit, err := Open("data/sample.2bit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer twoBit.Close()
+
+	for fastaIndex, name := range twoBit.Names() {
+		expected := fastas[fastaIndex].Sequence
+		length, err := twoBit.Length(name)
+		if err != nil || length != len(expected) {
+			t.Fatalf("%s: expected a length of %d. Got %d, %v", name, len(expected), length, err)
+		}
+		// every range that starts or ends in every position of a packed byte and in and around the blocks.
+		for start := 0; start < length; start++ {
+			for _, end := range []int{start, start + 1, start + 2, start + 3, start + 4, start + 5, start + 13, length} {
+				if end > length {
+					continue
+				}
+				sequence, err := twoBit.Fetch(name, start, end)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sequence != expected[start:end] {
+					t.Fatalf("%s %d-%d: expected %q. Got %q", name, start, end, expected[start:end], sequence)
+				}
+			}
+		}
+	}
+
+	if _, err := twoBit.Fetch("chrM", 10, 1001); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange past the end. Got: %v", err)
+	}
+	if _, err := twoBit.Fetch("chrM", 10, 9); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange for a backwards range. Got: %v", err)
+	}
+	if _, err := twoBit.Fetch("chrX", 0, 1); !errors.Is(err, ErrUnknownSequence) {
+		t.Errorf("Expected ErrUnknownSequence. Got: %v", err)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	twoBit, err := Open("data/sample.2bit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer twoBit.Close()
+
+	sequence, err := twoBit.Sequence("chr1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedLocus := poly.Locus{Name: "chr1", SequenceLength: "240", MoleculeType: "DNA", SequenceCoding: "bp"}
+	if diff := cmp.Diff(expectedLocus, sequence.Meta.Locus); diff != "" {
+		t.Errorf("Locus was not filled in (-want +got):\n%s", diff)
+	}
+	expectedGaps := []poly.Gap{{Start: 0, End: 10}, {Start: 197, End: 206}}
+	if diff := cmp.Diff(expectedGaps, sequence.Meta.Gaps); diff != "" {
+		t.Errorf("N blocks did not become gaps (-want +got):\n%s", diff)
+	}
+
+	sequences := make(chan poly.Sequence)
+	go func() { _ = twoBit.StreamSequences(sequences) }()
+	var names []string
+	for sequence := range sequences {
+		names = append(names, sequence.Meta.Name)
+	}
+	if diff := cmp.Diff(twoBit.Names(), names); diff != "" {
+		t.Errorf("Sequences were not streamed in order (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	fastas, _ := fasta.Read("data/sample.fasta")
+	twoBit, err := Build(fastas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := ioutil.ReadFile("data/sample.2bit")
+	if !bytes.Equal(expected, twoBit) {
+		t.Errorf("Build does not match the 2bit file made by the spec")
+	}
+
+	// anything that isn't ACGT becomes an N, case and all.
+	reader, err := NewReader(bytes.NewReader(mustBuild(t, []fasta.Fasta{{Name: "iupac", Sequence: "ACRYgtwsNn"}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sequence, _ := reader.Fetch("iupac", 0, 10)
+	if sequence != "ACNNgtnnNn" {
+		t.Errorf("Expected ambiguous bases to become Ns. Got %q", sequence)
+	}
+
+	empty, err := NewReader(bytes.NewReader(mustBuild(t, []fasta.Fasta{{Name: "empty"}})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length, err := empty.Length("empty"); err != nil || length != 0 {
+		t.Errorf("Expected an empty sequence. Got %d, %v", length, err)
+	}
+}
+
+func mustBuild(t *testing.T, fastas []fasta.Fasta) []byte {
+	twoBit, err := Build(fastas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return twoBit
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fastas []fasta.Fasta
+	}{
+		{name: "no name", fastas: []fasta.Fasta{{Name: " ", Sequence: "ACGT"}}},
+		{name: "duplicate name", fastas: []fasta.Fasta{{Name: "a one", Sequence: "ACGT"}, {Name: "a two", Sequence: "ACGT"}}},
+		{name: "long name", fastas: []fasta.Fasta{{Name: strings.Repeat("a", 256), Sequence: "ACGT"}}},
+	}
+	for _, test := range tests {
+		if _, err := Build(test.fastas); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%s: expected ErrInvalidName. Got: %v", test.name, err)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	file, _ := ioutil.ReadFile("data/sample.2bit")
+
+	if _, err := NewReader(bytes.NewReader([]byte(">chr1\nACGT\n"))); !errors.Is(err, ErrNotTwoBit) {
+		t.Errorf("Expected ErrNotTwoBit. Got: %v", err)
+	}
+
+	version := append([]byte{}, file...)
+	version[4] = 2
+	if _, err := NewReader(bytes.NewReader(version)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion. Got: %v", err)
+	}
+
+	if _, err := NewReader(bytes.NewReader(file[:30])); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated for a cut off index. Got: %v", err)
+	}
+
+	// the index is fine but the last sequence isn't all there.
+	truncated, err := NewReader(bytes.NewReader(file[:len(file)-2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := truncated.Fasta("scaffold_3"); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated for cut off bases. Got: %v", err)
+	}
+
+	// a broken block count shouldn't try to allocate gigabytes.
+	blocks := append([]byte{}, file...)
+	chrMIndex := 16 + 1 + len("chr1") + 4 + 1 + len("chrM")
+	chrMOffset := binary.LittleEndian.Uint32(blocks[chrMIndex:])
+	binary.LittleEndian.PutUint32(blocks[chrMOffset+4:], math.MaxUint32)
+	broken, err := NewReader(bytes.NewReader(blocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broken.Length("chrM"); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated for a nonsense header. Got: %v", err)
+	}
+
+	tmpDataDir, err := ioutil.TempDir("", "data-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDataDir)
+	truncatedPath := tmpDataDir + "/truncated.2bit"
+	_ = ioutil.WriteFile(truncatedPath, file[:len(file)-2], 0644)
+	if err := ReadConcurrent(truncatedPath, make(chan fasta.Fasta, 10)); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated from ReadConcurrent. Got: %v", err)
+	}
+
+	if err := ReadConcurrent("data/missing.2bit", make(chan fasta.Fasta)); !os.IsNotExist(err) {
+		t.Errorf("Expected a missing file error. Got: %v", err)
+	}
+}
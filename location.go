@@ -0,0 +1,157 @@
+package poly
+
+import "strconv"
+
+/******************************************************************************
+
+Location helpers begin here.
+
+Circular sequences like plasmids don't really have a start or an end, so
+features are free to run across the origin. Poly models that as a Location
+whose End is before its Start, e.g. Start: 2600, End: 10 on a 2686 bp plasmid
+covers 2600..2686 and then 0..10.
+
+File formats don't always agree with that. GenBank writes origin spanning
+features as join(2601..2686,1..10) and GFF3 writes them with an end past the
+length of the sequence. SplitAtOrigin and MergeAtOrigin convert between the
+join form and poly's form.
+
+******************************************************************************/
+
+// SpansOrigin reports whether a location, or any of its sub locations, crosses the origin of a circular sequence.
+func (location Location) SpansOrigin() bool {
+	if len(location.SubLocations) == 0 {
+		return location.End < location.Start
+	}
+	for _, subLocation := range location.SubLocations {
+		if subLocation.SpansOrigin() {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitAtOrigin returns a copy of location where every origin spanning range is split
+// into a join of the range up to the end of the sequence and the range from the origin.
+func (location Location) SplitAtOrigin(sequenceLength int) Location {
+	if len(location.SubLocations) == 0 {
+		if !location.SpansOrigin() {
+			return location
+		}
+		head := Location{Start: location.Start, End: sequenceLength, FivePrimePartial: location.FivePrimePartial}
+		tail := Location{Start: 0, End: location.End, ThreePrimePartial: location.ThreePrimePartial}
+		return Location{Join: true, Complement: location.Complement, SubLocations: []Location{head, tail}}
+	}
+
+	subLocations := []Location{}
+	for _, subLocation := range location.SubLocations {
+		splitSubLocation := subLocation.SplitAtOrigin(sequenceLength)
+		if location.Join && len(subLocation.SubLocations) == 0 && subLocation.SpansOrigin() {
+			subLocations = append(subLocations, flattenJoin(splitSubLocation)...)
+		} else {
+			subLocations = append(subLocations, splitSubLocation)
+		}
+	}
+	location.SubLocations = subLocations
+	return location
+}
+
+// MergeAtOrigin is the inverse of SplitAtOrigin. Joins of exactly two ranges that meet
+// at the origin of a circular sequence are merged into a single origin spanning range.
+func (location Location) MergeAtOrigin(sequenceLength int) Location {
+	if len(location.SubLocations) == 0 {
+		return location
+	}
+
+	subLocations := make([]Location, len(location.SubLocations))
+	for subLocationIndex, subLocation := range location.SubLocations {
+		subLocations[subLocationIndex] = subLocation.MergeAtOrigin(sequenceLength)
+	}
+	location.SubLocations = subLocations
+	if !location.Join || len(location.SubLocations) != 2 {
+		return location
+	}
+
+	first, second := location.SubLocations[0], location.SubLocations[1]
+	if len(first.SubLocations) != 0 || len(second.SubLocations) != 0 || first.Complement != second.Complement {
+		return location
+	}
+
+	// join(complement(1..10),complement(2600..2686)) is the reverse strand way of writing the same thing.
+	if first.Complement && first.Start == 0 && second.End == sequenceLength {
+		first, second = second, first
+	}
+	if first.End != sequenceLength || second.Start != 0 || second.End >= first.Start {
+		return location
+	}
+
+	return Location{
+		Start:             first.Start,
+		End:               second.End,
+		Complement:        first.Complement != location.Complement,
+		FivePrimePartial:  first.FivePrimePartial,
+		ThreePrimePartial: second.ThreePrimePartial,
+	}
+}
+
+// Ranges returns the plain ranges a location is made of in the order they're read, so complement(join(a,b))
+// gives complement(b),complement(a). A location without sub locations is its own single range.
+func (location Location) Ranges() []Location {
+	if len(location.SubLocations) == 0 {
+		return []Location{location}
+	}
+	var ranges []Location
+	for _, subLocation := range flattenJoin(location) {
+		ranges = append(ranges, subLocation.Ranges()...)
+	}
+	return ranges
+}
+
+// Strand returns the strand a location is read off the way gff, gtf and bed write it: "-" for complements,
+// including joins of complements, and "+" for everything else.
+func (location Location) Strand() string {
+	if location.Complement || (len(location.SubLocations) > 0 && location.SubLocations[0].Complement) {
+		return "-"
+	}
+	return "+"
+}
+
+// Phases returns the phase gff and gtf give each of the location's Ranges when it is a CDS: the number of bases at
+// the start of the part before its first complete codon. codonStart is genbank's /codon_start, where the first
+// codon starts in the first part, and anything but 1, 2 or 3 is taken as 1.
+func (location Location) Phases(codonStart string) []string {
+	firstPhase, err := strconv.Atoi(codonStart)
+	if err != nil || firstPhase < 1 || firstPhase > 3 {
+		firstPhase = 1
+	}
+	firstPhase--
+
+	parts := location.Ranges()
+	phases := make([]string, len(parts))
+	translated := 0 // bases before the current part.
+	for partIndex, part := range parts {
+		phases[partIndex] = strconv.Itoa(((firstPhase-translated)%3 + 3) % 3)
+		translated += part.End - part.Start
+	}
+	return phases
+}
+
+// flattenJoin turns a join of leaves into the list of leaves it reads as inside of a parent join.
+// complement(join(a,b)) reads as complement(b),complement(a).
+func flattenJoin(location Location) []Location {
+	if !location.Complement {
+		return location.SubLocations
+	}
+	leaves := make([]Location, len(location.SubLocations))
+	for index, subLocation := range location.SubLocations {
+		subLocation.Complement = !subLocation.Complement
+		leaves[len(leaves)-1-index] = subLocation
+	}
+	return leaves
+}
+
+/******************************************************************************
+
+Location helpers end here.
+
+******************************************************************************/
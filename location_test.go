@@ -0,0 +1,157 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Open-Science-Global/poly/transform"
+)
+
+func ExampleLocation_SplitAtOrigin() {
+	// a feature covering the last two and first three bases of a 10 bp circular sequence.
+	location := Location{Start: 8, End: 3}
+
+	split := location.SplitAtOrigin(10)
+	fmt.Println(split.SubLocations[0].Start, split.SubLocations[0].End, split.SubLocations[1].Start, split.SubLocations[1].End)
+	// Output: 8 10 0 3
+}
+
+func TestLocation_MergeAtOrigin(t *testing.T) {
+	forward := Location{Join: true, SubLocations: []Location{{Start: 8, End: 10}, {Start: 0, End: 3}}}
+	merged := forward.MergeAtOrigin(10)
+	if merged.Start != 8 || merged.End != 3 || merged.Complement || !merged.SpansOrigin() {
+		t.Errorf("MergeAtOrigin failed on forward join. Got: %+v", merged)
+	}
+
+	complementJoin := Location{Join: true, Complement: true, SubLocations: []Location{{Start: 8, End: 10}, {Start: 0, End: 3}}}
+	merged = complementJoin.MergeAtOrigin(10)
+	if merged.Start != 8 || merged.End != 3 || !merged.Complement {
+		t.Errorf("MergeAtOrigin failed on complement(join()). Got: %+v", merged)
+	}
+
+	joinComplements := Location{Join: true, SubLocations: []Location{{Start: 0, End: 3, Complement: true}, {Start: 8, End: 10, Complement: true}}}
+	merged = joinComplements.MergeAtOrigin(10)
+	if merged.Start != 8 || merged.End != 3 || !merged.Complement {
+		t.Errorf("MergeAtOrigin failed on join(complement(),complement()). Got: %+v", merged)
+	}
+
+	notAtOrigin := Location{Join: true, SubLocations: []Location{{Start: 6, End: 8}, {Start: 0, End: 3}}}
+	if merged = notAtOrigin.MergeAtOrigin(10); !merged.Join {
+		t.Errorf("MergeAtOrigin should not merge ranges that don't meet at the origin. Got: %+v", merged)
+	}
+
+	// splitting and merging should get us back to where we started.
+	location := Location{Start: 8, End: 3, Complement: true, FivePrimePartial: true}
+	if roundTrip := location.SplitAtOrigin(10).MergeAtOrigin(10); roundTrip.Start != 8 || roundTrip.End != 3 || !roundTrip.Complement || !roundTrip.FivePrimePartial {
+		t.Errorf("SplitAtOrigin and MergeAtOrigin do not round trip. Got: %+v", roundTrip)
+	}
+}
+
+func ExampleLocation_Ranges() {
+	location := Location{Join: true, Complement: true, SubLocations: []Location{{Start: 0, End: 3}, {Start: 5, End: 9}}}
+
+	for _, part := range location.Ranges() {
+		fmt.Println(part.Start, part.End, part.Complement)
+	}
+	// Output:
+	// 5 9 true
+	// 0 3 true
+}
+
+func ExampleLocation_Phases() {
+	// a spliced CDS on the reverse strand is read from its last part back.
+	location := Location{Join: true, Complement: true, SubLocations: []Location{{Start: 0, End: 10}, {Start: 20, End: 24}}}
+
+	fmt.Println(location.Phases("1"))
+	fmt.Println(location.Phases("2"))
+	// Output:
+	// [0 2]
+	// [1 0]
+}
+
+func TestFeature_GetSequenceAcrossOrigin(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACC"
+	sequence.Meta.Locus.Circular = true
+
+	forward := Feature{SequenceLocation: Location{Start: 8, End: 3}}
+	reverse := Feature{SequenceLocation: Location{Start: 8, End: 3, Complement: true}}
+	pastEnd := Feature{SequenceLocation: Location{Start: 8, End: 13}}
+	sequence.AddFeature(&forward)
+	sequence.AddFeature(&reverse)
+	sequence.AddFeature(&pastEnd)
+
+	if got := forward.GetSequence(); got != "CCGGG" {
+		t.Errorf("GetSequence failed across origin. Got: %s", got)
+	}
+	if got := reverse.GetSequence(); got != transform.ReverseComplement("CCGGG") {
+		t.Errorf("GetSequence failed across origin on complement strand. Got: %s", got)
+	}
+	if got := pastEnd.GetSequence(); got != "CCGGG" {
+		t.Errorf("GetSequence failed for end past the length of the sequence. Got: %s", got)
+	}
+}
+
+func TestSequence_EditAcrossOrigin(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACC"
+	sequence.Meta.Locus.Circular = true
+
+	feature := Feature{SequenceLocation: Location{Start: 8, End: 3}}
+	sequence.AddFeature(&feature)
+
+	if err := sequence.Insert(5, "TT"); err != nil {
+		t.Fatal(err)
+	}
+	location := sequence.Features[0].SequenceLocation
+	if location.Start != 10 || location.End != 3 || !location.SpansOrigin() {
+		t.Errorf("Insert did not shift origin spanning feature. Got: %+v", location)
+	}
+	if got := sequence.Features[0].GetSequence(); got != "CCGGG" {
+		t.Errorf("Insert broke origin spanning feature. Got: %s", got)
+	}
+
+	if err := sequence.Delete(0, 1, MarkPartial); err != nil {
+		t.Fatal(err)
+	}
+	if got := sequence.Features[0].GetSequence(); got != "CCGG" {
+		t.Errorf("Delete broke origin spanning feature. Got: %s", got)
+	}
+}
+
+func TestFeature_GetSequenceSitesAndRemotes(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "GGGAAAAACC"
+
+	site := Feature{SequenceLocation: Location{Start: 3, End: 3, Between: true}}
+	remote := Feature{SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 0, End: 3}, {Start: 99, End: 202, Accession: "J00194.1"}}}}
+	sequence.AddFeature(&site)
+	sequence.AddFeature(&remote)
+
+	if got := site.GetSequence(); got != "" {
+		t.Errorf("Sites between bases should have no sequence. Got: %s", got)
+	}
+	if got := remote.GetSequence(); got != "GGG" {
+		t.Errorf("Remote locations should be skipped. Got: %s", got)
+	}
+
+	// 10^1 is the site at the origin of a circular sequence. It has an End before its Start like other wraps.
+	sequence.Meta.Locus.Circular = true
+	origin := Feature{SequenceLocation: Location{Start: 10, End: 0, Between: true}}
+	around := Feature{SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 8, End: 10}, {Start: 10, End: 0, Between: true}, {Start: 0, End: 3}}}}
+	sequence.AddFeature(&origin)
+	sequence.AddFeature(&around)
+	if got := origin.GetSequence(); got != "" {
+		t.Errorf("The site at the origin should have no sequence. Got: %s", got)
+	}
+	if got := around.GetSequence(); got != "CCGGG" {
+		t.Errorf("The site at the origin should add nothing to a join. Got: %s", got)
+	}
+	if issues := sequence.Validate(); len(issues) != 0 {
+		t.Errorf("The site at the origin of a circular sequence should be valid. Got: %v", issues)
+	}
+	sequence.Meta.Locus.Circular = false
+	if issues := sequence.Validate(); len(issues) == 0 {
+		t.Errorf("The site at the origin of a linear sequence should not be valid.")
+	}
+}
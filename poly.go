@@ -2,6 +2,7 @@ package poly
 
 import (
 	"bytes"
+	"encoding/json"
 
 	"github.com/Open-Science-Global/poly/transform"
 )
@@ -39,7 +40,14 @@ type Meta struct {
 	Origin      string            `json:"origin"`
 	Locus       Locus             `json:"locus"`
 	References  []Reference       `json:"references"`
+	Contig      string            `json:"contig"`     // join of other records this record is assembled from. See genbank.ExpandContig.
+	WGS         string            `json:"wgs"`        // range of whole genome shotgun contigs like AAAA01000001-AAAA01000500.
+	WGSScaffold string            `json:"wgs_scafld"` // range of whole genome shotgun scaffolds.
+	Gaps        []Gap             `json:"gaps"`       // runs of unknown bases in an assembly.
 	Other       map[string]string `json:"other"`
+	// GbkSections holds the top level sections of a genbank file as they were written, in order, so Build can
+	// write untouched sections back out byte for byte. FEATURES and ORIGIN only keep their own line.
+	GbkSections []string `json:"gbk_sections"`
 }
 
 // Reference holds information one reference in a Meta struct.
@@ -53,6 +61,16 @@ type Reference struct {
 	Range   string `json:"range"`
 }
 
+// Gap is a run of unknown bases in an assembled sequence, usually filled in with Ns. Start and End are 0-based
+// and half-open like a Location. Type and LinkageEvidence hold the INSDC /gap_type and /linkage_evidence values.
+type Gap struct {
+	Start           int      `json:"start"`
+	End             int      `json:"end"`
+	UnknownLength   bool     `json:"unknown_length"` // the length of the gap is only a placeholder.
+	Type            string   `json:"type"`
+	LinkageEvidence []string `json:"linkage_evidence"`
+}
+
 // Locus holds Locus information in a Meta struct.
 type Locus struct {
 	Name             string `json:"name"`
@@ -65,14 +83,24 @@ type Locus struct {
 	Linear           bool   `json:"linear"`
 }
 
-// Location holds nested location info for sequence region.
+// Location holds nested location info for sequence region. On circular sequences
+// a Location whose End is before its Start spans the origin. See SpansOrigin.
+//
+// Join, Order and Bond combine SubLocations. Between marks a site between two bases like 123^124
+// and SingleBase marks a single unknown base within a range like 102.110. Accession is set for
+// locations that point into another entry like J00194.1:100..202.
 type Location struct {
 	Start             int        `json:"start"`
 	End               int        `json:"end"`
 	Complement        bool       `json:"complement"`
 	Join              bool       `json:"join"`
+	Order             bool       `json:"order"`
+	Bond              bool       `json:"bond"`
+	Between           bool       `json:"between"`
+	SingleBase        bool       `json:"single_base"`
 	FivePrimePartial  bool       `json:"five_prime_partial"`
 	ThreePrimePartial bool       `json:"three_prime_partial"`
+	Accession         string     `json:"accession"`
 	SubLocations      []Location `json:"sub_locations"`
 }
 
@@ -80,19 +108,65 @@ type Location struct {
 type Feature struct {
 	Name string //Seqid in gff, name in gbk
 	//gff specific
-	Source               string            `json:"source"`
-	Type                 string            `json:"type"`
-	Score                string            `json:"score"`
-	Strand               string            `json:"strand"`
-	Phase                string            `json:"phase"`
-	Attributes           map[string]string `json:"attributes"`
-	GbkLocationString    string            `json:"gbk_location_string"`
-	Sequence             string            `json:"sequence"`
-	SequenceLocation     Location          `json:"sequence_location"`
-	SequenceHash         string            `json:"sequence_hash"`
-	Description          string            `json:"description"`
-	SequenceHashFunction string            `json:"hash_function"`
-	ParentSequence       *Sequence         `json:"-"`
+	Source               string     `json:"source"`
+	Type                 string     `json:"type"`
+	Score                string     `json:"score"`
+	Strand               string     `json:"strand"`
+	Phase                string     `json:"phase"`
+	Attributes           Attributes `json:"attributes"`
+	GbkLocationString    string     `json:"gbk_location_string"`
+	GbkFeatureString     string     `json:"gbk_feature_string"` // the feature's lines as they were written in a genbank file.
+	Sequence             string     `json:"sequence"`
+	SequenceLocation     Location   `json:"sequence_location"`
+	SequenceHash         string     `json:"sequence_hash"`
+	Description          string     `json:"description"`
+	SequenceHashFunction string     `json:"hash_function"`
+	ParentSequence       *Sequence  `json:"-"`
+}
+
+// Attributes holds the qualifiers of a Feature. Qualifiers like /db_xref in genbank can be repeated and
+// gff attributes can hold comma separated values so every key maps to its values in the order they were read.
+type Attributes map[string][]string
+
+// Get returns the first value of a qualifier or an empty string if there is none.
+func (attributes Attributes) Get(key string) string {
+	values := attributes[key]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Add appends a value to a qualifier.
+func (attributes Attributes) Add(key, value string) {
+	attributes[key] = append(attributes[key], value)
+}
+
+// UnmarshalJSON reads Attributes from JSON. Older poly JSON files stored a single string per
+// qualifier instead of a list so those are read in as a qualifier with one value.
+func (attributes *Attributes) UnmarshalJSON(data []byte) error {
+	var rawAttributes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawAttributes); err != nil {
+		return err
+	}
+	if rawAttributes == nil {
+		*attributes = nil
+		return nil
+	}
+
+	*attributes = make(Attributes, len(rawAttributes))
+	for key, rawValue := range rawAttributes {
+		var values []string
+		if err := json.Unmarshal(rawValue, &values); err != nil {
+			var value string
+			if legacyErr := json.Unmarshal(rawValue, &value); legacyErr != nil {
+				return err
+			}
+			values = []string{value}
+		}
+		(*attributes)[key] = values
+	}
+	return nil
 }
 
 // Sequence holds all sequence information in a single struct.
@@ -113,6 +187,19 @@ func (sequence *Sequence) AddFeature(feature *Feature) []Feature {
 	return sequence.Features
 }
 
+// DisplayName returns the name a sequence goes by in formats that refer to it by name, like the first column of
+// gff, gtf and bed files. It's the first of Meta.Name, the locus name and the accession that is set or "unknown".
+func (sequence Sequence) DisplayName() string {
+	if sequence.Meta.Name != "" {
+		return sequence.Meta.Name
+	} else if sequence.Meta.Locus.Name != "" {
+		return sequence.Meta.Locus.Name
+	} else if sequence.Meta.Accession != "" {
+		return sequence.Meta.Accession
+	}
+	return "unknown"
+}
+
 // GetSequence is a method wrapper to get a Feature's sequence. Mutates with Sequence.
 func (feature Feature) GetSequence() string {
 	return getFeatureSequence(feature, feature.SequenceLocation)
@@ -125,7 +212,7 @@ func getFeatureSequence(feature Feature, location Location) string {
 	parentSequence := feature.ParentSequence.Sequence
 
 	if len(location.SubLocations) == 0 {
-		sequenceBuffer.WriteString(parentSequence[location.Start:location.End])
+		sequenceBuffer.WriteString(getRangeSequence(parentSequence, location, feature.ParentSequence.Meta.Locus.Circular))
 	} else {
 
 		for _, subLocation := range location.SubLocations {
@@ -143,6 +230,27 @@ func getFeatureSequence(feature Feature, location Location) string {
 	return sequenceString
 }
 
+// getRangeSequence slices a single range out of a parent sequence wrapping around the origin of circular sequences.
+func getRangeSequence(parentSequence string, location Location, circular bool) string {
+	// remote locations point into some other sequence.
+	if location.Accession != "" {
+		return ""
+	}
+	// sites between bases have no sequence. On circular sequences n^1 is the site at the origin, which wraps from the
+	// last base back to the first with an End before its Start, and is just as empty.
+	if location.Between {
+		return ""
+	}
+	if circular && location.End < location.Start {
+		return parentSequence[location.Start:] + parentSequence[:location.End]
+	}
+	// GFF3 and some other tools write origin spanning ranges with an end past the length of the sequence.
+	if circular && location.End > len(parentSequence) {
+		return parentSequence[location.Start:] + parentSequence[:location.End-len(parentSequence)]
+	}
+	return parentSequence[location.Start:location.End]
+}
+
 /******************************************************************************
 
 Sequence related structs end here.
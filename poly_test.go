@@ -1,6 +1,7 @@
 package poly
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -36,6 +37,12 @@ func ExampleSequence_AddFeature() {
 	// Output: true
 }
 
+func ExampleSequence_DisplayName() {
+	sequence := Sequence{Meta: Meta{Locus: Locus{Name: "pUC19"}, Accession: "L09137"}}
+	fmt.Println(sequence.DisplayName())
+	// Output: pUC19
+}
+
 func ExampleFeature_GetSequence() {
 
 	// Sequence for greenflourescent protein (GFP) that we're using as test data for this example.
@@ -116,3 +123,26 @@ func TestFeature_GetSequence(t *testing.T) {
 	}
 
 }
+
+func TestAttributes_UnmarshalJSON(t *testing.T) {
+	var feature Feature
+	err := json.Unmarshal([]byte(`{"attributes": {"db_xref": ["GI:1786182", "EcoGene:EG11277"], "gene": "thrL"}}`), &feature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(feature.Attributes["db_xref"]) != 2 || feature.Attributes["db_xref"][1] != "EcoGene:EG11277" {
+		t.Errorf("Attributes with multiple values were not read. Got: %v", feature.Attributes["db_xref"])
+	}
+	if feature.Attributes.Get("gene") != "thrL" {
+		t.Errorf("Legacy single value attribute was not read. Got: %v", feature.Attributes["gene"])
+	}
+	if feature.Attributes.Get("missing") != "" {
+		t.Errorf("Get should return an empty string for missing attributes.")
+	}
+
+	feature.Attributes.Add("gene", "thrL2")
+	if len(feature.Attributes["gene"]) != 2 {
+		t.Errorf("Add did not append a value. Got: %v", feature.Attributes["gene"])
+	}
+}
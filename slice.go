@@ -0,0 +1,145 @@
+package poly
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Sequence slicing and concatenation begins here.
+
+Cutting a region like an operon out of a genome should bring its annotations
+along. Slice is built on top of Delete so features are trimmed the same way
+an edit would trim them.
+
+Concatenate goes the other way and glues parts back together, like the
+product of a GoldenGate or Gibson assembly, while keeping the features of
+every part.
+
+******************************************************************************/
+
+// Slice returns a new linear Sequence holding the 0-based half-open range [start, end) of sequence.
+// Features inside the range are rebased, features overlapping its edges are clipped and marked
+// partial and everything else is dropped. On circular sequences an end before start wraps around the origin.
+func (sequence Sequence) Slice(start, end int) (Sequence, error) {
+	length := len(sequence.Sequence)
+	if start < 0 || end < 0 || start > length || end > length {
+		return Sequence{}, fmt.Errorf("slice range %d..%d is outside of sequence of length %d", start, end, length)
+	}
+	if end < start && !sequence.Meta.Locus.Circular {
+		return Sequence{}, fmt.Errorf("slice range %d..%d is backwards on a linear sequence", start, end)
+	}
+
+	slice := sequence.copy()
+
+	// move the origin to start so that the slice never wraps.
+	if end < start {
+		if err := slice.Rotate(start); err != nil {
+			return Sequence{}, err
+		}
+		start, end = 0, end-start+length
+	}
+
+	// the slice is linear so nothing in it can span the origin anymore.
+	for featureIndex := range slice.Features {
+		location := slice.Features[featureIndex].SequenceLocation.SplitAtOrigin(length)
+		setFeatureLocation(&slice.Features[featureIndex], location)
+	}
+	slice.Meta.Locus.Circular = false
+	slice.Meta.Locus.Linear = true
+
+	if err := slice.Delete(end, length, MarkPartial); err != nil {
+		return Sequence{}, err
+	}
+	if err := slice.Delete(0, start, MarkPartial); err != nil {
+		return Sequence{}, err
+	}
+
+	return slice, nil
+}
+
+// Concatenate joins sequences end to end into a single new Sequence, offsetting the features of every part.
+// If circular is true the result is marked circular. If markParts is true a misc_feature labeled with
+// the part's locus name is added over each part.
+func Concatenate(sequences []Sequence, circular bool, markParts bool) Sequence {
+	var concatenated Sequence
+	var sequenceBuilder strings.Builder
+	var features []Feature
+
+	for _, part := range sequences {
+		offset := sequenceBuilder.Len()
+		partLength := len(part.Sequence)
+		sequenceBuilder.WriteString(part.Sequence)
+
+		if markParts {
+			name := part.Meta.Locus.Name
+			if name == "" {
+				name = part.Meta.Name
+			}
+			partFeature := Feature{Name: name, Type: "misc_feature", Attributes: Attributes{"label": {name}}}
+			partFeature.SequenceLocation = Location{Start: offset, End: offset + partLength}
+			features = append(features, partFeature)
+		}
+
+		for _, feature := range part.Features {
+			// parts are no longer circular once they're glued to something else.
+			location := feature.SequenceLocation.SplitAtOrigin(partLength)
+			// shifting a location is the same as inserting offset bases in front of it.
+			location = insertLocation(location, 0, offset)
+			setFeatureLocation(&feature, location)
+			features = append(features, feature)
+		}
+	}
+
+	concatenated.Sequence = sequenceBuilder.String()
+	if len(sequences) > 0 {
+		concatenated.Meta.Locus.MoleculeType = sequences[0].Meta.Locus.MoleculeType
+	}
+	concatenated.Meta.Locus.SequenceLength = strconv.Itoa(len(concatenated.Sequence))
+	concatenated.Meta.Locus.Circular = circular
+	concatenated.Meta.Locus.Linear = !circular
+
+	for _, feature := range features {
+		concatenated.AddFeature(&feature)
+	}
+	return concatenated
+}
+
+// copy returns a copy of sequence that can be edited without touching the original.
+func (sequence Sequence) copy() Sequence {
+	sequenceCopy := sequence
+	sequenceCopy.Meta.References = append([]Reference{}, sequence.Meta.References...)
+	if sequence.Meta.Other != nil {
+		sequenceCopy.Meta.Other = make(map[string]string, len(sequence.Meta.Other))
+		for key, value := range sequence.Meta.Other {
+			sequenceCopy.Meta.Other[key] = value
+		}
+	}
+	sequenceCopy.Meta.GbkSections = append([]string(nil), sequence.Meta.GbkSections...)
+	sequenceCopy.Meta.Gaps = nil
+	for _, gap := range sequence.Meta.Gaps {
+		gap.LinkageEvidence = append([]string(nil), gap.LinkageEvidence...)
+		sequenceCopy.Meta.Gaps = append(sequenceCopy.Meta.Gaps, gap)
+	}
+	sequenceCopy.Features = append([]Feature{}, sequence.Features...)
+	for featureIndex := range sequenceCopy.Features {
+		feature := &sequenceCopy.Features[featureIndex]
+		feature.ParentSequence = &sequenceCopy
+		if feature.Attributes != nil {
+			attributes := make(Attributes, len(feature.Attributes))
+			for key, values := range feature.Attributes {
+				attributes[key] = append([]string(nil), values...)
+			}
+			feature.Attributes = attributes
+		}
+	}
+	return sequenceCopy
+}
+
+/******************************************************************************
+
+Sequence slicing and concatenation ends here.
+
+******************************************************************************/
@@ -0,0 +1,185 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleSequence_Slice() {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGGCCCCC"
+
+	var feature Feature
+	feature.Name = "G's"
+	feature.SequenceLocation.Start = 10
+	feature.SequenceLocation.End = 15
+	sequence.AddFeature(&feature)
+
+	slice, _ := sequence.Slice(8, 20)
+
+	fmt.Println(slice.Sequence, slice.Features[0].GetSequence())
+	// Output: TTGGGGGCCCCC GGGGG
+}
+
+func TestSequence_Slice(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAATTTTTGGGGGCCCCC"
+	sequence.Meta.Locus.SequenceLength = "20"
+	sequence.Meta.Locus.Circular = true
+	sequence.Meta.References = []Reference{{Title: "A sequence"}}
+
+	left := Feature{Name: "left", SequenceLocation: Location{Start: 0, End: 7}}
+	inside := Feature{Name: "inside", SequenceLocation: Location{Start: 10, End: 15, Complement: true}}
+	right := Feature{Name: "right", SequenceLocation: Location{Start: 13, End: 20}}
+	spanning := Feature{Name: "spanning", SequenceLocation: Location{Start: 18, End: 2}}
+	sequence.AddFeature(&left)
+	sequence.AddFeature(&inside)
+	sequence.AddFeature(&right)
+	sequence.AddFeature(&spanning)
+
+	slice, err := sequence.Slice(5, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slice.Sequence != "TTTTTGGGGGCC" {
+		t.Errorf("Slice produced the wrong sequence. Got: %s", slice.Sequence)
+	}
+	if slice.Meta.Locus.SequenceLength != "12" || slice.Meta.Locus.Circular {
+		t.Errorf("Slice did not update locus. Got: %+v", slice.Meta.Locus)
+	}
+	if len(slice.Meta.References) != 1 {
+		t.Errorf("Slice did not keep references.")
+	}
+	if len(slice.Features) != 3 {
+		t.Fatalf("Slice should keep 3 features. Got %d", len(slice.Features))
+	}
+
+	leftLocation := slice.Features[0].SequenceLocation
+	if leftLocation.Start != 0 || leftLocation.End != 2 || !leftLocation.FivePrimePartial {
+		t.Errorf("Slice did not clip left feature. Got: %+v", leftLocation)
+	}
+	if got := slice.Features[1].GetSequence(); got != "CCCCC" {
+		t.Errorf("Slice did not rebase inside feature. Got: %s", got)
+	}
+	rightLocation := slice.Features[2].SequenceLocation
+	if rightLocation.Start != 8 || rightLocation.End != 12 || !rightLocation.ThreePrimePartial {
+		t.Errorf("Slice did not clip right feature. Got: %+v", rightLocation)
+	}
+
+	// the original should be untouched.
+	if sequence.Sequence != "AAAAATTTTTGGGGGCCCCC" || len(sequence.Features) != 4 || sequence.Features[0].SequenceLocation.End != 7 {
+		t.Errorf("Slice modified the original sequence.")
+	}
+
+	// slicing across the origin of a circular sequence.
+	slice, err = sequence.Slice(15, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slice.Sequence != "CCCCCAAAAA" {
+		t.Errorf("Slice across origin produced the wrong sequence. Got: %s", slice.Sequence)
+	}
+	for _, feature := range slice.Features {
+		if feature.Name == "spanning" && feature.GetSequence() != "CCAA" {
+			t.Errorf("Slice across origin broke spanning feature. Got: %s", feature.GetSequence())
+		}
+	}
+
+	sequence.Meta.Locus.Circular = false
+	if _, err := sequence.Slice(15, 5); err == nil {
+		t.Errorf("Slice should fail on backwards ranges of linear sequences.")
+	}
+}
+
+func TestSequence_SliceDoesNotShare(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "AAAAANNNNNGGGGGCCCCC"
+	sequence.Meta.GbkSections = []string{"LOCUS       slice"}
+	sequence.Meta.Gaps = []Gap{{Start: 5, End: 10, LinkageEvidence: []string{"paired-ends"}}}
+	feature := Feature{Name: "G's", SequenceLocation: Location{Start: 10, End: 15}, Attributes: Attributes{"label": {"G's"}}}
+	sequence.AddFeature(&feature)
+
+	slice, err := sequence.Slice(0, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slice.Meta.GbkSections[0] = "LOCUS       edited"
+	slice.Meta.Gaps[0].LinkageEvidence[0] = "unspecified"
+	slice.Features[0].Attributes["label"][0] = "edited"
+	slice.Features[0].Attributes.Add("note", "edited")
+	if err := slice.Insert(0, "TTTTT"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sequence.Meta.GbkSections[0] != "LOCUS       slice" {
+		t.Errorf("Editing a slice changed the GbkSections of the original.")
+	}
+	if gap := sequence.Meta.Gaps[0]; gap.Start != 5 || gap.LinkageEvidence[0] != "paired-ends" {
+		t.Errorf("Editing a slice changed the gaps of the original. Got: %+v", gap)
+	}
+	if attributes := sequence.Features[0].Attributes; attributes.Get("label") != "G's" || attributes.Get("note") != "" {
+		t.Errorf("Editing a slice changed the attributes of the original. Got: %v", attributes)
+	}
+}
+
+func ExampleConcatenate() {
+	var promoter Sequence
+	promoter.Sequence = "TTGACAATTAATCATCGGCTCGTATAATG"
+	promoter.Meta.Locus.Name = "promoter"
+
+	var gene Sequence
+	gene.Sequence = "ATGGCTAGCAAAGGAGAAGAACTTTTCACTGGAGTTGTCCCAATTCTTGTTGAATTAGATGGTGATGTTAATGGGCACAAATTTTCTGTCAGTGGAGAGGGTGAAGGTGATGCTACATACGGAAAGCTTACCCTTAAATTTATTTGCACTACTGGAAAACTACCTGTTCCATGGCCAACACTTGTCACTACTTTCTCTTATGGTGTTCAATGCTTTTCCCGTTATCCGGATCATATGAAACGGCATGACTTTTTCAAGAGTGCCATGCCCGAAGGTTATGTACAGGAACGCACTATATCTTTCAAAGATGACGGGAACTACAAGACGCGTGCTGAAGTCAAGTTTGAAGGTGATACCCTTGTTAATCGTATCGAGTTAAAAGGTATTGATTTTAAAGAAGATGGAAACATTCTCGGACACAAACTCGAGTACAACTATAACTCACACAATGTATACATCACGGCAGACAAACAAAAGAATGGAATCAAAGCTAACTTCAAAATTCGCCACAACATTGAAGATGGATCCGTTCAACTAGCAGACCATTATCAACAAAATACTCCAATTGGCGATGGCCCTGTCCTTTTACCAGACAACCATTACCTGTCGACACAATCTGCCCTTTCGAAAGATCCCAACGAAAAGCGTGACCACATGGTCCTTCTTGAGTTTGTAACTGCTGCTGGGATTACACATGGCATGGATGAGCTCTACAAATAA"
+	gene.Meta.Locus.Name = "gfp"
+
+	var cds Feature
+	cds.Type = "CDS"
+	cds.SequenceLocation.Start = 0
+	cds.SequenceLocation.End = len(gene.Sequence)
+	gene.AddFeature(&cds)
+
+	construct := Concatenate([]Sequence{promoter, gene}, false, true)
+
+	for _, feature := range construct.Features {
+		fmt.Println(feature.Type, feature.SequenceLocation.Start, feature.SequenceLocation.End)
+	}
+	// Output:
+	// misc_feature 0 29
+	// misc_feature 29 749
+	// CDS 29 749
+}
+
+func TestConcatenate(t *testing.T) {
+	var first Sequence
+	first.Sequence = "GGGAAAAACC"
+	first.Meta.Locus.Circular = true
+	spanning := Feature{Name: "spanning", SequenceLocation: Location{Start: 8, End: 3}}
+	first.AddFeature(&spanning)
+
+	var second Sequence
+	second.Sequence = "TTTTT"
+	reverse := Feature{Name: "reverse", SequenceLocation: Location{Start: 1, End: 4, Complement: true}, GbkLocationString: "complement(2..4)"}
+	second.AddFeature(&reverse)
+
+	concatenated := Concatenate([]Sequence{first, second}, true, false)
+	if concatenated.Sequence != "GGGAAAAACCTTTTT" {
+		t.Errorf("Concatenate produced the wrong sequence. Got: %s", concatenated.Sequence)
+	}
+	if !concatenated.Meta.Locus.Circular || concatenated.Meta.Locus.SequenceLength != "15" {
+		t.Errorf("Concatenate did not set up locus. Got: %+v", concatenated.Meta.Locus)
+	}
+	if len(concatenated.Features) != 2 {
+		t.Fatalf("Concatenate should keep both features. Got %d", len(concatenated.Features))
+	}
+	if got := concatenated.Features[0].GetSequence(); got != "CCGGG" {
+		t.Errorf("Concatenate broke origin spanning feature of a part. Got: %s", got)
+	}
+	if got := concatenated.Features[1].GetSequence(); got != "AAA" {
+		t.Errorf("Concatenate did not offset features. Got: %s", got)
+	}
+	if concatenated.Features[1].GbkLocationString != "" {
+		t.Errorf("Concatenate did not clear stale GbkLocationString")
+	}
+	if concatenated.Features[1].ParentSequence.Sequence != concatenated.Sequence {
+		t.Errorf("Concatenate did not point features at the new sequence")
+	}
+}
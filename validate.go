@@ -0,0 +1,209 @@
+package poly
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/******************************************************************************
+
+Sequence validation begins here.
+
+Parsers are forgiving on purpose so a slightly wonky file still opens. The
+downside is that bad records flow through pipelines without anyone noticing.
+Validate looks over a Sequence for the structural problems we've seen bite
+people and reports them instead of fixing them.
+
+Problems come in two severities. Errors mean the record contradicts itself,
+like a feature that ends past the end of its sequence. Warnings are things
+that are legal but usually a mistake, like a CDS whose length isn't a
+multiple of 3.
+
+******************************************************************************/
+
+// Severity is how bad a ValidationIssue is.
+type Severity int
+
+const (
+	// SeverityError marks a record that contradicts itself.
+	SeverityError Severity = iota
+	// SeverityWarning marks something that is legal but probably a mistake.
+	SeverityWarning
+)
+
+func (severity Severity) String() string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// ValidationIssue is a single problem found by Validate. FeatureIndex is the index of the
+// offending feature in Sequence.Features or -1 if the problem is with the sequence itself.
+type ValidationIssue struct {
+	Severity     Severity `json:"severity"`
+	FeatureIndex int      `json:"feature_index"`
+	Message      string   `json:"message"`
+}
+
+func (issue ValidationIssue) String() string {
+	if issue.FeatureIndex < 0 {
+		return issue.Severity.String() + ": " + issue.Message
+	}
+	return fmt.Sprintf("%s: feature %d: %s", issue.Severity, issue.FeatureIndex, issue.Message)
+}
+
+// ValidationError is returned by strict parsers when a Sequence has validation errors. It holds every issue found, warnings included.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+func (validationError *ValidationError) Error() string {
+	var messages []string
+	for _, issue := range validationError.Issues {
+		if issue.Severity == SeverityError {
+			messages = append(messages, issue.String())
+		}
+	}
+	return "invalid sequence: " + strings.Join(messages, "; ")
+}
+
+// nucleotideAlphabet and proteinAlphabet are the characters seqhash accepts, plus lowercase and gaps.
+const (
+	nucleotideAlphabet = "ATUGCYRSWKMBDHVNZ-"
+	proteinAlphabet    = "ACDEFGHIKLMNPQRSTVWYUO*BXZ-"
+)
+
+// Validate checks a Sequence for structural problems and returns every one it finds.
+func (sequence Sequence) Validate() []ValidationIssue {
+	var issues []ValidationIssue
+	addIssue := func(severity Severity, featureIndex int, format string, arguments ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: severity, FeatureIndex: featureIndex, Message: fmt.Sprintf(format, arguments...)})
+	}
+
+	length := len(sequence.Sequence)
+	// their lengths and feature locations can't be checked until they're expanded.
+	assembled := sequence.assembled()
+	switch {
+	case assembled:
+		addIssue(SeverityWarning, -1, unexpandedMessage)
+	case length == 0:
+		addIssue(SeverityWarning, -1, "sequence is empty")
+	}
+
+	if sequenceLength := sequence.Meta.Locus.SequenceLength; sequenceLength != "" && !assembled {
+		declaredLength, err := strconv.Atoi(strings.TrimSpace(sequenceLength))
+		if err != nil {
+			addIssue(SeverityError, -1, "locus sequence length %q is not a number", sequenceLength)
+		} else if declaredLength != length {
+			addIssue(SeverityError, -1, "locus sequence length %d does not match sequence length %d", declaredLength, length)
+		}
+	}
+
+	alphabet := nucleotideAlphabet
+	if sequenceType, _ := moleculeType(sequence.Meta.Locus); sequenceType == "PROTEIN" {
+		alphabet = proteinAlphabet
+	}
+	for position, character := range strings.ToUpper(sequence.Sequence) {
+		if !strings.ContainsRune(alphabet, character) {
+			addIssue(SeverityError, -1, "invalid character %q at position %d", character, position+1)
+			break
+		}
+	}
+
+	for featureIndex, feature := range sequence.Features {
+		location := feature.SequenceLocation
+		if !assembled {
+			for _, message := range validateLocation(location, length, sequence.Meta.Locus.Circular) {
+				addIssue(SeverityError, featureIndex, "%s", message)
+			}
+		}
+		if feature.Type == "CDS" && !location.FivePrimePartial && !location.ThreePrimePartial {
+			if cdsLength := locationLength(location, length); cdsLength%3 != 0 {
+				addIssue(SeverityWarning, featureIndex, "CDS length %d is not a multiple of 3", cdsLength)
+			}
+		}
+	}
+
+	hierarchy := sequence.Hierarchy()
+	for _, orphan := range hierarchy.Orphans() {
+		addIssue(SeverityWarning, orphan, "parent %s does not exist", strings.Join(sequence.Features[orphan].Attributes["Parent"], ","))
+	}
+	for _, cycle := range hierarchy.Cycles() {
+		addIssue(SeverityError, cycle[0], "feature is its own ancestor through features %v", cycle)
+	}
+
+	return issues
+}
+
+// ValidateStrict runs Validate and returns a *ValidationError if any of the issues it finds are errors. Warnings alone pass.
+func (sequence Sequence) ValidateStrict() error {
+	issues := sequence.Validate()
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return &ValidationError{Issues: issues}
+		}
+	}
+	return nil
+}
+
+// unexpandedMessage is what's wrong with a record that is still assembled from other records.
+const unexpandedMessage = "sequence is assembled from other records and hasn't been expanded"
+
+// assembled reports whether sequence is assembled from other records, like CON and WGS master records,
+// and has no sequence of its own until it's expanded.
+func (sequence Sequence) assembled() bool {
+	return len(sequence.Sequence) == 0 && (sequence.Meta.Contig != "" || sequence.Meta.WGS != "" || sequence.Meta.WGSScaffold != "")
+}
+
+// validateLocation returns a message for every range in location that doesn't fit in a sequence of length.
+func validateLocation(location Location, length int, circular bool) []string {
+	if len(location.SubLocations) > 0 {
+		var messages []string
+		for _, subLocation := range location.SubLocations {
+			messages = append(messages, validateLocation(subLocation, length, circular)...)
+		}
+		return messages
+	}
+	// remote locations point into some other sequence so we can't check them.
+	if location.Accession != "" {
+		return nil
+	}
+
+	switch {
+	case location.Start < 0 || location.End < 0:
+		return []string{fmt.Sprintf("location %d..%d has a negative position", location.Start+1, location.End)}
+	case location.Start >= length && !(location.Between && location.Start == length):
+		return []string{fmt.Sprintf("location %d..%d starts past the end of the sequence at %d", location.Start+1, location.End, length)}
+	case location.End > length && (!circular || location.End-length > location.Start):
+		return []string{fmt.Sprintf("location %d..%d ends past the end of the sequence at %d", location.Start+1, location.End, length)}
+	case location.End < location.Start && !circular:
+		return []string{fmt.Sprintf("location %d..%d ends before it starts on a linear sequence", location.Start+1, location.End)}
+	}
+	return nil
+}
+
+// locationLength returns how many bases a location covers.
+func locationLength(location Location, sequenceLength int) int {
+	if len(location.SubLocations) > 0 {
+		length := 0
+		for _, subLocation := range location.SubLocations {
+			length += locationLength(subLocation, sequenceLength)
+		}
+		return length
+	}
+	if location.Between {
+		return 0
+	}
+	if location.SpansOrigin() {
+		return sequenceLength - location.Start + location.End
+	}
+	return location.End - location.Start
+}
+
+/******************************************************************************
+
+Sequence validation ends here.
+
+******************************************************************************/
@@ -0,0 +1,88 @@
+package poly
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func ExampleSequence_Validate() {
+	var sequence Sequence
+	sequence.Sequence = "ATGAAATAG"
+	sequence.Meta.Locus.SequenceLength = "9"
+
+	cds := Feature{Type: "CDS", SequenceLocation: Location{Start: 0, End: 10}}
+	sequence.AddFeature(&cds)
+
+	for _, issue := range sequence.Validate() {
+		fmt.Println(issue)
+	}
+	// Output:
+	// error: feature 0: location 1..10 ends past the end of the sequence at 9
+	// warning: feature 0: CDS length 10 is not a multiple of 3
+}
+
+func TestSequence_Validate(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "ATGAAATAGxCC"
+	sequence.Meta.Locus.SequenceLength = "20"
+	sequence.Meta.Locus.Circular = true
+
+	features := []Feature{
+		{Type: "CDS", SequenceLocation: Location{Start: 0, End: 9}},
+		{Type: "CDS", SequenceLocation: Location{Start: 0, End: 8, ThreePrimePartial: true}},
+		{Type: "CDS", SequenceLocation: Location{Start: 10, End: 1}},
+		{Type: "gene", SequenceLocation: Location{Start: 4, End: 2}},
+		{Type: "gene", SequenceLocation: Location{Start: 12, End: 13}},
+		{Type: "gene", SequenceLocation: Location{Join: true, SubLocations: []Location{{Start: 0, End: 3}, {Start: -1, End: 4}, {Start: 99, End: 202, Accession: "J00194.1"}}}},
+		{Type: "exon", Attributes: Attributes{"Parent": {"missing"}}, SequenceLocation: Location{Start: 0, End: 3}},
+		{Type: "gene", Attributes: Attributes{"ID": {"loop"}, "Parent": {"loop"}}, SequenceLocation: Location{Start: 0, End: 3}},
+	}
+	for _, feature := range features {
+		sequence.AddFeature(&feature)
+	}
+
+	expected := []ValidationIssue{
+		{SeverityError, -1, "locus sequence length 20 does not match sequence length 12"},
+		{SeverityError, -1, "invalid character 'X' at position 10"},
+		{SeverityError, 4, "location 13..13 starts past the end of the sequence at 12"},
+		{SeverityError, 5, "location 0..4 has a negative position"},
+		{SeverityWarning, 6, "parent missing does not exist"},
+		{SeverityError, 7, "feature is its own ancestor through features [7]"},
+	}
+	if diff := cmp.Diff(expected, sequence.Validate()); diff != "" {
+		t.Errorf("Validate got this diff:\n%s", diff)
+	}
+
+	// the same backwards feature is broken once the sequence is linear.
+	sequence.Meta.Locus.Circular = false
+	sequence.Meta.Locus.SequenceLength = ""
+	sequence.Sequence = "ATGAAATAGACC"
+	issues := sequence.Validate()
+	if len(issues) != 6 || issues[0].FeatureIndex != 2 || issues[1].FeatureIndex != 3 {
+		t.Errorf("Validate should flag locations ending before they start on linear sequences. Got: %v", issues)
+	}
+}
+
+func TestSequence_ValidateProtein(t *testing.T) {
+	var sequence Sequence
+	sequence.Sequence = "MKLVQE*"
+	sequence.Meta.Locus.MoleculeType = "aa"
+	if issues := sequence.Validate(); len(issues) != 0 {
+		t.Errorf("Protein sequence should be valid. Got: %v", issues)
+	}
+}
+
+func TestSequence_ValidateContig(t *testing.T) {
+	var sequence Sequence
+	sequence.Meta.Locus.SequenceLength = "3000"
+	sequence.Meta.Contig = "join(AAAA01000001.1:1..3000)"
+	sequence.Features = []Feature{{Type: "gene", SequenceLocation: Location{Start: 100, End: 200}}}
+	if err := sequence.ValidateStrict(); err != nil {
+		t.Errorf("Unexpanded CONTIG record should pass strict validation. Got: %s", err)
+	}
+	if issues := sequence.Validate(); len(issues) != 1 || issues[0].Severity != SeverityWarning {
+		t.Errorf("Unexpanded CONTIG record should get a single warning. Got: %v", issues)
+	}
+}